@@ -0,0 +1,225 @@
+// Package audit keeps an append-only log of security relevant events: who did what to which
+// share or user, from which address and in which request.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"qr-pastebin-api/common"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	ACTION_LOGIN                 = "login"
+	ACTION_LOGIN_FAILED          = "login.failed"
+	ACTION_SESSION_CREATED       = "session.created"
+	ACTION_SHARE_CREATED         = "share.created"
+	ACTION_SHARE_UPDATED         = "share.updated"
+	ACTION_SHARE_DELETED         = "share.deleted"
+	ACTION_SHARE_UNLOCK_FAILED   = "share.unlock_failed"
+	ACTION_USER_ROLE_CHANGED     = "user.role_changed"
+	ACTION_USER_DISABLED         = "user.disabled"
+	ACTION_USER_ENABLED          = "user.enabled"
+	ACTION_USER_SESSIONS_EXPIRED = "user.sessions_expired"
+	ACTION_USER_DELETED          = "user.deleted"
+)
+
+const (
+	TARGET_SHARE = "share"
+	TARGET_USER  = "user"
+)
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+	exportPageSize       = 1000
+)
+
+// Entry is one event, ActorId is -1 for requests without a session
+type Entry struct {
+	Id         int64     `json:"id"`
+	At         time.Time `json:"at"`
+	Action     string    `json:"action"`
+	ActorId    int       `json:"actorId"`
+	ActorName  string    `json:"actorName,omitempty"`
+	TargetType string    `json:"targetType,omitempty"`
+	TargetId   string    `json:"targetId,omitempty"`
+	Ip         string    `json:"ip"`
+	RequestId  string    `json:"requestId"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// Filter narrows down the log, empty fields match every entry. From and To are RFC 3339 timestamps.
+type Filter struct {
+	Action     string `form:"action"`
+	ActorId    *int   `form:"actorId"`
+	TargetType string `form:"targetType" binding:"omitempty,oneof=share user"`
+	TargetId   string `form:"targetId"`
+	RequestId  string `form:"requestId"`
+	From       string `form:"from"`
+	To         string `form:"to"`
+	Limit      int    `form:"limit"`
+	Offset     int    `form:"offset"`
+}
+
+type AuditDBHandler struct {
+	DB *pgx.Conn
+}
+
+func NewAuditHandler(db *pgx.Conn) *AuditDBHandler {
+	return &AuditDBHandler{DB: db}
+}
+
+// Record appends an entry. Entries are never changed or removed, the table refuses it as well.
+func (handler *AuditDBHandler) Record(entry Entry) error {
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+	query := "INSERT INTO audit_log (at, action, actor_id, target_type, target_id, ip, request_id, detail) VALUES ($1, $2, $3, $4, $5, $6, $7, $8);"
+	_, err := handler.DB.Exec(context.Background(), query, entry.At, entry.Action, entry.ActorId, entry.TargetType, entry.TargetId, entry.Ip, entry.RequestId, entry.Detail)
+	if err != nil {
+		return fmt.Errorf("couldn't write audit entry '%s': %w", entry.Action, err)
+	}
+	return nil
+}
+
+// GetEntries returns a page of matching entries, newest first
+func (handler *AuditDBHandler) GetEntries(filter Filter) ([]Entry, error) {
+	where, args, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	limit = min(limit, maxAuditPageSize)
+	offset := max(filter.Offset, 0)
+
+	query := fmt.Sprintf("SELECT %s FROM audit_log AS a LEFT JOIN users AS u ON u.id = a.actor_id%s ORDER BY a.id DESC LIMIT $%d OFFSET $%d;", entryColumns, where, len(args)+1, len(args)+2)
+	return handler.queryEntries(query, append(args, limit, offset)...)
+}
+
+// Export writes every matching entry as a line of JSON, newest first. The log is read in pages so
+// the connection is not held for the whole export.
+func (handler *AuditDBHandler) Export(filter Filter, w io.Writer) error {
+	where, args, err := filter.where()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+
+	var beforeId int64 = -1
+	for {
+		pageWhere := where
+		pageArgs := args
+		if beforeId != -1 {
+			pageArgs = append(append([]any{}, args...), beforeId)
+			pageWhere = joinConditions(where, fmt.Sprintf("a.id < $%d", len(pageArgs)))
+		}
+		query := fmt.Sprintf("SELECT %s FROM audit_log AS a LEFT JOIN users AS u ON u.id = a.actor_id%s ORDER BY a.id DESC LIMIT $%d;", entryColumns, pageWhere, len(pageArgs)+1)
+		entries, err := handler.queryEntries(query, append(pageArgs, exportPageSize)...)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		if len(entries) < exportPageSize {
+			return nil
+		}
+		beforeId = entries[len(entries)-1].Id
+	}
+}
+
+const entryColumns = "a.id, a.at, a.action, a.actor_id, COALESCE(u.name, ''), a.target_type, a.target_id, a.ip, a.request_id, a.detail"
+
+func (handler *AuditDBHandler) queryEntries(query string, args ...any) ([]Entry, error) {
+	rows, err := handler.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]Entry, 0)
+	for rows.Next() {
+		var entry Entry
+		err := rows.Scan(&entry.Id, &entry.At, &entry.Action, &entry.ActorId, &entry.ActorName, &entry.TargetType, &entry.TargetId, &entry.Ip, &entry.RequestId, &entry.Detail)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return entries, nil
+}
+
+// where builds the WHERE clause of the filter, with a leading space when there is one
+func (filter Filter) where() (string, []any, error) {
+	conditions := []string{}
+	args := []any{}
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Action != "" {
+		add("a.action = $%d", filter.Action)
+	}
+	if filter.ActorId != nil {
+		add("a.actor_id = $%d", *filter.ActorId)
+	}
+	if filter.TargetType != "" {
+		add("a.target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		add("a.target_id = $%d", filter.TargetId)
+	}
+	if filter.RequestId != "" {
+		add("a.request_id = $%d", filter.RequestId)
+	}
+
+	fields := make([]common.FieldError, 0)
+	for _, bound := range []struct {
+		field     string
+		value     string
+		condition string
+	}{
+		{"from", filter.From, "a.at >= $%d"},
+		{"to", filter.To, "a.at < $%d"},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			fields = append(fields, common.FieldError{Field: bound.field, Rule: "rfc3339", Message: "must be an RFC 3339 timestamp like 2030-01-31T12:00:00Z"})
+			continue
+		}
+		add(bound.condition, date)
+	}
+	if len(fields) > 0 {
+		return "", nil, &common.ValidationError{Fields: fields}
+	}
+
+	return joinConditions("", conditions...), args, nil
+}
+
+func joinConditions(where string, conditions ...string) string {
+	if len(conditions) == 0 {
+		return where
+	}
+	if where == "" {
+		return " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return where + " AND " + strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"errors"
+	"qr-pastebin-api/common"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterWhere(t *testing.T) {
+	actorId := 7
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		filter Filter
+		where  string
+		args   []any
+	}{
+		{Filter{}, "", []any{}},
+		{Filter{Action: ACTION_SHARE_DELETED}, " WHERE a.action = $1", []any{ACTION_SHARE_DELETED}},
+		{
+			Filter{ActorId: &actorId, TargetType: TARGET_SHARE, TargetId: "abc1234", From: "2026-03-01T00:00:00Z"},
+			" WHERE a.actor_id = $1 AND a.target_type = $2 AND a.target_id = $3 AND a.at >= $4",
+			[]any{7, TARGET_SHARE, "abc1234", from},
+		},
+		{Filter{RequestId: "req-1", To: "2026-03-01T02:00:00+02:00"}, " WHERE a.request_id = $1 AND a.at < $2", []any{"req-1", from}},
+	}
+	for _, test := range tests {
+		where, args, err := test.filter.where()
+		if err != nil {
+			t.Errorf("%+v: unexpected error %v", test.filter, err)
+			continue
+		}
+		if where != test.where {
+			t.Errorf("%+v: expected '%s', got '%s'", test.filter, test.where, where)
+		}
+		if len(args) != len(test.args) {
+			t.Errorf("%+v: expected args %v, got %v", test.filter, test.args, args)
+			continue
+		}
+		for i := range args {
+			if date, ok := args[i].(time.Time); ok {
+				if !date.Equal(test.args[i].(time.Time)) {
+					t.Errorf("%+v: expected %v, got %v", test.filter, test.args[i], date)
+				}
+			} else if !reflect.DeepEqual(args[i], test.args[i]) {
+				t.Errorf("%+v: expected %v, got %v", test.filter, test.args[i], args[i])
+			}
+		}
+	}
+}
+
+func TestFilterRejectsMalformedTimes(t *testing.T) {
+	_, _, err := Filter{From: "yesterday", To: "2026-03-01"}.where()
+	var validationErr *common.ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Fields) != 2 {
+		t.Fatalf("expected validation errors for from and to, got %v", err)
+	}
+	if validationErr.Fields[0].Field != "from" || validationErr.Fields[1].Field != "to" || validationErr.Fields[0].Rule != "rfc3339" {
+		t.Errorf("unexpected fields %+v", validationErr.Fields)
+	}
+}
+
+func TestJoinConditions(t *testing.T) {
+	if got := joinConditions(" WHERE a.action = $1", "a.id < $2"); got != " WHERE a.action = $1 AND a.id < $2" {
+		t.Errorf("unexpected clause '%s'", got)
+	}
+	if got := joinConditions("", "a.id < $1"); got != " WHERE a.id < $1" {
+		t.Errorf("unexpected clause '%s'", got)
+	}
+}
@@ -0,0 +1,46 @@
+// Package blob stores uploaded files outside of the database
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+type Storage interface {
+	// Put stores size bytes read from body under the key, replacing anything stored there before
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under the key, the caller has to close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob, deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+type NotFoundError struct {
+	Key string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("blob '%s' does not exist", e.Key)
+}
+
+// NewStorageFromEnv picks the storage based on BLOB_DRIVER. Anything other than "s3" stores
+// blobs on the local filesystem, so local development works without an object store.
+func NewStorageFromEnv() Storage {
+	if os.Getenv("BLOB_DRIVER") == "s3" {
+		return &S3Storage{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyId:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		}
+	}
+
+	root := os.Getenv("BLOB_PATH")
+	if root == "" {
+		root = "blobs"
+	}
+	return &LocalStorage{Root: root}
+}
@@ -0,0 +1,78 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage keeps every blob as a file below the root directory
+type LocalStorage struct {
+	Root string
+}
+
+func (storage *LocalStorage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create directory for blob '%s': %w", key, err)
+	}
+
+	// Writing to a temporary file first means readers never see a partially written blob
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("could not create blob '%s': %w", key, err)
+	}
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write blob '%s': %w", key, err)
+	}
+	if written != size {
+		return fmt.Errorf("blob '%s' should be %d bytes long, got %d", key, size, written)
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (storage *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := storage.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &NotFoundError{Key: key}
+	}
+	return file, err
+}
+
+func (storage *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := storage.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not delete blob '%s': %w", key, err)
+	}
+	return nil
+}
+
+// path maps the key to a file, refusing keys that would point outside of the root
+func (storage *LocalStorage) path(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(filepath.FromSlash(key)) || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("blob key '%s' is not valid", key)
+	}
+	return filepath.Join(storage.Root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,179 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Storage keeps blobs in a bucket of any S3-compatible object store (AWS S3, MinIO, Garage, ...).
+// Buckets are addressed path-style as "endpoint/bucket/key", which every implementation supports.
+type S3Storage struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyId     string
+	SecretAccessKey string
+	Client          *http.Client
+}
+
+func (storage *S3Storage) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	request, err := storage.newRequest(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+	request.Header.Set("Content-Type", contentType)
+
+	response, err := storage.do(request)
+	if err != nil {
+		return fmt.Errorf("could not upload blob '%s': %w", key, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("could not upload blob '%s': %w", key, readS3Error(response))
+	}
+	return nil
+}
+
+func (storage *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	request, err := storage.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := storage.do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not download blob '%s': %w", key, err)
+	}
+	if response.StatusCode == http.StatusNotFound {
+		response.Body.Close()
+		return nil, &NotFoundError{Key: key}
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		return nil, fmt.Errorf("could not download blob '%s': %w", key, readS3Error(response))
+	}
+	return response.Body, nil
+}
+
+func (storage *S3Storage) Delete(ctx context.Context, key string) error {
+	request, err := storage.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := storage.do(request)
+	if err != nil {
+		return fmt.Errorf("could not delete blob '%s': %w", key, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("could not delete blob '%s': %w", key, readS3Error(response))
+	}
+	return nil
+}
+
+func (storage *S3Storage) newRequest(ctx context.Context, method string, key string, body io.Reader) (*http.Request, error) {
+	objectUrl := fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(storage.Endpoint, "/"), storage.Bucket, uriEncode(key, false))
+	request, err := http.NewRequestWithContext(ctx, method, objectUrl, body)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request for blob '%s': %w", key, err)
+	}
+	request.Header.Set("x-amz-content-sha256", unsignedPayload)
+	return request, nil
+}
+
+func (storage *S3Storage) do(request *http.Request) (*http.Response, error) {
+	storage.sign(request, time.Now())
+	client := storage.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(request)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header. The host and every header already set
+// on the request are signed, the payload hash is taken from x-amz-content-sha256.
+func (storage *S3Storage) sign(request *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	request.Header.Set("x-amz-date", amzDate)
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		uriEncode(request.URL.Path, false),
+		request.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		request.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, storage.Region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex(canonicalRequest)}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+storage.SecretAccessKey), date)
+	key = hmacSha256(key, storage.Region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", storage.AccessKeyId, scope, signedHeaders, signature))
+}
+
+// uriEncode escapes everything except unreserved characters, as Signature Version 4 requires.
+// Slashes are kept unless encodeSlash is set.
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		unreserved := (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~'
+		if unreserved || (b == '/' && !encodeSlash) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+func readS3Error(response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	return fmt.Errorf("object store responded with status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+}
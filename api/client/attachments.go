@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"qr-pastebin-api/shares"
+	"strconv"
+)
+
+type Attachment struct {
+	Body     io.ReadCloser
+	MimeType string
+	Size     int64
+	// Checksum is the hex encoded SHA-256 of the file as the server stored it
+	Checksum string
+}
+
+// UploadAttachment streams file to the share. Uploads are not retried, as the file can only be read once.
+func (client *Client) UploadAttachment(ctx context.Context, shareId string, fileName string, mimeType string, file io.Reader) (*shares.AttachmentResponse, error) {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%s`, strconv.Quote(fileName)))
+		if mimeType != "" {
+			header.Set("Content-Type", mimeType)
+		}
+		part, err := form.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	path := sharePath(shareId) + "/attachments"
+	headers := map[string]string{"Content-Type": form.FormDataContentType()}
+	response, err := client.sendOnce(ctx, http.MethodPost, path, nil, headers, reader)
+	reader.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 400 {
+		return nil, readError(response)
+	}
+
+	var attachment shares.AttachmentResponse
+	if err := decode(response, &attachment); err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// DownloadAttachment opens the file for reading, the caller has to close its Body
+func (client *Client) DownloadAttachment(ctx context.Context, shareId string, attachmentId string, unlock Unlock) (*Attachment, error) {
+	path := fmt.Sprintf("%s/attachments/%s", sharePath(shareId), escape(attachmentId))
+	response, err := client.send(ctx, http.MethodGet, path, unlock.query(), unlock.headers(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Attachment{
+		Body:     response.Body,
+		MimeType: response.Header.Get("Content-Type"),
+		Size:     response.ContentLength,
+		Checksum: response.Header.Get("X-Checksum-Sha256"),
+	}, nil
+}
+
+func (client *Client) DeleteAttachment(ctx context.Context, shareId string, attachmentId string) error {
+	path := fmt.Sprintf("%s/attachments/%s", sharePath(shareId), escape(attachmentId))
+	return client.call(ctx, http.MethodDelete, path, nil, nil, nil, nil)
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"qr-pastebin-api/audit"
+	"strconv"
+)
+
+// GetAuditLog and ExportAuditLog need the audit.read permission
+func (client *Client) GetAuditLog(ctx context.Context, filter audit.Filter) ([]audit.Entry, error) {
+	var response []audit.Entry
+	if err := client.call(ctx, http.MethodGet, "/admin/audit", auditQuery(filter), nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ExportAuditLog copies every matching entry as JSON lines to w
+func (client *Client) ExportAuditLog(ctx context.Context, filter audit.Filter, w io.Writer) error {
+	response, err := client.send(ctx, http.MethodGet, "/admin/audit/export", auditQuery(filter), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, err = io.Copy(w, response.Body)
+	return err
+}
+
+func auditQuery(filter audit.Filter) url.Values {
+	values := pageQuery(filter.Limit, filter.Offset)
+	for name, value := range map[string]string{
+		"action":     filter.Action,
+		"targetType": filter.TargetType,
+		"targetId":   filter.TargetId,
+		"requestId":  filter.RequestId,
+		"from":       filter.From,
+		"to":         filter.To,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	if filter.ActorId != nil {
+		values.Set("actorId", strconv.Itoa(*filter.ActorId))
+	}
+	return values
+}
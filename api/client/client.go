@@ -0,0 +1,211 @@
+// Package client is a typed Go client for the qr-pastebin API.
+//
+//	api := client.New("https://api.example.com", client.WithSession(sessionId))
+//	created, err := api.CreateShare(ctx, shares.ShareRequest{Title: "notes", Content: "...", ExpireIn: "1_days"})
+//
+// Errors the server reports are returned as *Error, which unwraps to the error type the server
+// used where the client can recognise it, e.g. *common.NotFoundError or *shares.ExpiredShareError.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiVersionPrefix is the version of the API the client is written for
+const apiVersionPrefix = "/v1"
+
+const (
+	defaultMaxRetries = 3
+	defaultRetryDelay = 200 * time.Millisecond
+)
+
+type Client struct {
+	BaseUrl   string
+	SessionId string
+	// Language is sent as Accept-Language and picks the language of human readable texts
+	Language   string
+	HttpClient *http.Client
+	// MaxRetries is how often idempotent requests are repeated after network errors or
+	// temporary server errors, waiting RetryDelay before the first retry and twice as long after each
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+type Option func(*Client)
+
+func WithSession(sessionId string) Option {
+	return func(client *Client) {
+		client.SessionId = sessionId
+	}
+}
+
+func WithLanguage(language string) Option {
+	return func(client *Client) {
+		client.Language = language
+	}
+}
+
+func WithHttpClient(httpClient *http.Client) Option {
+	return func(client *Client) {
+		client.HttpClient = httpClient
+	}
+}
+
+func WithRetries(maxRetries int, delay time.Duration) Option {
+	return func(client *Client) {
+		client.MaxRetries = maxRetries
+		client.RetryDelay = delay
+	}
+}
+
+func New(baseUrl string, options ...Option) *Client {
+	client := &Client{
+		BaseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		HttpClient: http.DefaultClient,
+		MaxRetries: defaultMaxRetries,
+		RetryDelay: defaultRetryDelay,
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// call sends in as JSON body (unless nil) and decodes the JSON response into out (unless nil)
+func (client *Client) call(ctx context.Context, method string, path string, query url.Values, headers map[string]string, in any, out any) error {
+	var body []byte
+	if in != nil {
+		encoded, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("could not encode request body: %w", err)
+		}
+		body = encoded
+	}
+
+	response, err := client.send(ctx, method, path, query, headers, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return decode(response, out)
+}
+
+func decode(response *http.Response, out any) error {
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode response of %s %s: %w", response.Request.Method, response.Request.URL.Path, err)
+	}
+	return nil
+}
+
+// send performs the request, retrying idempotent ones. Responses with error statuses are turned
+// into errors, the caller has to close the body of a successful response.
+func (client *Client) send(ctx context.Context, method string, path string, query url.Values, headers map[string]string, body []byte) (*http.Response, error) {
+	retries := 0
+	if isIdempotent(method) {
+		retries = client.MaxRetries
+	}
+
+	delay := client.RetryDelay
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+			headers = withHeader(headers, "Content-Type", "application/json")
+		}
+		response, err := client.sendOnce(ctx, method, path, query, headers, reader)
+
+		retryable := false
+		if err != nil {
+			retryable = ctx.Err() == nil
+		} else if response.StatusCode >= 400 {
+			retryable = isRetryableStatus(response.StatusCode)
+			if !retryable || attempt >= retries {
+				defer response.Body.Close()
+				return nil, readError(response)
+			}
+			response.Body.Close()
+		} else {
+			return response, nil
+		}
+		if !retryable || attempt >= retries {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (client *Client) sendOnce(ctx context.Context, method string, path string, query url.Values, headers map[string]string, body io.Reader) (*http.Response, error) {
+	requestUrl := client.BaseUrl + apiVersionPrefix + path
+	if len(query) > 0 {
+		requestUrl += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	if client.SessionId != "" {
+		request.Header.Set("Authorization", "Bearer "+client.SessionId)
+	}
+	if client.Language != "" {
+		request.Header.Set("Accept-Language", client.Language)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+
+	httpClient := client.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("could not send %s %s: %w", method, path, err)
+	}
+	return response, nil
+}
+
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodPut || method == http.MethodDelete
+}
+
+// Rate limiting and errors of proxies in front of the API are worth another try
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+func withHeader(headers map[string]string, name string, value string) map[string]string {
+	combined := map[string]string{name: value}
+	for key, existing := range headers {
+		combined[key] = existing
+	}
+	return combined
+}
+
+func passwordHeader(password string) map[string]string {
+	if password == "" {
+		return nil
+	}
+	return map[string]string{"X-Share-Password": password}
+}
+
+func escape(segment string) string {
+	return url.PathEscape(segment)
+}
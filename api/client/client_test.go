@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"qr-pastebin-api/audit"
+	"qr-pastebin-api/common"
+	"qr-pastebin-api/shares"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return New(server.URL, WithSession("session"), WithRetries(3, time.Millisecond))
+}
+
+func TestRecognisesServerErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		target any
+	}{
+		{http.StatusNotFound, `{"status": 404, "code": "share_expired", "detail": "share is expired"}`, new(*shares.ExpiredShareError)},
+		{http.StatusNotFound, `{"status": 404, "code": "not_found", "detail": "resource not found"}`, new(*common.NotFoundError)},
+		{http.StatusUnauthorized, `{"status": 401, "code": "password_incorrect", "detail": "password is incorrect"}`, new(*common.PasswordIncorrectError)},
+		{http.StatusNotAcceptable, `{"status": 406, "code": "oauth_account", "detail": "user logged in via oauth"}`, new(*common.UserLoggedInViaOauth)},
+		{http.StatusBadRequest, `{"status": 400, "code": "validation_failed", "detail": "Request is not valid", "fields": [{"field": "title", "rule": "required", "message": "is required"}]}`, new(*common.ValidationError)},
+	}
+	for _, test := range tests {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.body))
+		})
+
+		_, err := client.GetShare(context.Background(), "abc", nil)
+		var apiErr *Error
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+			t.Errorf("%s: expected *Error with status %d, got %v", test.body, test.status, err)
+		}
+		if !errors.As(err, test.target) {
+			t.Errorf("%s: expected error to unwrap to %T, got %v", test.body, test.target, err)
+		}
+	}
+}
+
+func TestKeepsUnknownProblems(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"status": 401, "code": "authorization_required", "detail": "Authorization header is required"}`))
+	})
+
+	_, err := client.GetShares(context.Background(), "")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "authorization_required" || apiErr.Message != "Authorization header is required" {
+		t.Errorf("expected code and detail of the problem, got %v", err)
+	}
+	if apiErr != nil && apiErr.Err != nil {
+		t.Errorf("expected no known error for code %s, got %T", apiErr.Code, apiErr.Err)
+	}
+}
+
+func TestRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer session" {
+			t.Errorf("expected session to be sent, got '%s'", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"id": "abc", "title": "notes"}`))
+	})
+
+	share, err := client.GetShare(context.Background(), "abc", nil)
+	if err != nil {
+		t.Fatalf("expected request to succeed after retries, got %v", err)
+	}
+	if share.Title != "notes" || calls.Load() != 3 {
+		t.Errorf("expected share after 3 calls, got '%s' after %d", share.Title, calls.Load())
+	}
+}
+
+func TestDoesNotRetryCreate(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := client.CreateShare(context.Background(), shares.ShareRequest{Title: "notes", Content: "x", ExpireIn: "never"})
+	if err == nil || calls.Load() != 1 {
+		t.Errorf("expected a single failed call, got %d calls and %v", calls.Load(), err)
+	}
+}
+
+func TestStopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		cancel()
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	client.RetryDelay = time.Hour
+
+	_, err := client.GetThemes(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation error, got %v", err)
+	}
+}
+
+func TestSendsPasswordAndLink(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/share/abc/raw" || r.URL.Query().Get("sig") != "signature" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.Header.Get("X-Share-Password") != "secret" {
+			t.Errorf("expected password header, got '%s'", r.Header.Get("X-Share-Password"))
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("hello"))
+	})
+
+	link := &shares.SignedLink{ExpiresAt: 1, KeyId: "k1", Signature: "signature"}
+	raw, err := client.GetRawShare(context.Background(), "abc", Unlock{Link: link, Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw.Content) != "hello" || raw.MimeType != "text/plain; charset=utf-8" {
+		t.Errorf("unexpected raw share %q of type %s", raw.Content, raw.MimeType)
+	}
+}
+
+func TestSendsLanguage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Language") != "lt" {
+			t.Errorf("expected language header, got '%s'", r.Header.Get("Accept-Language"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"abc","expiresIn":"Nustos galioti po 2 dienų"}`))
+	})
+	WithLanguage("lt")(client)
+
+	share, err := client.GetShare(context.Background(), "abc", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if share.ExpiresIn != "Nustos galioti po 2 dienų" {
+		t.Errorf("unexpected expiry '%s'", share.ExpiresIn)
+	}
+}
+
+func TestReadsPublishTime(t *testing.T) {
+	publishAt := time.Date(2030, time.January, 31, 12, 0, 0, 0, time.UTC)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", publishAt.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status": 404, "code": "share_not_published", "detail": "share is not available until 2030-01-31T12:00:00Z"}`))
+	})
+
+	_, err := client.GetShare(context.Background(), "abc", nil)
+	var notPublished *shares.NotPublishedError
+	if !errors.As(err, &notPublished) || !notPublished.PublishAt.Equal(publishAt) {
+		t.Errorf("expected share to be published at %s, got %v", publishAt, err)
+	}
+}
+
+func TestExportsAuditLog(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/admin/audit/export" || r.URL.Query().Get("actorId") != "-1" || r.URL.Query().Get("action") != "login.failed" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte("{\"id\":2}\n{\"id\":1}\n"))
+	})
+
+	anonymous := -1
+	var export strings.Builder
+	err := client.ExportAuditLog(context.Background(), audit.Filter{Action: audit.ACTION_LOGIN_FAILED, ActorId: &anonymous}, &export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.String() != "{\"id\":2}\n{\"id\":1}\n" {
+		t.Errorf("unexpected export %q", export.String())
+	}
+}
+
+func TestRedeliversWebhook(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/webhooks/3/deliveries/42/redeliver" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("null"))
+	})
+
+	if err := client.RedeliverWebhook(context.Background(), 3, 42); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"qr-pastebin-api/common"
+	"qr-pastebin-api/shares"
+	"qr-pastebin-api/teams"
+	"qr-pastebin-api/users"
+)
+
+// Error is a problem the API answered with
+type Error struct {
+	StatusCode int
+	// Code is the stable identifier of the problem, e.g. share_expired
+	Code    string
+	Message string
+	Fields  []common.FieldError
+	// Err is the error type the server reported, when the client recognises the code
+	Err error
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("api responded with status %d: %s", e.StatusCode, e.Message)
+	for _, field := range e.Fields {
+		message += fmt.Sprintf("\n  %s %s", field.Field, field.Message)
+	}
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// knownErrors are the server errors without parameters by their problem code
+var knownErrors = map[string]func() error{
+	common.PROBLEM_NOT_FOUND:                    func() error { return &common.NotFoundError{} },
+	common.PROBLEM_PASSWORD_INCORRECT:           func() error { return &common.PasswordIncorrectError{} },
+	common.PROBLEM_OAUTH_ACCOUNT:                func() error { return &common.UserLoggedInViaOauth{} },
+	common.PROBLEM_SHARE_EXPIRED:                func() error { return &shares.ExpiredShareError{} },
+	common.PROBLEM_PASSWORD_REQUIRED:            func() error { return &shares.PasswordRequiredError{} },
+	common.PROBLEM_SIGNED_LINK_INVALID:          func() error { return &shares.SignedLinkInvalidError{} },
+	common.PROBLEM_LINK_SIGNING_DISABLED:        func() error { return &shares.LinkSigningDisabledError{} },
+	common.PROBLEM_LINK_EXPIRY_INVALID:          func() error { return &shares.LinkExpiryInvalidError{} },
+	common.PROBLEM_GRANT_TO_OWNER:               func() error { return &shares.GrantToOwnerError{} },
+	common.PROBLEM_TEAM_MEMBERSHIP_REQUIRED:     func() error { return &shares.TeamMembershipRequiredError{} },
+	common.PROBLEM_TEAM_MOVE_NOT_ALLOWED:        func() error { return &shares.TeamMoveNotAllowedError{} },
+	common.PROBLEM_SHARE_OWNER_REQUIRED:         func() error { return &shares.OwnerRequiredError{} },
+	common.PROBLEM_SHARE_OWNERLESS:              func() error { return &shares.OwnerlessShareError{} },
+	common.PROBLEM_PLAINTEXT_REQUIRED:           func() error { return &shares.PlaintextRequiredError{} },
+	common.PROBLEM_AUTHOR_MISMATCH:              func() error { return &shares.AuthorMismatchError{} },
+	common.PROBLEM_USER_EXISTS:                  func() error { return &users.UserAlreadyExistsError{} },
+	common.PROBLEM_TWO_FACTOR_CODE_INCORRECT:    func() error { return &users.TwoFactorCodeIncorrectError{} },
+	common.PROBLEM_TWO_FACTOR_CHALLENGE_EXPIRED: func() error { return &users.TwoFactorChallengeExpiredError{} },
+	common.PROBLEM_TWO_FACTOR_LOCKED:            func() error { return &users.TwoFactorLockedError{} },
+	common.PROBLEM_TWO_FACTOR_ENABLED:           func() error { return &users.TwoFactorAlreadyEnabledError{} },
+	common.PROBLEM_TWO_FACTOR_NOT_ENABLED:       func() error { return &users.TwoFactorNotEnabledError{} },
+	common.PROBLEM_TOKEN_INVALID:                func() error { return &users.TokenInvalidError{} },
+	common.PROBLEM_EMAIL_INVALID:                func() error { return &users.EmailInvalidError{} },
+	common.PROBLEM_EMAIL_IN_USE:                 func() error { return &users.EmailAlreadyInUseError{} },
+	common.PROBLEM_PASSWORD_EMPTY:               func() error { return &users.EmptyPasswordError{} },
+	common.PROBLEM_ACCOUNT_DISABLED:             func() error { return &users.AccountDisabledError{} },
+	common.PROBLEM_MODIFY_SELF:                  func() error { return &users.CannotModifySelfError{} },
+	common.PROBLEM_TEAM_EXISTS:                  func() error { return &teams.TeamAlreadyExistsError{} },
+	common.PROBLEM_TEAM_NAME_INVALID:            func() error { return &teams.TeamNameInvalidError{} },
+	common.PROBLEM_TEAM_PERMISSION_REQUIRED:     func() error { return &teams.TeamPermissionError{} },
+	common.PROBLEM_TEAM_LAST_OWNER:              func() error { return &teams.LastOwnerError{} },
+	common.PROBLEM_TEAM_OWNERLESS_SHARES:        func() error { return &teams.OwnerlessSharesError{} },
+}
+
+func readError(response *http.Response) error {
+	apiError := &Error{StatusCode: response.StatusCode}
+	var problem common.Problem
+	if err := json.NewDecoder(response.Body).Decode(&problem); err != nil || problem.Detail == "" {
+		apiError.Message = http.StatusText(response.StatusCode)
+	} else {
+		apiError.Message = problem.Detail
+	}
+	apiError.Code = problem.Code
+	apiError.Fields = problem.Fields
+
+	if create, known := knownErrors[problem.Code]; known {
+		apiError.Err = create()
+	} else if problem.Code == common.PROBLEM_SHARE_NOT_PUBLISHED {
+		// Retry-After carries the time the share is published at
+		publishAt, _ := http.ParseTime(response.Header.Get("Retry-After"))
+		apiError.Err = &shares.NotPublishedError{PublishAt: publishAt}
+	} else if problem.Code == common.PROBLEM_VALIDATION_FAILED {
+		apiError.Err = &common.ValidationError{Fields: problem.Fields}
+	}
+	return apiError
+}
@@ -0,0 +1,218 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"qr-pastebin-api/shares"
+	"strconv"
+)
+
+// Unlock opens a share that is private or password protected, either with a signed link or with its password
+type Unlock struct {
+	Link     *shares.SignedLink
+	Password string
+}
+
+func (unlock Unlock) query() url.Values {
+	if unlock.Link == nil {
+		return url.Values{}
+	}
+	return unlock.Link.QueryValues()
+}
+
+func (unlock Unlock) headers() map[string]string {
+	return passwordHeader(unlock.Password)
+}
+
+type RawShare struct {
+	Content  []byte
+	MimeType string
+	ETag     string
+}
+
+// CreateShare creates the share as the session user, or without an author when the client has no session
+func (client *Client) CreateShare(ctx context.Context, request shares.ShareRequest) (*shares.CreateShareResponse, error) {
+	var response shares.CreateShareResponse
+	if err := client.call(ctx, http.MethodPost, "/share", nil, nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetShare reads a share that has no password, link may be nil
+func (client *Client) GetShare(ctx context.Context, shareId string, link *shares.SignedLink) (*shares.ShareResponse, error) {
+	var response shares.ShareResponse
+	unlock := Unlock{Link: link}
+	if err := client.call(ctx, http.MethodGet, sharePath(shareId), unlock.query(), nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) IsPasswordProtected(ctx context.Context, shareId string) (bool, error) {
+	var response shares.IsPasswordProtectedResponse
+	if err := client.call(ctx, http.MethodGet, sharePath(shareId)+"/protected", nil, nil, nil, &response); err != nil {
+		return false, err
+	}
+	return response.IsPasswordProtected, nil
+}
+
+func (client *Client) GetProtectedShare(ctx context.Context, shareId string, password string) (*shares.ShareResponse, error) {
+	var response shares.ShareResponse
+	request := shares.GetProtectedShareRequest{Password: password}
+	if err := client.call(ctx, http.MethodPost, sharePath(shareId)+"/protected", nil, nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) GetRawShare(ctx context.Context, shareId string, unlock Unlock) (*RawShare, error) {
+	response, err := client.send(ctx, http.MethodGet, sharePath(shareId)+"/raw", unlock.query(), unlock.headers(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read content of share '%s': %w", shareId, err)
+	}
+	return &RawShare{Content: content, MimeType: response.Header.Get("Content-Type"), ETag: response.Header.Get("ETag")}, nil
+}
+
+// GetHighlightedShare renders the share as HTML, theme may be empty for the default one
+func (client *Client) GetHighlightedShare(ctx context.Context, shareId string, theme string, lineNumbers bool, unlock Unlock) (*shares.HighlightResponse, error) {
+	query := unlock.query()
+	if theme != "" {
+		query.Set("theme", theme)
+	}
+	if lineNumbers {
+		query.Set("lineNumbers", "true")
+	}
+
+	var response shares.HighlightResponse
+	if err := client.call(ctx, http.MethodGet, sharePath(shareId)+"/highlighted", query, unlock.headers(), nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) GetRenderedShare(ctx context.Context, shareId string, unlock Unlock) (*shares.RenderedResponse, error) {
+	var response shares.RenderedResponse
+	if err := client.call(ctx, http.MethodGet, sharePath(shareId)+"/rendered", unlock.query(), unlock.headers(), nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) GetThemes(ctx context.Context) ([]string, error) {
+	var response []string
+	if err := client.call(ctx, http.MethodGet, "/themes", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetShares lists shares of the session user, filtered by query unless it is empty
+func (client *Client) GetShares(ctx context.Context, query string) ([]shares.ShareResponse, error) {
+	values := url.Values{}
+	if query != "" {
+		values.Set("query", query)
+	}
+
+	var response []shares.ShareResponse
+	if err := client.call(ctx, http.MethodGet, "/shares", values, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetShareForEdit(ctx context.Context, shareId string) (*shares.ShareResponse, error) {
+	var response shares.ShareResponse
+	if err := client.call(ctx, http.MethodGet, sharePath(shareId)+"/edit", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) UpdateShare(ctx context.Context, shareId string, request shares.ShareRequest) error {
+	return client.call(ctx, http.MethodPatch, sharePath(shareId)+"/edit", nil, nil, request, nil)
+}
+
+func (client *Client) DeleteShare(ctx context.Context, shareId string) error {
+	return client.call(ctx, http.MethodDelete, sharePath(shareId), nil, nil, nil, nil)
+}
+
+func (client *Client) GetGrants(ctx context.Context, shareId string) ([]shares.GrantResponse, error) {
+	var response []shares.GrantResponse
+	if err := client.call(ctx, http.MethodGet, sharePath(shareId)+"/grants", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetShareStats counts views of a share, a zero request uses daily buckets over 30 days
+func (client *Client) GetShareStats(ctx context.Context, shareId string, request shares.StatsRequest) (*shares.StatsResponse, error) {
+	values := url.Values{}
+	if request.Bucket != "" {
+		values.Set("bucket", request.Bucket)
+	}
+	if request.Days > 0 {
+		values.Set("days", strconv.Itoa(request.Days))
+	}
+
+	var response shares.StatsResponse
+	if err := client.call(ctx, http.MethodGet, sharePath(shareId)+"/stats", values, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) GrantAccess(ctx context.Context, shareId string, request shares.GrantRequest) error {
+	return client.call(ctx, http.MethodPut, sharePath(shareId)+"/grants", nil, nil, request, nil)
+}
+
+func (client *Client) RevokeAccess(ctx context.Context, shareId string, userId int) error {
+	path := fmt.Sprintf("%s/grants/%d", sharePath(shareId), userId)
+	return client.call(ctx, http.MethodDelete, path, nil, nil, nil, nil)
+}
+
+func (client *Client) CreateSignedLink(ctx context.Context, shareId string, request shares.CreateLinkRequest) (*shares.CreateLinkResponse, error) {
+	var response shares.CreateLinkResponse
+	if err := client.call(ctx, http.MethodPost, sharePath(shareId)+"/link", nil, nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) RevokeSignedLinks(ctx context.Context, shareId string) error {
+	return client.call(ctx, http.MethodDelete, sharePath(shareId)+"/link", nil, nil, nil, nil)
+}
+
+// GetSharesForReview pages through all shares, it needs the moderation permission
+func (client *Client) GetSharesForReview(ctx context.Context, request shares.ReviewRequest) ([]shares.ShareResponse, error) {
+	var response []shares.ShareResponse
+	query := pageQuery(request.Limit, request.Offset)
+	if err := client.call(ctx, http.MethodGet, "/moderation/shares", query, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func sharePath(shareId string) string {
+	return "/share/" + escape(shareId)
+}
+
+func pageQuery(limit int, offset int) url.Values {
+	values := url.Values{}
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		values.Set("offset", strconv.Itoa(offset))
+	}
+	return values
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"qr-pastebin-api/shares"
+	"qr-pastebin-api/teams"
+)
+
+func (client *Client) CreateTeam(ctx context.Context, request teams.TeamRequest) (*teams.CreateTeamResponse, error) {
+	var response teams.CreateTeamResponse
+	if err := client.call(ctx, http.MethodPost, "/teams", nil, nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) GetTeams(ctx context.Context) ([]teams.TeamResponse, error) {
+	var response []teams.TeamResponse
+	if err := client.call(ctx, http.MethodGet, "/teams", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetTeam(ctx context.Context, teamId int) (*teams.TeamResponse, error) {
+	var response teams.TeamResponse
+	if err := client.call(ctx, http.MethodGet, teamPath(teamId), nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) DeleteTeam(ctx context.Context, teamId int) error {
+	return client.call(ctx, http.MethodDelete, teamPath(teamId), nil, nil, nil, nil)
+}
+
+func (client *Client) GetTeamShares(ctx context.Context, teamId int) ([]shares.ShareResponse, error) {
+	var response []shares.ShareResponse
+	if err := client.call(ctx, http.MethodGet, teamPath(teamId)+"/shares", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SetTeamMember(ctx context.Context, teamId int, request teams.MemberRequest) error {
+	return client.call(ctx, http.MethodPut, teamPath(teamId)+"/members", nil, nil, request, nil)
+}
+
+func (client *Client) RemoveTeamMember(ctx context.Context, teamId int, userId int) error {
+	path := fmt.Sprintf("%s/members/%d", teamPath(teamId), userId)
+	return client.call(ctx, http.MethodDelete, path, nil, nil, nil, nil)
+}
+
+func teamPath(teamId int) string {
+	return fmt.Sprintf("/teams/%d", teamId)
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"qr-pastebin-api/common"
+	"qr-pastebin-api/shares"
+	"qr-pastebin-api/users"
+	"strconv"
+)
+
+func (client *Client) CreateUser(ctx context.Context, credentials users.UserCredentials) error {
+	return client.call(ctx, http.MethodPost, "/user", nil, nil, credentials, nil)
+}
+
+// Login creates a session and, unless a second factor is required, uses it for further requests.
+// When TwoFactorRequired is set, finish the login with CompleteTwoFactorLogin.
+func (client *Client) Login(ctx context.Context, credentials users.UserCredentials) (*users.SessionData, error) {
+	var session users.SessionData
+	if err := client.call(ctx, http.MethodPost, "/user/session", nil, nil, credentials, &session); err != nil {
+		return nil, err
+	}
+	if !session.TwoFactorRequired {
+		client.SessionId = session.SessionId
+	}
+	return &session, nil
+}
+
+func (client *Client) CompleteTwoFactorLogin(ctx context.Context, request users.TwoFactorLoginRequest) (*users.SessionData, error) {
+	var session users.SessionData
+	if err := client.call(ctx, http.MethodPost, "/user/session/2fa", nil, nil, request, &session); err != nil {
+		return nil, err
+	}
+	client.SessionId = session.SessionId
+	return &session, nil
+}
+
+// GetSessionUser returns the user the session belongs to
+func (client *Client) GetSessionUser(ctx context.Context, sessionId string) (*common.User, error) {
+	var user common.User
+	if err := client.call(ctx, http.MethodGet, "/user/session/"+escape(sessionId), nil, nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (client *Client) GithubUserExists(ctx context.Context, githubId int) (bool, error) {
+	var response struct {
+		Exists bool `json:"exists"`
+	}
+	if err := client.call(ctx, http.MethodGet, "/oauth/github/"+strconv.Itoa(githubId), nil, nil, nil, &response); err != nil {
+		return false, err
+	}
+	return response.Exists, nil
+}
+
+func (client *Client) EnrollTwoFactor(ctx context.Context) (*users.TwoFactorEnrollment, error) {
+	var response users.TwoFactorEnrollment
+	if err := client.call(ctx, http.MethodPost, "/user/2fa", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) ActivateTwoFactor(ctx context.Context, code string) (*users.RecoveryCodesResponse, error) {
+	var response users.RecoveryCodesResponse
+	if err := client.call(ctx, http.MethodPost, "/user/2fa/activate", nil, nil, users.TwoFactorCodeRequest{Code: code}, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) DisableTwoFactor(ctx context.Context, request users.TwoFactorDisableRequest) error {
+	return client.call(ctx, http.MethodPost, "/user/2fa/disable", nil, nil, request, nil)
+}
+
+func (client *Client) RegenerateRecoveryCodes(ctx context.Context, code string) (*users.RecoveryCodesResponse, error) {
+	var response users.RecoveryCodesResponse
+	if err := client.call(ctx, http.MethodPost, "/user/2fa/recovery-codes", nil, nil, users.TwoFactorCodeRequest{Code: code}, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) ChangeEmail(ctx context.Context, email string) error {
+	return client.call(ctx, http.MethodPut, "/user/email", nil, nil, users.EmailRequest{Email: email}, nil)
+}
+
+func (client *Client) ResendVerificationEmail(ctx context.Context) error {
+	return client.call(ctx, http.MethodPost, "/user/email/verification", nil, nil, nil, nil)
+}
+
+func (client *Client) VerifyEmail(ctx context.Context, token string) error {
+	return client.call(ctx, http.MethodPost, "/user/email/verify", nil, nil, users.TokenRequest{Token: token}, nil)
+}
+
+func (client *Client) ForgotPassword(ctx context.Context, email string) error {
+	return client.call(ctx, http.MethodPost, "/user/password/forgot", nil, nil, users.EmailRequest{Email: email}, nil)
+}
+
+func (client *Client) ResetPassword(ctx context.Context, request users.ResetPasswordRequest) error {
+	return client.call(ctx, http.MethodPost, "/user/password/reset", nil, nil, request, nil)
+}
+
+// SearchUsers and the other admin calls need the user management permission
+func (client *Client) SearchUsers(ctx context.Context, request users.UserSearchRequest) ([]users.UserSummary, error) {
+	query := pageQuery(request.Limit, request.Offset)
+	if request.Query != "" {
+		query.Set("query", request.Query)
+	}
+
+	var response []users.UserSummary
+	if err := client.call(ctx, http.MethodGet, "/admin/users", query, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetUserForAdmin(ctx context.Context, userId int) (*users.UserSummary, error) {
+	var response users.UserSummary
+	if err := client.call(ctx, http.MethodGet, adminUserPath(userId), nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) GetUserSharesForAdmin(ctx context.Context, userId int) ([]shares.ShareResponse, error) {
+	var response []shares.ShareResponse
+	if err := client.call(ctx, http.MethodGet, adminUserPath(userId)+"/shares", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) ChangeUserRole(ctx context.Context, userId int, role string) error {
+	return client.call(ctx, http.MethodPut, adminUserPath(userId)+"/role", nil, nil, users.ChangeRoleRequest{Role: role}, nil)
+}
+
+func (client *Client) ChangeUserDisabled(ctx context.Context, userId int, disabled bool) error {
+	return client.call(ctx, http.MethodPut, adminUserPath(userId)+"/disabled", nil, nil, users.ChangeDisabledRequest{Disabled: disabled}, nil)
+}
+
+func (client *Client) ExpireUserSessions(ctx context.Context, userId int) error {
+	return client.call(ctx, http.MethodDelete, adminUserPath(userId)+"/sessions", nil, nil, nil, nil)
+}
+
+func (client *Client) DeleteUser(ctx context.Context, userId int) error {
+	return client.call(ctx, http.MethodDelete, adminUserPath(userId), nil, nil, nil, nil)
+}
+
+func (client *Client) Health(ctx context.Context) (*common.HealthResponse, error) {
+	var response common.HealthResponse
+	if err := client.call(ctx, http.MethodGet, "/health", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func adminUserPath(userId int) string {
+	return fmt.Sprintf("/admin/users/%d", userId)
+}
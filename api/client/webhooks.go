@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"qr-pastebin-api/webhooks"
+)
+
+// CreateWebhook returns the signing secret of the webhook, which later calls don't show again
+func (client *Client) CreateWebhook(ctx context.Context, request webhooks.WebhookRequest) (*webhooks.WebhookResponse, error) {
+	var response webhooks.WebhookResponse
+	if err := client.call(ctx, http.MethodPost, "/webhooks", nil, nil, request, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) GetWebhooks(ctx context.Context) ([]webhooks.WebhookResponse, error) {
+	var response []webhooks.WebhookResponse
+	if err := client.call(ctx, http.MethodGet, "/webhooks", nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) DeleteWebhook(ctx context.Context, webhookId int) error {
+	return client.call(ctx, http.MethodDelete, webhookPath(webhookId), nil, nil, nil, nil)
+}
+
+func (client *Client) GetWebhookDeliveries(ctx context.Context, webhookId int, request webhooks.DeliveryRequest) ([]webhooks.DeliveryResponse, error) {
+	query := pageQuery(request.Limit, request.Offset)
+	if request.Status != "" {
+		query.Set("status", request.Status)
+	}
+	var response []webhooks.DeliveryResponse
+	if err := client.call(ctx, http.MethodGet, webhookPath(webhookId)+"/deliveries", query, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetWebhookDelivery(ctx context.Context, webhookId int, deliveryId int64) (*webhooks.DeliveryResponse, error) {
+	var response webhooks.DeliveryResponse
+	if err := client.call(ctx, http.MethodGet, deliveryPath(webhookId, deliveryId), nil, nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (client *Client) RedeliverWebhook(ctx context.Context, webhookId int, deliveryId int64) error {
+	return client.call(ctx, http.MethodPost, deliveryPath(webhookId, deliveryId)+"/redeliver", nil, nil, nil, nil)
+}
+
+func webhookPath(webhookId int) string {
+	return fmt.Sprintf("/webhooks/%d", webhookId)
+}
+
+func deliveryPath(webhookId int, deliveryId int64) string {
+	return fmt.Sprintf("%s/deliveries/%d", webhookPath(webhookId), deliveryId)
+}
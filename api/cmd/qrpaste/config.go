@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"qr-pastebin-api/client"
+)
+
+const (
+	defaultApiAddress = "http://localhost:8080"
+	defaultWebAddress = "http://localhost:5173"
+)
+
+// config is kept in the user's config directory, it holds the session created by "qrpaste login"
+type config struct {
+	ApiAddress string `json:"apiAddress,omitempty"`
+	WebAddress string `json:"webAddress,omitempty"`
+	SessionId  string `json:"sessionId,omitempty"`
+	UserName   string `json:"userName,omitempty"`
+}
+
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "qrpaste", "config.json"), nil
+}
+
+func loadConfig() (*config, error) {
+	var conf config
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &conf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// save writes the config readable only by the user, as it contains the session
+func (conf *config) save() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(conf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// apiAddress prefers the flag, then QRPASTE_API, then the stored address
+func (conf *config) apiAddress(flagValue string) string {
+	return firstNonEmpty(flagValue, os.Getenv("QRPASTE_API"), conf.ApiAddress, defaultApiAddress)
+}
+
+// client talks to the chosen API with the stored session
+func (conf *config) client(apiFlag string) *client.Client {
+	return client.New(conf.apiAddress(apiFlag), client.WithSession(conf.SessionId))
+}
+
+// webAddress prefers the flag, then QRPASTE_WEB, then the stored address
+func (conf *config) webAddress(flagValue string) string {
+	return firstNonEmpty(flagValue, os.Getenv("QRPASTE_WEB"), conf.WebAddress, defaultWebAddress)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
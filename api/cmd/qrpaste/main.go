@@ -0,0 +1,341 @@
+// Command qrpaste creates shares from the command line and prints them as a QR code.
+//
+//	kubectl logs deploy/api | qrpaste --expire 1_hours
+//	qrpaste --title "nginx config" nginx.conf
+//	qrpaste get abc1234
+//	qrpaste update --expire 1_days abc1234 nginx.conf
+//	qrpaste delete abc1234
+//	qrpaste login --name jonas
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"qr-pastebin-api/client"
+	"qr-pastebin-api/shares"
+	"qr-pastebin-api/users"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const usage = `Usage:
+  qrpaste [create] [flags] [file ...]   share stdin or each file, print its link and QR code
+  qrpaste get [flags] <id>              print the content of a share
+  qrpaste update [flags] <id> [file]    change a share, new content is read from the file or stdin
+  qrpaste delete [flags] <id>           delete a share
+  qrpaste login [flags]                 log in and store the session for the other commands
+  qrpaste logout                        forget the stored session
+
+Run "qrpaste <command> -h" to see the flags of a command.
+`
+
+func main() {
+	args := os.Args[1:]
+	command := "create"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "create", "get", "update", "delete", "login", "logout":
+			command, args = args[0], args[1:]
+		case "help":
+			fmt.Print(usage)
+			return
+		}
+	}
+
+	conf, err := loadConfig()
+	if err != nil {
+		exit(fmt.Errorf("could not load config: %w", err))
+	}
+
+	switch command {
+	case "create":
+		err = create(conf, args)
+	case "get":
+		err = get(conf, args)
+	case "update":
+		err = update(conf, args)
+	case "delete":
+		err = remove(conf, args)
+	case "login":
+		err = login(conf, args)
+	case "logout":
+		conf.SessionId, conf.UserName = "", ""
+		err = conf.save()
+	}
+	if err != nil {
+		exit(err)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "qrpaste: %v\n", err)
+	os.Exit(1)
+}
+
+// shareFlags are the flags mapping to ShareRequest fields, shared by create and update
+type shareFlags struct {
+	title      *string
+	password   *string
+	expire     *string
+	publishAt  *string
+	hideAuthor *bool
+	private    *bool
+	format     *string
+	set        map[string]bool
+}
+
+func addShareFlags(flags *flag.FlagSet, defaultExpire string) *shareFlags {
+	return &shareFlags{
+		title:      flags.String("title", "", "title of the share, defaults to the file name"),
+		password:   flags.String("password", "", "password needed to open the share"),
+		expire:     flags.String("expire", defaultExpire, "how long the share lives, e.g. 10_minutes, 2_weeks, PT90M, P1DT12H or never"),
+		publishAt:  flags.String("publish-at", "", "RFC 3339 time before which only you can open the share, e.g. 2030-01-31T09:00:00+02:00"),
+		hideAuthor: flags.Bool("hide-author", false, "don't show who created the share"),
+		private:    flags.Bool("private", false, "only let the author and users it is shared with open the share"),
+		format:     flags.String("format", "", "content format, plain or markdown"),
+	}
+}
+
+// visited remembers which flags were given, so update only changes those
+func (share *shareFlags) visited(flags *flag.FlagSet) {
+	share.set = map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { share.set[f.Name] = true })
+}
+
+func connectionFlags(flags *flag.FlagSet) (*string, *string) {
+	return flags.String("api", "", "address of the API (env QRPASTE_API)"), flags.String("web", "", "address of the website used in links (env QRPASTE_WEB)")
+}
+
+func create(conf *config, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ExitOnError)
+	share := addShareFlags(flags, "never")
+	invert := flags.Bool("invert", false, "invert the QR code, for terminals with a light background")
+	apiFlag, webFlag := connectionFlags(flags)
+	flags.Parse(args)
+
+	api := conf.client(*apiFlag)
+	inputs, err := readInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	for _, input := range inputs {
+		title := *share.title
+		if title == "" {
+			title = input.name
+		}
+		request := shares.ShareRequest{
+			Title:       title,
+			Content:     input.content,
+			SetPassword: *share.password != "",
+			Password:    *share.password,
+			ExpireIn:    *share.expire,
+			PublishAt:   *share.publishAt,
+			HideAuthor:  *share.hideAuthor,
+			Format:      *share.format,
+		}
+		if *share.private {
+			request.Visibility = shares.VISIBILITY_PRIVATE
+		}
+
+		response, err := api.CreateShare(context.Background(), request)
+		if err != nil {
+			return fmt.Errorf("could not create share of %s: %w", input.name, err)
+		}
+
+		shareUrl := fmt.Sprintf("%s/%s", strings.TrimSuffix(conf.webAddress(*webFlag), "/"), response.ShareId)
+		// Scans of the QR code are counted apart from opening the printed link
+		qrCode, err := renderQrCode(shareUrl+"?src="+shares.VIEW_SOURCE_QR, *invert)
+		if err != nil {
+			return err
+		}
+		fmt.Print(qrCode)
+		fmt.Println(shareUrl)
+	}
+	return nil
+}
+
+func get(conf *config, args []string) error {
+	flags := flag.NewFlagSet("get", flag.ExitOnError)
+	password := flags.String("password", "", "password of the share")
+	apiFlag, _ := connectionFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("get needs the id of the share")
+	}
+
+	api := conf.client(*apiFlag)
+	raw, err := api.GetRawShare(context.Background(), flags.Arg(0), client.Unlock{Password: *password})
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(raw.Content)
+	return err
+}
+
+func update(conf *config, args []string) error {
+	flags := flag.NewFlagSet("update", flag.ExitOnError)
+	share := addShareFlags(flags, "no-change")
+	removePassword := flags.Bool("remove-password", false, "remove the password of the share")
+	apiFlag, _ := connectionFlags(flags)
+	flags.Parse(args)
+	share.visited(flags)
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		return fmt.Errorf("update needs the id of the share and optionally a file with the new content")
+	}
+	if conf.SessionId == "" {
+		return fmt.Errorf("updating shares needs a session, run qrpaste login first")
+	}
+
+	api := conf.client(*apiFlag)
+	shareId := flags.Arg(0)
+	current, err := api.GetShareForEdit(context.Background(), shareId)
+	if err != nil {
+		return err
+	}
+
+	request := shares.ShareRequest{
+		Title:      current.Title,
+		Content:    current.Content,
+		ExpireIn:   *share.expire,
+		PublishAt:  *share.publishAt,
+		HideAuthor: current.HideAuthor,
+		Language:   current.Language,
+		Format:     *share.format,
+	}
+	if flags.NArg() == 2 || !term.IsTerminal(int(os.Stdin.Fd())) {
+		inputs, err := readInputs(flags.Args()[1:])
+		if err != nil {
+			return err
+		}
+		request.Content = inputs[0].content
+		// The language is detected again for the new content
+		request.Language = ""
+	}
+	if share.set["title"] {
+		request.Title = *share.title
+	}
+	if share.set["hide-author"] {
+		request.HideAuthor = *share.hideAuthor
+	}
+	if share.set["private"] {
+		request.Visibility = shares.VISIBILITY_PUBLIC
+		if *share.private {
+			request.Visibility = shares.VISIBILITY_PRIVATE
+		}
+	}
+	if *share.password != "" || *removePassword {
+		request.SetPassword = true
+		request.Password = *share.password
+	}
+
+	return api.UpdateShare(context.Background(), shareId, request)
+}
+
+func remove(conf *config, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	apiFlag, _ := connectionFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("delete needs the id of the share")
+	}
+	if conf.SessionId == "" {
+		return fmt.Errorf("deleting shares needs a session, run qrpaste login first")
+	}
+
+	return conf.client(*apiFlag).DeleteShare(context.Background(), flags.Arg(0))
+}
+
+// login creates a session like the website does, asking for the second factor when it's enabled.
+// The addresses used are stored too, so later commands talk to the same server.
+func login(conf *config, args []string) error {
+	flags := flag.NewFlagSet("login", flag.ExitOnError)
+	name := flags.String("name", "", "user name")
+	apiFlag, webFlag := connectionFlags(flags)
+	flags.Parse(args)
+
+	if *name == "" {
+		*name = prompt("User name: ")
+	}
+	password, err := promptSecret("Password: ")
+	if err != nil {
+		return err
+	}
+
+	api := client.New(conf.apiAddress(*apiFlag))
+	credentials := users.UserCredentials{Name: *name, Password: password}
+	session, err := api.Login(context.Background(), credentials)
+	if err != nil {
+		return err
+	}
+	if session.TwoFactorRequired {
+		code := prompt("Authentication or recovery code: ")
+		challenge := users.TwoFactorLoginRequest{ChallengeId: session.ChallengeId, Code: code}
+		session, err = api.CompleteTwoFactorLogin(context.Background(), challenge)
+		if err != nil {
+			return err
+		}
+	}
+
+	conf.SessionId = session.SessionId
+	conf.UserName = *name
+	conf.ApiAddress = conf.apiAddress(*apiFlag)
+	conf.WebAddress = conf.webAddress(*webFlag)
+	if err := conf.save(); err != nil {
+		return fmt.Errorf("could not store session: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Logged in as %s\n", *name)
+	return nil
+}
+
+type input struct {
+	name    string
+	content string
+}
+
+// readInputs reads every file, or stdin when no file is given
+func readInputs(files []string) ([]input, error) {
+	if len(files) == 0 {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, fmt.Errorf("nothing to share, pipe content into qrpaste or pass files")
+		}
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		return []input{{name: "stdin", content: string(content)}}, nil
+	}
+
+	inputs := make([]input, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input{name: filepath.Base(file), content: string(content)})
+	}
+	return inputs, nil
+}
+
+// Prompts are written to stderr, so stdout stays clean for piping
+func prompt(label string) string {
+	fmt.Fprint(os.Stderr, label)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
+func promptSecret(label string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return prompt(label), nil
+	}
+	fmt.Fprint(os.Stderr, label)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(secret), err
+}
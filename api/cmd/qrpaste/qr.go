@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// renderQrCode draws the QR code with Unicode half blocks, two rows of modules per line of text.
+// By default light modules are drawn, which reads right on the usual dark terminal background,
+// invert draws dark modules for terminals with a light background.
+func renderQrCode(content string, invert bool) (string, error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return "", err
+	}
+	return renderBitmap(code.Bitmap(), invert), nil
+}
+
+func renderBitmap(bitmap [][]bool, invert bool) string {
+	filled := func(y int, x int) bool {
+		if y >= len(bitmap) {
+			return !invert
+		}
+		return bitmap[y][x] == invert
+	}
+
+	var rendered strings.Builder
+	for y := 0; y < len(bitmap); y += 2 {
+		for x := range bitmap[y] {
+			top, bottom := filled(y, x), filled(y+1, x)
+			switch {
+			case top && bottom:
+				rendered.WriteString("█")
+			case top:
+				rendered.WriteString("▀")
+			case bottom:
+				rendered.WriteString("▄")
+			default:
+				rendered.WriteString(" ")
+			}
+		}
+		rendered.WriteString("\n")
+	}
+	return rendered.String()
+}
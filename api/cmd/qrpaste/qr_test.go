@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderBitmap(t *testing.T) {
+	bitmap := [][]bool{
+		{true, false, true},
+		{true, true, false},
+		{false, true, false},
+	}
+
+	// Last row has no partner, the missing half is drawn like the quiet zone
+	want := " ▀▄\n█▄█\n"
+	if got := renderBitmap(bitmap, false); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	want = "█▄▀\n ▀ \n"
+	if got := renderBitmap(bitmap, true); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRenderQrCodeIsSquare(t *testing.T) {
+	rendered, err := renderQrCode("https://example.com/abc1234", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(rendered, "\n"), "\n")
+	width := len([]rune(lines[0]))
+	if len(lines) != (width+1)/2 {
+		t.Errorf("expected %d lines for a code %d modules wide, got %d", (width+1)/2, width, len(lines))
+	}
+}
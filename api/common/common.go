@@ -11,28 +11,14 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-type Role int
-
-const (
-	USER  = 0
-	ADMIN = 1
-)
-
-var roles = map[Role]string{
-	USER:  "user",
-	ADMIN: "admin",
-}
-
-func (r Role) String() string {
-	return roles[r]
-}
-
 type User struct {
-	Id           int    `json:"id"`
-	Name         string `json:"name"`
-	PasswordHash string `json:"password"`
-	Role         Role   `json:"role"`
-	IsOauth      bool   `json:"isoauth"`
+	Id           int          `json:"id"`
+	Name         string       `json:"name"`
+	PasswordHash string       `json:"password"`
+	Role         Role         `json:"role"`
+	Permissions  []Permission `json:"permissions"`
+	IsOauth      bool         `json:"isoauth"`
+	Disabled     bool         `json:"disabled"`
 }
 
 type HealthResponse struct {
@@ -70,19 +56,21 @@ func IsPasswordCorrect(passwordHash, password string) bool {
 
 func GetUserByName(db *pgx.Conn, name string) (*User, error) {
 	var user User
-	err := db.QueryRow(context.Background(), "SELECT id, name, passwordHash, role, isoauth FROM users WHERE name = $1;", name).Scan(&user.Id, &user.Name, &user.PasswordHash, &user.Role, &user.IsOauth)
+	err := db.QueryRow(context.Background(), "SELECT id, name, passwordHash, role, isoauth, disabled FROM users WHERE name = $1;", name).Scan(&user.Id, &user.Name, &user.PasswordHash, &user.Role, &user.IsOauth, &user.Disabled)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user with name '%s': %w", name, err)
 	}
+	user.Permissions = user.Role.Permissions()
 	return &user, nil
 }
 
 func GetUserById(db *pgx.Conn, id int) (*User, error) {
 	var user User
-	err := db.QueryRow(context.Background(), "SELECT id, name, passwordHash, role, isoauth FROM users WHERE id = $1;", id).Scan(&user.Id, &user.Name, &user.PasswordHash, &user.Role, &user.IsOauth)
+	err := db.QueryRow(context.Background(), "SELECT id, name, passwordHash, role, isoauth, disabled FROM users WHERE id = $1;", id).Scan(&user.Id, &user.Name, &user.PasswordHash, &user.Role, &user.IsOauth, &user.Disabled)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user with id '%d': %w", id, err)
 	}
+	user.Permissions = user.Role.Permissions()
 	return &user, nil
 }
 
@@ -1,5 +1,10 @@
 package common
 
+import (
+	"fmt"
+	"strings"
+)
+
 type NotFoundError struct {
 }
 
@@ -20,3 +25,30 @@ type UserLoggedInViaOauth struct {
 func (e *UserLoggedInViaOauth) Error() string {
 	return "user logged in via oauth"
 }
+
+// FieldError describes why a single field of a request was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		parts = append(parts, fmt.Sprintf("%s %s", field.Field, field.Message))
+	}
+	return fmt.Sprintf("request is not valid: %s", strings.Join(parts, ", "))
+}
+
+type RequestTooLargeError struct {
+	Limit int64
+}
+
+func (e *RequestTooLargeError) Error() string {
+	return fmt.Sprintf("request body is larger than the limit of %d bytes", e.Limit)
+}
@@ -0,0 +1,64 @@
+package common
+
+import "slices"
+
+type Role string
+
+const (
+	USER      Role = "user"
+	MODERATOR Role = "moderator"
+	ADMIN     Role = "admin"
+)
+
+type Permission string
+
+const (
+	SHARE_VIEW_ANY    Permission = "share.view.any"
+	SHARE_EDIT_ANY    Permission = "share.edit.any"
+	SHARE_DELETE_ANY  Permission = "share.delete.any"
+	USER_MANAGE       Permission = "user.manage"
+	MODERATION_REVIEW Permission = "moderation.review"
+	AUDIT_READ        Permission = "audit.read"
+)
+
+// Permissions every role is granted. Owners always have full access to their own shares,
+// so the share permissions here only describe access to shares of other users.
+var rolePermissions = map[Role][]Permission{
+	USER: {},
+	MODERATOR: {
+		SHARE_VIEW_ANY,
+		SHARE_DELETE_ANY,
+		MODERATION_REVIEW,
+	},
+	ADMIN: {
+		SHARE_VIEW_ANY,
+		SHARE_EDIT_ANY,
+		SHARE_DELETE_ANY,
+		USER_MANAGE,
+		MODERATION_REVIEW,
+		AUDIT_READ,
+	},
+}
+
+func (r Role) String() string {
+	return string(r)
+}
+
+// HasPermission is the only place where a role is checked, handlers should never compare role names
+func (r Role) HasPermission(permission Permission) bool {
+	return slices.Contains(rolePermissions[r], permission)
+}
+
+func (r Role) Permissions() []Permission {
+	permissions := rolePermissions[r]
+	if permissions == nil {
+		return []Permission{}
+	}
+	return permissions
+}
+
+func ParseRole(name string) (Role, bool) {
+	role := Role(name)
+	_, exists := rolePermissions[role]
+	return role, exists
+}
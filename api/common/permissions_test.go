@@ -0,0 +1,36 @@
+package common
+
+import "testing"
+
+func TestModeratorCanDeleteAnyShare(t *testing.T) {
+	if !MODERATOR.HasPermission(SHARE_DELETE_ANY) {
+		t.Errorf("expected moderator to have permission '%s'", SHARE_DELETE_ANY)
+	}
+}
+
+func TestModeratorCanNotManageUsers(t *testing.T) {
+	if MODERATOR.HasPermission(USER_MANAGE) {
+		t.Errorf("expected moderator not to have permission '%s'", USER_MANAGE)
+	}
+}
+
+func TestUserHasNoPermissions(t *testing.T) {
+	if len(USER.Permissions()) != 0 {
+		t.Errorf("expected user to have no permissions, got %v", USER.Permissions())
+	}
+}
+
+func TestUnknownRoleHasNoPermissions(t *testing.T) {
+	if Role("owner").HasPermission(SHARE_DELETE_ANY) {
+		t.Errorf("expected unknown role not to have any permissions")
+	}
+	if _, ok := ParseRole("owner"); ok {
+		t.Errorf("expected unknown role not to be parsed")
+	}
+}
+
+func TestOnlyAdminCanReadAuditLog(t *testing.T) {
+	if !ADMIN.HasPermission(AUDIT_READ) || MODERATOR.HasPermission(AUDIT_READ) {
+		t.Errorf("expected only admins to have permission '%s'", AUDIT_READ)
+	}
+}
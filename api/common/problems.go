@@ -0,0 +1,81 @@
+package common
+
+// Problem is an RFC 7807 problem details body. Code is stable and meant for programs, Detail
+// explains the problem to people and may change.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Fields   []FieldError `json:"fields,omitempty"`
+	// Debug is the underlying error, only sent when the server runs in debug mode
+	Debug string `json:"debug,omitempty"`
+}
+
+const PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+func ProblemType(code string) string {
+	return "urn:qr-pastebin:problem:" + code
+}
+
+const (
+	PROBLEM_INTERNAL               = "internal_error"
+	PROBLEM_NOT_FOUND              = "not_found"
+	PROBLEM_FORBIDDEN              = "forbidden"
+	PROBLEM_VALIDATION_FAILED      = "validation_failed"
+	PROBLEM_REQUEST_TOO_LARGE      = "request_too_large"
+	PROBLEM_AUTHORIZATION_REQUIRED = "authorization_required"
+	PROBLEM_AUTHORIZATION_INVALID  = "authorization_invalid"
+	PROBLEM_SESSION_INVALID        = "session_invalid"
+	PROBLEM_PERMISSION_REQUIRED    = "permission_required"
+
+	PROBLEM_PASSWORD_INCORRECT           = "password_incorrect"
+	PROBLEM_PASSWORD_EMPTY               = "password_empty"
+	PROBLEM_OAUTH_ACCOUNT                = "oauth_account"
+	PROBLEM_USER_EXISTS                  = "user_exists"
+	PROBLEM_ACCOUNT_DISABLED             = "account_disabled"
+	PROBLEM_MODIFY_SELF                  = "modify_self"
+	PROBLEM_ROLE_UNKNOWN                 = "role_unknown"
+	PROBLEM_TWO_FACTOR_CODE_INCORRECT    = "two_factor_code_incorrect"
+	PROBLEM_TWO_FACTOR_CHALLENGE_EXPIRED = "two_factor_challenge_expired"
+	PROBLEM_TWO_FACTOR_LOCKED            = "two_factor_locked"
+	PROBLEM_TWO_FACTOR_ENABLED           = "two_factor_enabled"
+	PROBLEM_TWO_FACTOR_NOT_ENABLED       = "two_factor_not_enabled"
+	PROBLEM_TOKEN_INVALID                = "token_invalid"
+	PROBLEM_EMAIL_INVALID                = "email_invalid"
+	PROBLEM_EMAIL_IN_USE                 = "email_in_use"
+
+	PROBLEM_SHARE_EXPIRED            = "share_expired"
+	PROBLEM_SHARE_NOT_PUBLISHED      = "share_not_published"
+	PROBLEM_PASSWORD_REQUIRED        = "password_required"
+	PROBLEM_ACCESS_INVALID           = "access_invalid"
+	PROBLEM_VISIBILITY_INVALID       = "visibility_invalid"
+	PROBLEM_GRANT_TO_OWNER           = "grant_to_owner"
+	PROBLEM_TEAM_MEMBERSHIP_REQUIRED = "team_membership_required"
+	PROBLEM_TEAM_MOVE_NOT_ALLOWED    = "team_move_not_allowed"
+	PROBLEM_SHARE_OWNER_REQUIRED     = "share_owner_required"
+	PROBLEM_SHARE_OWNERLESS          = "share_ownerless"
+	PROBLEM_SIGNED_LINK_INVALID      = "signed_link_invalid"
+	PROBLEM_LINK_SIGNING_DISABLED    = "link_signing_disabled"
+	PROBLEM_LINK_EXPIRY_INVALID      = "link_expiry_invalid"
+	PROBLEM_ENCRYPTION_UNSUPPORTED   = "encryption_unsupported"
+	PROBLEM_CIPHERTEXT_INVALID       = "ciphertext_invalid"
+	PROBLEM_PLAINTEXT_REQUIRED       = "plaintext_required"
+	PROBLEM_MIME_TYPE_INVALID        = "mime_type_invalid"
+	PROBLEM_ATTACHMENT_TOO_LARGE     = "attachment_too_large"
+	PROBLEM_ATTACHMENT_QUOTA         = "attachment_quota_exceeded"
+	PROBLEM_LANGUAGE_UNSUPPORTED     = "language_unsupported"
+	PROBLEM_THEME_UNKNOWN            = "theme_unknown"
+	PROBLEM_FORMAT_INVALID           = "format_invalid"
+	PROBLEM_STORAGE_QUOTA            = "storage_quota_exceeded"
+	PROBLEM_AUTHOR_MISMATCH          = "author_mismatch"
+
+	PROBLEM_TEAM_EXISTS              = "team_exists"
+	PROBLEM_TEAM_NAME_INVALID        = "team_name_invalid"
+	PROBLEM_TEAM_ROLE_INVALID        = "team_role_invalid"
+	PROBLEM_TEAM_PERMISSION_REQUIRED = "team_permission_required"
+	PROBLEM_TEAM_LAST_OWNER          = "team_last_owner"
+	PROBLEM_TEAM_OWNERLESS_SHARES    = "team_ownerless_shares"
+)
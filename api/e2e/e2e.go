@@ -0,0 +1,130 @@
+// Package e2e implements the client-side encryption format of end-to-end encrypted shares.
+//
+// Content is encrypted with AES-256-GCM and sent as base64url (without padding) of the nonce
+// followed by the ciphertext. The key never reaches the server, it travels in the fragment of
+// the share URL, which browsers do not send in requests.
+package e2e
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const Algorithm = "AES-256-GCM"
+
+const (
+	KeySize   = 32
+	NonceSize = 12
+	tagSize   = 16
+)
+
+var encoding = base64.RawURLEncoding
+
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("could not generate key: %w", err)
+	}
+	return key, nil
+}
+
+func Encrypt(key []byte, plaintext []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("could not generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	return encoding.EncodeToString(sealed), nil
+}
+
+func Decrypt(key []byte, content string) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := encoding.DecodeString(content)
+	if err != nil {
+		return nil, fmt.Errorf("content is not valid base64url: %w", err)
+	}
+	if len(sealed) < NonceSize+tagSize {
+		return nil, errors.New("content is too short to be encrypted")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:NonceSize], sealed[NonceSize:], nil)
+	if err != nil {
+		return nil, errors.New("content could not be decrypted, the key is wrong or the content was changed")
+	}
+	return plaintext, nil
+}
+
+// Validate checks that content looks like output of Encrypt, it can't tell whether it decrypts
+func Validate(content string) error {
+	sealed, err := encoding.DecodeString(content)
+	if err != nil {
+		return fmt.Errorf("content is not valid base64url: %w", err)
+	}
+	if len(sealed) < NonceSize+tagSize {
+		return errors.New("content is too short to be encrypted")
+	}
+	return nil
+}
+
+func EncodeKey(key []byte) string {
+	return encoding.EncodeToString(key)
+}
+
+func DecodeKey(encoded string) ([]byte, error) {
+	key, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64url: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes long, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// ShareUrl puts the key into the fragment of the share URL
+func ShareUrl(baseUrl string, shareId string, key []byte) string {
+	return fmt.Sprintf("%s/%s#%s", strings.TrimSuffix(baseUrl, "/"), shareId, EncodeKey(key))
+}
+
+// ParseShareUrl returns the share id and key from a URL made by ShareUrl
+func ParseShareUrl(shareUrl string) (string, []byte, error) {
+	parsed, err := url.Parse(shareUrl)
+	if err != nil {
+		return "", nil, err
+	}
+	if parsed.Fragment == "" {
+		return "", nil, errors.New("share url has no key in its fragment")
+	}
+	key, err := DecodeKey(parsed.Fragment)
+	if err != nil {
+		return "", nil, err
+	}
+	shareId := parsed.Path[strings.LastIndex(parsed.Path, "/")+1:]
+	return shareId, key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes long, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
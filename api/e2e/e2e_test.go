@@ -0,0 +1,61 @@
+package e2e
+
+import (
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "kubectl logs output"
+	content, err := Encrypt(key, []byte(want))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Validate(content); err != nil {
+		t.Errorf("expected encrypted content to be valid, got %v", err)
+	}
+
+	got, err := Decrypt(key, content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf(`expected "%s", got "%s"`, want, got)
+	}
+}
+
+func TestDecryptWithWrongKey(t *testing.T) {
+	key, _ := GenerateKey()
+	otherKey, _ := GenerateKey()
+	content, _ := Encrypt(key, []byte("secret"))
+	if _, err := Decrypt(otherKey, content); err == nil {
+		t.Errorf("expected decryption with another key to fail")
+	}
+}
+
+func TestValidateRejectsPlaintext(t *testing.T) {
+	if err := Validate("hello world"); err == nil {
+		t.Errorf("expected plaintext to be rejected")
+	}
+	if err := Validate("aGVsbG8"); err == nil {
+		t.Errorf("expected too short content to be rejected")
+	}
+}
+
+func TestShareUrl(t *testing.T) {
+	key, _ := GenerateKey()
+	shareUrl := ShareUrl("https://localhost:5173/", "abc1234", key)
+	shareId, parsedKey, err := ParseShareUrl(shareUrl)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if shareId != "abc1234" {
+		t.Errorf(`expected "abc1234", got "%s"`, shareId)
+	}
+	if EncodeKey(parsedKey) != EncodeKey(key) {
+		t.Errorf("expected key to survive the round trip")
+	}
+}
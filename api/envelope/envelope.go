@@ -0,0 +1,173 @@
+// Package envelope implements envelope encryption of data stored in the database.
+//
+// Every record is encrypted with its own random data key. The data key is stored next to the
+// record, wrapped (encrypted) by a master key from the configuration, together with the id of
+// that master key. Rotating the master key only requires re-wrapping the data keys.
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const keySize = 32
+
+type Keyring struct {
+	activeKeyId string
+	keys        map[string][]byte
+}
+
+// NewKeyring parses master keys formatted as "id:base64key,id:base64key". The first key wraps new
+// data keys, the others are kept to unwrap data keys until they are rotated.
+func NewKeyring(keys string) (*Keyring, error) {
+	keyring := &Keyring{keys: map[string][]byte{}}
+	if strings.TrimSpace(keys) == "" {
+		return keyring, nil
+	}
+
+	for i, key := range strings.Split(keys, ",") {
+		parts := strings.SplitN(strings.TrimSpace(key), ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("master key #%d is not of correct format, make sure it is formatted as 'id:base64key'", i+1)
+		}
+		if _, exists := keyring.keys[parts[0]]; exists {
+			return nil, fmt.Errorf("master key id '%s' is used more than once", parts[0])
+		}
+		decoded, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("master key '%s' is not valid base64: %w", parts[0], err)
+		}
+		if len(decoded) != keySize {
+			return nil, fmt.Errorf("master key '%s' must be %d bytes long, got %d", parts[0], keySize, len(decoded))
+		}
+		keyring.keys[parts[0]] = decoded
+		if i == 0 {
+			keyring.activeKeyId = parts[0]
+		}
+	}
+	return keyring, nil
+}
+
+func NewKeyringFromEnv() (*Keyring, error) {
+	return NewKeyring(os.Getenv("SHARE_MASTER_KEYS"))
+}
+
+// Enabled is false when no master key is configured, then data is stored unencrypted
+func (keyring *Keyring) Enabled() bool {
+	return keyring != nil && keyring.activeKeyId != ""
+}
+
+func (keyring *Keyring) ActiveKeyId() string {
+	if keyring == nil {
+		return ""
+	}
+	return keyring.activeKeyId
+}
+
+// NewDataKey returns a fresh data key, the same key wrapped by the active master key and that key's id
+func (keyring *Keyring) NewDataKey() ([]byte, string, string, error) {
+	if !keyring.Enabled() {
+		return nil, "", "", errors.New("no master key is configured")
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, "", "", fmt.Errorf("could not generate data key: %w", err)
+	}
+	wrapped, err := Seal(keyring.keys[keyring.activeKeyId], dataKey, []byte(keyring.activeKeyId))
+	if err != nil {
+		return nil, "", "", err
+	}
+	return dataKey, wrapped, keyring.activeKeyId, nil
+}
+
+func (keyring *Keyring) UnwrapDataKey(keyId string, wrapped string) ([]byte, error) {
+	if keyring == nil {
+		return nil, fmt.Errorf("master key '%s' is not configured", keyId)
+	}
+	masterKey, exists := keyring.keys[keyId]
+	if !exists {
+		return nil, fmt.Errorf("master key '%s' is not configured", keyId)
+	}
+	dataKey, err := Open(masterKey, wrapped, []byte(keyId))
+	if err != nil {
+		return nil, fmt.Errorf("could not unwrap data key with master key '%s': %w", keyId, err)
+	}
+	return dataKey, nil
+}
+
+// RewrapDataKey wraps an existing data key with the active master key, the encrypted data stays as it is
+func (keyring *Keyring) RewrapDataKey(keyId string, wrapped string) (string, string, error) {
+	dataKey, err := keyring.UnwrapDataKey(keyId, wrapped)
+	if err != nil {
+		return "", "", err
+	}
+	if !keyring.Enabled() {
+		return "", "", errors.New("no master key is configured")
+	}
+	rewrapped, err := Seal(keyring.keys[keyring.activeKeyId], dataKey, []byte(keyring.activeKeyId))
+	if err != nil {
+		return "", "", err
+	}
+	return rewrapped, keyring.activeKeyId, nil
+}
+
+// Seal encrypts with AES-256-GCM. The additional data is authenticated but not stored, so
+// ciphertext can't be moved to a place with different additional data.
+func Seal(key []byte, plaintext []byte, additionalData []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("could not generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plaintext, additionalData)), nil
+}
+
+func Open(key []byte, ciphertext string, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("ciphertext is not valid base64: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes long, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// RetiredKeyIds returns ids of the configured master keys that no longer wrap new data keys
+func (keyring *Keyring) RetiredKeyIds() []string {
+	ids := make([]string, 0)
+	if keyring == nil {
+		return ids
+	}
+	for id := range keyring.keys {
+		if id != keyring.activeKeyId {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
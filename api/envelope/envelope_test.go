@@ -0,0 +1,79 @@
+package envelope
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+var firstKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", keySize)))
+var secondKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", keySize)))
+
+func TestDataKeyRoundTrip(t *testing.T) {
+	keyring, err := NewKeyring("k1:" + firstKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dataKey, wrapped, keyId, err := keyring.NewDataKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ciphertext, _ := Seal(dataKey, []byte("paste content"), []byte("abc1234/content"))
+	unwrapped, err := keyring.UnwrapDataKey(keyId, wrapped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plaintext, err := Open(unwrapped, ciphertext, []byte("abc1234/content"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(plaintext) != "paste content" {
+		t.Errorf(`expected "paste content", got "%s"`, plaintext)
+	}
+}
+
+func TestAdditionalDataMustMatch(t *testing.T) {
+	keyring, _ := NewKeyring("k1:" + firstKey)
+	dataKey, _, _, _ := keyring.NewDataKey()
+	ciphertext, _ := Seal(dataKey, []byte("paste content"), []byte("abc1234/content"))
+	if _, err := Open(dataKey, ciphertext, []byte("abc1234/title")); err == nil {
+		t.Errorf("expected ciphertext moved to another field to be rejected")
+	}
+}
+
+func TestRewrapWithRotatedKey(t *testing.T) {
+	oldKeyring, _ := NewKeyring("k1:" + firstKey)
+	dataKey, wrapped, keyId, _ := oldKeyring.NewDataKey()
+
+	rotatedKeyring, _ := NewKeyring("k2:" + secondKey + ",k1:" + firstKey)
+	rewrapped, newKeyId, err := rotatedKeyring.RewrapDataKey(keyId, wrapped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if newKeyId != "k2" {
+		t.Errorf(`expected "k2", got "%s"`, newKeyId)
+	}
+
+	retiredKeyring, _ := NewKeyring("k2:" + secondKey)
+	unwrapped, err := retiredKeyring.UnwrapDataKey(newKeyId, rewrapped)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(unwrapped) != string(dataKey) {
+		t.Errorf("expected rewrapped data key to stay the same")
+	}
+}
+
+func TestKeyringFormat(t *testing.T) {
+	if _, err := NewKeyring("k1:c2hvcnQ="); err == nil {
+		t.Errorf("expected short master key to be rejected")
+	}
+	keyring, err := NewKeyring("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keyring.Enabled() {
+		t.Errorf("expected keyring without keys to be disabled")
+	}
+}
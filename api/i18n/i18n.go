@@ -0,0 +1,181 @@
+// Package i18n picks the language of texts meant for people and knows how each language
+// pluralises. Clients choose the language with the Accept-Language header.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Locale string
+
+const (
+	ENGLISH    Locale = "en"
+	LITHUANIAN Locale = "lt"
+	GERMAN     Locale = "de"
+)
+
+const DEFAULT_LOCALE = ENGLISH
+
+// PluralCategory follows the CLDR plural categories, a language only uses some of them
+type PluralCategory int
+
+const (
+	OTHER PluralCategory = iota
+	ONE
+	FEW
+)
+
+type Unit int
+
+const (
+	YEAR Unit = iota
+	MONTH
+	DAY
+	HOUR
+	MINUTE
+)
+
+// Translation holds the texts of a locale. Units are worded to follow ExpiresIn, which is why
+// Lithuanian uses the genitive ("po 2 dienų") and German the dative ("in 2 Tagen").
+type Translation struct {
+	Expired         string
+	ExpiresIn       string
+	ExpiresInMinute string
+	// Parts of a duration are joined with Separator, the last one with LastSeparator
+	Separator     string
+	LastSeparator string
+	Units         map[Unit]map[PluralCategory]string
+}
+
+var translations = map[Locale]Translation{
+	ENGLISH: {
+		Expired:         "Already expired",
+		ExpiresIn:       "Expires in %s",
+		ExpiresInMinute: "Expires in less than a minute",
+		Separator:       " ",
+		LastSeparator:   " and ",
+		Units: map[Unit]map[PluralCategory]string{
+			YEAR:   {ONE: "year", OTHER: "years"},
+			MONTH:  {ONE: "month", OTHER: "months"},
+			DAY:    {ONE: "day", OTHER: "days"},
+			HOUR:   {ONE: "hour", OTHER: "hours"},
+			MINUTE: {ONE: "minute", OTHER: "minutes"},
+		},
+	},
+	LITHUANIAN: {
+		Expired:         "Jau nebegalioja",
+		ExpiresIn:       "Nustos galioti po %s",
+		ExpiresInMinute: "Nustos galioti greičiau nei po minutės",
+		Separator:       ", ",
+		LastSeparator:   " ir ",
+		Units: map[Unit]map[PluralCategory]string{
+			YEAR:   {ONE: "metų", OTHER: "metų"},
+			MONTH:  {ONE: "mėnesio", OTHER: "mėnesių"},
+			DAY:    {ONE: "dienos", OTHER: "dienų"},
+			HOUR:   {ONE: "valandos", OTHER: "valandų"},
+			MINUTE: {ONE: "minutės", OTHER: "minučių"},
+		},
+	},
+	GERMAN: {
+		Expired:         "Bereits abgelaufen",
+		ExpiresIn:       "Läuft in %s ab",
+		ExpiresInMinute: "Läuft in weniger als einer Minute ab",
+		Separator:       ", ",
+		LastSeparator:   " und ",
+		Units: map[Unit]map[PluralCategory]string{
+			YEAR:   {ONE: "Jahr", OTHER: "Jahren"},
+			MONTH:  {ONE: "Monat", OTHER: "Monaten"},
+			DAY:    {ONE: "Tag", OTHER: "Tagen"},
+			HOUR:   {ONE: "Stunde", OTHER: "Stunden"},
+			MINUTE: {ONE: "Minute", OTHER: "Minuten"},
+		},
+	},
+}
+
+func (locale Locale) Translation() Translation {
+	translation, exists := translations[locale]
+	if !exists {
+		return translations[DEFAULT_LOCALE]
+	}
+	return translation
+}
+
+// Plural picks the category of a whole number
+func (locale Locale) Plural(n int) PluralCategory {
+	if n < 0 {
+		n = -n
+	}
+	switch locale {
+	case LITHUANIAN:
+		teens := n%100 >= 11 && n%100 <= 19
+		if n%10 == 1 && !teens {
+			return ONE
+		}
+		if n%10 >= 2 && !teens {
+			return FEW
+		}
+		return OTHER
+	default:
+		if n == 1 {
+			return ONE
+		}
+		return OTHER
+	}
+}
+
+// Count words a number of units, e.g. "2 days"
+func (locale Locale) Count(unit Unit, n int) string {
+	forms := locale.Translation().Units[unit]
+	form, exists := forms[locale.Plural(n)]
+	if !exists {
+		form = forms[OTHER]
+	}
+	return fmt.Sprintf("%d %s", n, form)
+}
+
+// List joins parts like "1 year, 2 months and 3 days"
+func (locale Locale) List(parts []string) string {
+	translation := locale.Translation()
+	if len(parts) <= 1 {
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts[:len(parts)-1], translation.Separator) + translation.LastSeparator + parts[len(parts)-1]
+}
+
+// Negotiate picks the supported locale the client prefers most, by the q-values of Accept-Language
+func Negotiate(acceptLanguage string) Locale {
+	type candidate struct {
+		locale  Locale
+		quality float64
+	}
+	candidates := make([]candidate, 0)
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		tag, parameters, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		quality := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(parameters), "q="); found {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		locale := Locale(primary)
+		if primary == "*" {
+			locale = DEFAULT_LOCALE
+		}
+		if _, supported := translations[locale]; supported && quality > 0 {
+			candidates = append(candidates, candidate{locale, quality})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return DEFAULT_LOCALE
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	return candidates[0].locale
+}
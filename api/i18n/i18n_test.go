@@ -0,0 +1,72 @@
+package i18n
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected Locale
+	}{
+		{"", ENGLISH},
+		{"lt", LITHUANIAN},
+		{"de-DE,de;q=0.9,en;q=0.8", GERMAN},
+		{"fr-FR,lt;q=0.5,de;q=0.7", GERMAN},
+		{"fr, en-GB;q=0.1", ENGLISH},
+		{"LT-lt", LITHUANIAN},
+		{"de;q=0, lt;q=0.2", LITHUANIAN},
+		{"*", ENGLISH},
+		{"fr", ENGLISH},
+		{"de;q=abc", ENGLISH},
+	}
+	for _, test := range tests {
+		if got := Negotiate(test.header); got != test.expected {
+			t.Errorf(`"%s": expected %s, got %s`, test.header, test.expected, got)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		locale   Locale
+		n        int
+		expected PluralCategory
+	}{
+		{ENGLISH, 0, OTHER},
+		{ENGLISH, 1, ONE},
+		{ENGLISH, 2, OTHER},
+		{ENGLISH, 21, OTHER},
+		{GERMAN, 1, ONE},
+		{GERMAN, 11, OTHER},
+		{LITHUANIAN, 1, ONE},
+		{LITHUANIAN, 21, ONE},
+		{LITHUANIAN, 11, OTHER},
+		{LITHUANIAN, 2, FEW},
+		{LITHUANIAN, 29, FEW},
+		{LITHUANIAN, 12, OTHER},
+		{LITHUANIAN, 10, OTHER},
+		{LITHUANIAN, 111, OTHER},
+	}
+	for _, test := range tests {
+		if got := test.locale.Plural(test.n); got != test.expected {
+			t.Errorf("%s %d: expected category %d, got %d", test.locale, test.n, test.expected, got)
+		}
+	}
+}
+
+func TestCountAndList(t *testing.T) {
+	tests := []struct {
+		locale   Locale
+		parts    []string
+		expected string
+	}{
+		{ENGLISH, []string{ENGLISH.Count(YEAR, 1), ENGLISH.Count(DAY, 3)}, "1 year and 3 days"},
+		{GERMAN, []string{GERMAN.Count(YEAR, 2), GERMAN.Count(MONTH, 1), GERMAN.Count(DAY, 5)}, "2 Jahren, 1 Monat und 5 Tagen"},
+		{LITHUANIAN, []string{LITHUANIAN.Count(HOUR, 21), LITHUANIAN.Count(MINUTE, 12)}, "21 valandos ir 12 minučių"},
+		{LITHUANIAN, []string{LITHUANIAN.Count(DAY, 2)}, "2 dienų"},
+	}
+	for _, test := range tests {
+		if got := test.locale.List(test.parts); got != test.expected {
+			t.Errorf(`%s: expected "%s", got "%s"`, test.locale, test.expected, got)
+		}
+	}
+}
@@ -2,8 +2,16 @@ CREATE TABLE public.users (
 	id int NOT NULL,
 	"name" text NOT NULL,
 	passwordhash text NOT NULL,
-	"role" int DEFAULT 0 NOT NULL,
+	"role" text DEFAULT 'user' NOT NULL,
 	"isoauth" bool DEFAULT false NOT NULL,
+	totp_secret text DEFAULT '' NOT NULL,
+	totp_enabled bool DEFAULT false NOT NULL,
+	totp_last_step bigint DEFAULT 0 NOT NULL,
+	totp_failures int DEFAULT 0 NOT NULL,
+	totp_locked_until timestamp with time zone,
+	email text DEFAULT '' NOT NULL,
+	email_verified bool DEFAULT false NOT NULL,
+	disabled bool DEFAULT false NOT NULL,
 	CONSTRAINT users_pk PRIMARY KEY (id)
 );
 
@@ -15,6 +23,18 @@ CREATE TABLE public.shares (
 	expire_at timestamp with time zone NOT NULL,
 	author_id int NOT NULL,
 	hide_author bool DEFAULT false NOT NULL,
+	created_at timestamp with time zone DEFAULT now() NOT NULL,
+	visibility text DEFAULT 'public' NOT NULL,
+	team_id int DEFAULT -1 NOT NULL,
+	link_version int DEFAULT 0 NOT NULL,
+	"encryption" text DEFAULT '' NOT NULL,
+	data_key text DEFAULT '' NOT NULL,
+	key_id text DEFAULT '' NOT NULL,
+	mime_type text DEFAULT 'text/plain' NOT NULL,
+	"language" text DEFAULT '' NOT NULL,
+	"format" text DEFAULT 'plain' NOT NULL,
+	content_size bigint DEFAULT 0 NOT NULL,
+	publish_at timestamp with time zone DEFAULT '0001-01-01 00:00:00+00' NOT NULL,
 	CONSTRAINT shares_pk PRIMARY KEY (id)
 );
 
@@ -23,4 +43,141 @@ CREATE TABLE public.sessions (
 	user_id int NOT NULL,
 	expire_at timestamp with time zone NOT NULL,
 	CONSTRAINT sessions_pk PRIMARY KEY (session_id)
-);
\ No newline at end of file
+);
+
+CREATE TABLE public.recovery_codes (
+	id serial NOT NULL,
+	user_id int NOT NULL,
+	code_hash text NOT NULL,
+	CONSTRAINT recovery_codes_pk PRIMARY KEY (id)
+);
+
+CREATE TABLE public.login_challenges (
+	challenge_id text NOT NULL,
+	user_id int NOT NULL,
+	attempts int DEFAULT 0 NOT NULL,
+	expire_at timestamp with time zone NOT NULL,
+	CONSTRAINT login_challenges_pk PRIMARY KEY (challenge_id)
+);
+
+CREATE TABLE public.user_tokens (
+	token_hash text NOT NULL,
+	user_id int NOT NULL,
+	purpose text NOT NULL,
+	expire_at timestamp with time zone NOT NULL,
+	CONSTRAINT user_tokens_pk PRIMARY KEY (token_hash)
+);
+
+CREATE TABLE public.share_grants (
+	share_id text NOT NULL,
+	user_id int NOT NULL,
+	"access" text NOT NULL,
+	CONSTRAINT share_grants_pk PRIMARY KEY (share_id, user_id)
+);
+
+CREATE TABLE public.teams (
+	id serial NOT NULL,
+	"name" text NOT NULL,
+	created_at timestamp with time zone DEFAULT now() NOT NULL,
+	CONSTRAINT teams_pk PRIMARY KEY (id),
+	CONSTRAINT teams_name_unique UNIQUE ("name")
+);
+
+CREATE TABLE public.team_members (
+	team_id int NOT NULL,
+	user_id int NOT NULL,
+	"role" text DEFAULT 'member' NOT NULL,
+	CONSTRAINT team_members_pk PRIMARY KEY (team_id, user_id)
+);
+CREATE TABLE public.attachments (
+	id text NOT NULL,
+	share_id text NOT NULL,
+	file_name text NOT NULL,
+	mime_type text NOT NULL,
+	"size" bigint NOT NULL,
+	checksum text NOT NULL,
+	storage_key text NOT NULL,
+	created_at timestamp with time zone DEFAULT now() NOT NULL,
+	CONSTRAINT attachments_pk PRIMARY KEY (id)
+);
+
+CREATE TABLE public.share_views (
+	id bigserial NOT NULL,
+	share_id text NOT NULL,
+	viewed_at timestamp with time zone NOT NULL,
+	agent text NOT NULL,
+	referrer text DEFAULT '' NOT NULL,
+	ip text DEFAULT '' NOT NULL,
+	from_qr bool DEFAULT false NOT NULL,
+	CONSTRAINT share_views_pk PRIMARY KEY (id)
+);
+CREATE INDEX share_views_share_id_idx ON public.share_views (share_id, viewed_at);
+
+CREATE TABLE public.audit_log (
+	id bigserial NOT NULL,
+	at timestamp with time zone DEFAULT now() NOT NULL,
+	"action" text NOT NULL,
+	actor_id int NOT NULL,
+	target_type text DEFAULT '' NOT NULL,
+	target_id text DEFAULT '' NOT NULL,
+	ip text DEFAULT '' NOT NULL,
+	request_id text DEFAULT '' NOT NULL,
+	detail text DEFAULT '' NOT NULL,
+	CONSTRAINT audit_log_pk PRIMARY KEY (id)
+);
+CREATE INDEX audit_log_actor_id_idx ON public.audit_log (actor_id);
+CREATE INDEX audit_log_target_idx ON public.audit_log (target_type, target_id);
+
+-- The audit log is append-only, entries can't be changed or removed
+CREATE FUNCTION public.audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON public.audit_log
+	FOR EACH ROW EXECUTE FUNCTION public.audit_log_append_only();
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON public.audit_log
+	FOR EACH STATEMENT EXECUTE FUNCTION public.audit_log_append_only();
+
+CREATE INDEX shares_expire_at_idx ON public.shares (expire_at);
+
+CREATE TABLE public.webhooks (
+	id serial NOT NULL,
+	user_id int NOT NULL,
+	url text NOT NULL,
+	secret text NOT NULL,
+	events text[] NOT NULL,
+	created_at timestamp with time zone DEFAULT now() NOT NULL,
+	CONSTRAINT webhooks_pk PRIMARY KEY (id)
+);
+CREATE INDEX webhooks_user_id_idx ON public.webhooks (user_id);
+
+-- Outbox of webhook deliveries, sent and retried by the dispatcher of any API instance
+CREATE TABLE public.webhook_deliveries (
+	id bigserial NOT NULL,
+	webhook_id int NOT NULL,
+	event_id text NOT NULL,
+	"event" text NOT NULL,
+	payload text NOT NULL,
+	status text DEFAULT 'pending' NOT NULL,
+	attempts int DEFAULT 0 NOT NULL,
+	next_attempt_at timestamp with time zone NOT NULL,
+	delivered_at timestamp with time zone DEFAULT '0001-01-01 00:00:00+00' NOT NULL,
+	created_at timestamp with time zone DEFAULT now() NOT NULL,
+	CONSTRAINT webhook_deliveries_pk PRIMARY KEY (id),
+	CONSTRAINT webhook_deliveries_event_unique UNIQUE (webhook_id, event_id)
+);
+CREATE INDEX webhook_deliveries_due_idx ON public.webhook_deliveries (status, next_attempt_at);
+
+CREATE TABLE public.webhook_attempts (
+	id bigserial NOT NULL,
+	delivery_id bigint NOT NULL,
+	attempted_at timestamp with time zone NOT NULL,
+	status_code int DEFAULT 0 NOT NULL,
+	error text DEFAULT '' NOT NULL,
+	response text DEFAULT '' NOT NULL,
+	duration_ms bigint NOT NULL,
+	CONSTRAINT webhook_attempts_pk PRIMARY KEY (id)
+);
+CREATE INDEX webhook_attempts_delivery_id_idx ON public.webhook_attempts (delivery_id);
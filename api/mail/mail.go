@@ -0,0 +1,104 @@
+package mail
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(message Message) error
+}
+
+// NewMailerFromEnv picks the mail delivery based on MAIL_DRIVER, "smtp" or "log". Mails carry
+// login tokens, so they are only written to a log when that is asked for, e.g. for local development.
+func NewMailerFromEnv() (Mailer, error) {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		mailer := &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}
+		if mailer.Host == "" || mailer.Port == "" || mailer.From == "" {
+			return nil, fmt.Errorf("the smtp mail driver needs SMTP_HOST, SMTP_PORT and MAIL_FROM")
+		}
+		return mailer, nil
+	case "log":
+		return &LogMailer{Path: os.Getenv("MAIL_LOG_PATH")}, nil
+	case "":
+		return nil, fmt.Errorf("MAIL_DRIVER is not set, use 'smtp' or 'log'")
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER '%s', use 'smtp' or 'log'", driver)
+	}
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (mailer *SMTPMailer) Send(message Message) error {
+	var auth smtp.Auth
+	if mailer.Username != "" {
+		auth = smtp.PlainAuth("", mailer.Username, mailer.Password, mailer.Host)
+	}
+
+	address := fmt.Sprintf("%s:%s", mailer.Host, mailer.Port)
+	err := smtp.SendMail(address, auth, mailer.From, []string{message.To}, formatMessage(mailer.From, message))
+	if err != nil {
+		return fmt.Errorf("could not send mail to '%s': %w", message.To, err)
+	}
+	return nil
+}
+
+// LogMailer appends every message to a file, or prints it to standard output when no path is set
+type LogMailer struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (mailer *LogMailer) Send(message Message) error {
+	mailer.mu.Lock()
+	defer mailer.mu.Unlock()
+
+	formatted := fmt.Sprintf("----- %s -----\n%s\n", time.Now().Format(time.RFC3339), formatMessage("qr-pastebin", message))
+	if mailer.Path == "" {
+		fmt.Print(formatted)
+		return nil
+	}
+
+	file, err := os.OpenFile(mailer.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open mail log '%s': %w", mailer.Path, err)
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(formatted)
+	return err
+}
+
+func formatMessage(from string, message Message) []byte {
+	headers := []string{
+		fmt.Sprintf("From: %s", from),
+		fmt.Sprintf("To: %s", message.To),
+		fmt.Sprintf("Subject: %s", message.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + message.Body + "\r\n")
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMailerFromEnv(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"MAIL_DRIVER": "log"}, "*mail.LogMailer"},
+		{map[string]string{"MAIL_DRIVER": "smtp", "SMTP_HOST": "mail.example.com", "SMTP_PORT": "587", "MAIL_FROM": "qr@example.com"}, "*mail.SMTPMailer"},
+		{map[string]string{"MAIL_DRIVER": "smtp", "SMTP_PORT": "587"}, ""},
+		{map[string]string{"MAIL_DRIVER": ""}, ""},
+		{map[string]string{"MAIL_DRIVER": "sendmail"}, ""},
+	}
+	for _, test := range tests {
+		for _, name := range []string{"MAIL_DRIVER", "SMTP_HOST", "SMTP_PORT", "MAIL_FROM"} {
+			t.Setenv(name, test.env[name])
+		}
+		mailer, err := NewMailerFromEnv()
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("%v: expected an error, got %T", test.env, mailer)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.env, err)
+			continue
+		}
+		if got := fmt.Sprintf("%T", mailer); got != test.want {
+			t.Errorf("%v: expected %s, got %s", test.env, test.want, got)
+		}
+	}
+}
+
+func TestLogMailerAppendsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	mailer := &LogMailer{Path: path}
+	for _, subject := range []string{"First", "Second"} {
+		if err := mailer.Send(Message{To: "user@example.com", Subject: subject, Body: "token"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "Subject: First") || !strings.Contains(string(content), "Subject: Second") {
+		t.Errorf("expected both mails in the log, got %q", content)
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	got := string(formatMessage("qr@example.com", Message{To: "user@example.com", Subject: "Hello", Body: "Body"}))
+	want := "From: qr@example.com\r\nTo: user@example.com\r\nSubject: Hello\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n\r\nBody\r\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -7,37 +7,61 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"os"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"qr-pastebin-api/audit"
+	"qr-pastebin-api/blob"
 	"qr-pastebin-api/common"
+	"qr-pastebin-api/envelope"
+	"qr-pastebin-api/i18n"
+	"qr-pastebin-api/mail"
 	"qr-pastebin-api/shares"
+	"qr-pastebin-api/teams"
 	"qr-pastebin-api/users"
+	"qr-pastebin-api/webhooks"
 
 	"github.com/jackc/pgx/v5"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	_ "github.com/joho/godotenv/autoload"
 )
 
-type APIError struct {
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
-}
-
-func sendError(c *gin.Context, statusCode int, message string, err error) {
+// sendProblem answers with an RFC 7807 problem, message becomes its detail
+func sendProblem(c *gin.Context, statusCode int, code string, message string, err error, fields []common.FieldError) {
 	if statusCode >= 500 {
 		sendToDiscord(err.Error())
 	}
 
-	apiError := APIError{Message: message}
+	problem := common.Problem{
+		Type:     common.ProblemType(code),
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Detail:   message,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+		Fields:   fields,
+	}
 	if gin.IsDebugging() {
-		apiError.Details = err.Error()
+		problem.Debug = err.Error()
 	}
-	c.IndentedJSON(statusCode, apiError)
+	c.Header("Content-Type", common.PROBLEM_CONTENT_TYPE)
+	c.IndentedJSON(statusCode, problem)
+}
+
+// abortWithProblem stops the request in middlewares, which answer before any handler runs
+func abortWithProblem(c *gin.Context, statusCode int, code string, message string) {
+	c.Abort()
+	sendProblem(c, statusCode, code, message, errors.New(message), nil)
 }
 
 func sendToDiscord(message string) {
@@ -82,8 +106,53 @@ func sendDiscordMessage(message string) {
 var wrongPasswordErr *common.PasswordIncorrectError
 var userAlreadyExistsErr *users.UserAlreadyExistsError
 var expiredShareError *shares.ExpiredShareError
+var notPublishedError *shares.NotPublishedError
 var notFoundError *common.NotFoundError
 var oauthUser *common.UserLoggedInViaOauth
+var twoFactorCodeErr *users.TwoFactorCodeIncorrectError
+var twoFactorChallengeErr *users.TwoFactorChallengeExpiredError
+var twoFactorLockedErr *users.TwoFactorLockedError
+var twoFactorEnabledErr *users.TwoFactorAlreadyEnabledError
+var twoFactorNotEnabledErr *users.TwoFactorNotEnabledError
+var tokenInvalidErr *users.TokenInvalidError
+var emailInvalidErr *users.EmailInvalidError
+var emailInUseErr *users.EmailAlreadyInUseError
+var emptyPasswordErr *users.EmptyPasswordError
+var accountDisabledErr *users.AccountDisabledError
+var modifySelfErr *users.CannotModifySelfError
+var unknownRoleErr *users.UnknownRoleError
+var invalidAccessErr *shares.InvalidAccessError
+var invalidVisibilityErr *shares.InvalidVisibilityError
+var grantToOwnerErr *shares.GrantToOwnerError
+var teamMembershipErr *shares.TeamMembershipRequiredError
+var teamMoveErr *shares.TeamMoveNotAllowedError
+var ownerRequiredErr *shares.OwnerRequiredError
+var ownerlessShareErr *shares.OwnerlessShareError
+var passwordRequiredErr *shares.PasswordRequiredError
+var signedLinkErr *shares.SignedLinkInvalidError
+var linkSigningDisabledErr *shares.LinkSigningDisabledError
+var linkExpiryErr *shares.LinkExpiryInvalidError
+var unsupportedEncryptionErr *shares.UnsupportedEncryptionError
+var invalidCiphertextErr *shares.InvalidCiphertextError
+var plaintextRequiredErr *shares.PlaintextRequiredError
+var invalidMimeTypeErr *shares.InvalidMimeTypeError
+var attachmentTooLargeErr *shares.AttachmentTooLargeError
+var attachmentQuotaErr *shares.AttachmentQuotaExceededError
+var unsupportedLanguageErr *shares.UnsupportedLanguageError
+var unknownThemeErr *shares.UnknownThemeError
+var validationErr *common.ValidationError
+var bindingErrs validator.ValidationErrors
+var requestTooLargeErr *common.RequestTooLargeError
+var maxBytesErr *http.MaxBytesError
+var storageQuotaErr *shares.StorageQuotaExceededError
+var authorMismatchErr *shares.AuthorMismatchError
+var invalidFormatErr *shares.InvalidFormatError
+var teamExistsErr *teams.TeamAlreadyExistsError
+var teamNameErr *teams.TeamNameInvalidError
+var teamRoleErr *teams.TeamRoleInvalidError
+var teamPermissionErr *teams.TeamPermissionError
+var lastOwnerErr *teams.LastOwnerError
+var ownerlessSharesErr *teams.OwnerlessSharesError
 
 func ErrorHandlerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -94,39 +163,380 @@ func ErrorHandlerMiddleware() gin.HandlerFunc {
 
 			statusCode := http.StatusInternalServerError
 			message := "An unexpected server error encountered"
+			code := common.PROBLEM_INTERNAL
+			var fields []common.FieldError
 
 			if errors.As(err, &wrongPasswordErr) {
 				statusCode = http.StatusUnauthorized
+				code = common.PROBLEM_PASSWORD_INCORRECT
 				message = wrongPasswordErr.Error()
 			}
 
 			if errors.As(err, &userAlreadyExistsErr) {
 				statusCode = http.StatusConflict
+				code = common.PROBLEM_USER_EXISTS
 				message = "User already exists"
 			}
 
 			if errors.As(err, &expiredShareError) {
 				statusCode = http.StatusNotFound
+				code = common.PROBLEM_SHARE_EXPIRED
 				message = expiredShareError.Error()
 			}
 
+			// Scheduled shares tell viewers when to come back
+			if errors.As(err, &notPublishedError) {
+				statusCode = http.StatusNotFound
+				code = common.PROBLEM_SHARE_NOT_PUBLISHED
+				message = notPublishedError.Error()
+				c.Header("Retry-After", notPublishedError.PublishAt.UTC().Format(http.TimeFormat))
+			}
+
 			if errors.As(err, &notFoundError) {
 				statusCode = http.StatusNotFound
+				code = common.PROBLEM_NOT_FOUND
 				message = notFoundError.Error()
 			}
 
 			if errors.As(err, &oauthUser) {
 				statusCode = http.StatusNotAcceptable
+				code = common.PROBLEM_OAUTH_ACCOUNT
 				message = oauthUser.Error()
 			}
 
+			if errors.As(err, &twoFactorCodeErr) {
+				statusCode = http.StatusUnauthorized
+				code = common.PROBLEM_TWO_FACTOR_CODE_INCORRECT
+				message = twoFactorCodeErr.Error()
+			}
+
+			if errors.As(err, &twoFactorChallengeErr) {
+				statusCode = http.StatusUnauthorized
+				code = common.PROBLEM_TWO_FACTOR_CHALLENGE_EXPIRED
+				message = twoFactorChallengeErr.Error()
+			}
+
+			if errors.As(err, &twoFactorLockedErr) {
+				statusCode = http.StatusTooManyRequests
+				code = common.PROBLEM_TWO_FACTOR_LOCKED
+				message = twoFactorLockedErr.Error()
+				c.Header("Retry-After", twoFactorLockedErr.Until.UTC().Format(http.TimeFormat))
+			}
+
+			if errors.As(err, &twoFactorEnabledErr) {
+				statusCode = http.StatusConflict
+				code = common.PROBLEM_TWO_FACTOR_ENABLED
+				message = twoFactorEnabledErr.Error()
+			}
+
+			if errors.As(err, &twoFactorNotEnabledErr) {
+				statusCode = http.StatusConflict
+				code = common.PROBLEM_TWO_FACTOR_NOT_ENABLED
+				message = twoFactorNotEnabledErr.Error()
+			}
+
+			if errors.As(err, &tokenInvalidErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_TOKEN_INVALID
+				message = tokenInvalidErr.Error()
+			}
+
+			if errors.As(err, &emailInvalidErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_EMAIL_INVALID
+				message = emailInvalidErr.Error()
+			}
+
+			if errors.As(err, &emailInUseErr) {
+				statusCode = http.StatusConflict
+				code = common.PROBLEM_EMAIL_IN_USE
+				message = emailInUseErr.Error()
+			}
+
+			if errors.As(err, &emptyPasswordErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_PASSWORD_EMPTY
+				message = emptyPasswordErr.Error()
+			}
+
+			if errors.As(err, &accountDisabledErr) {
+				statusCode = http.StatusForbidden
+				code = common.PROBLEM_ACCOUNT_DISABLED
+				message = accountDisabledErr.Error()
+			}
+
+			if errors.As(err, &modifySelfErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_MODIFY_SELF
+				message = modifySelfErr.Error()
+			}
+
+			if errors.As(err, &unknownRoleErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_ROLE_UNKNOWN
+				message = unknownRoleErr.Error()
+			}
+
+			if errors.As(err, &invalidAccessErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_ACCESS_INVALID
+				message = invalidAccessErr.Error()
+			}
+
+			if errors.As(err, &invalidVisibilityErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_VISIBILITY_INVALID
+				message = invalidVisibilityErr.Error()
+			}
+
+			if errors.As(err, &grantToOwnerErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_GRANT_TO_OWNER
+				message = grantToOwnerErr.Error()
+			}
+
+			if errors.As(err, &teamMembershipErr) {
+				statusCode = http.StatusForbidden
+				code = common.PROBLEM_TEAM_MEMBERSHIP_REQUIRED
+				message = teamMembershipErr.Error()
+			}
+
+			if errors.As(err, &teamMoveErr) {
+				statusCode = http.StatusForbidden
+				code = common.PROBLEM_TEAM_MOVE_NOT_ALLOWED
+				message = teamMoveErr.Error()
+			}
+
+			if errors.As(err, &ownerRequiredErr) {
+				statusCode = http.StatusForbidden
+				code = common.PROBLEM_SHARE_OWNER_REQUIRED
+				message = ownerRequiredErr.Error()
+			}
+
+			if errors.As(err, &ownerlessShareErr) {
+				statusCode = http.StatusConflict
+				code = common.PROBLEM_SHARE_OWNERLESS
+				message = ownerlessShareErr.Error()
+			}
+
+			if errors.As(err, &teamExistsErr) {
+				statusCode = http.StatusConflict
+				code = common.PROBLEM_TEAM_EXISTS
+				message = teamExistsErr.Error()
+			}
+
+			if errors.As(err, &teamNameErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_TEAM_NAME_INVALID
+				message = teamNameErr.Error()
+			}
+
+			if errors.As(err, &teamRoleErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_TEAM_ROLE_INVALID
+				message = teamRoleErr.Error()
+			}
+
+			if errors.As(err, &teamPermissionErr) {
+				statusCode = http.StatusForbidden
+				code = common.PROBLEM_TEAM_PERMISSION_REQUIRED
+				message = teamPermissionErr.Error()
+			}
+
+			if errors.As(err, &lastOwnerErr) {
+				statusCode = http.StatusConflict
+				code = common.PROBLEM_TEAM_LAST_OWNER
+				message = lastOwnerErr.Error()
+			}
+
+			if errors.As(err, &ownerlessSharesErr) {
+				statusCode = http.StatusConflict
+				code = common.PROBLEM_TEAM_OWNERLESS_SHARES
+				message = ownerlessSharesErr.Error()
+			}
+
+			if errors.As(err, &passwordRequiredErr) {
+				statusCode = http.StatusUnauthorized
+				code = common.PROBLEM_PASSWORD_REQUIRED
+				message = passwordRequiredErr.Error()
+			}
+
+			if errors.As(err, &signedLinkErr) {
+				statusCode = http.StatusForbidden
+				code = common.PROBLEM_SIGNED_LINK_INVALID
+				message = signedLinkErr.Error()
+			}
+
+			if errors.As(err, &linkSigningDisabledErr) {
+				statusCode = http.StatusNotImplemented
+				code = common.PROBLEM_LINK_SIGNING_DISABLED
+				message = linkSigningDisabledErr.Error()
+			}
+
+			if errors.As(err, &linkExpiryErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_LINK_EXPIRY_INVALID
+				message = linkExpiryErr.Error()
+			}
+
+			if errors.As(err, &unsupportedEncryptionErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_ENCRYPTION_UNSUPPORTED
+				message = unsupportedEncryptionErr.Error()
+			}
+
+			if errors.As(err, &invalidCiphertextErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_CIPHERTEXT_INVALID
+				message = invalidCiphertextErr.Error()
+			}
+
+			if errors.As(err, &plaintextRequiredErr) {
+				statusCode = http.StatusUnprocessableEntity
+				code = common.PROBLEM_PLAINTEXT_REQUIRED
+				message = plaintextRequiredErr.Error()
+			}
+
+			if errors.As(err, &invalidMimeTypeErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_MIME_TYPE_INVALID
+				message = invalidMimeTypeErr.Error()
+			}
+
+			if errors.As(err, &attachmentTooLargeErr) {
+				statusCode = http.StatusRequestEntityTooLarge
+				code = common.PROBLEM_ATTACHMENT_TOO_LARGE
+				message = attachmentTooLargeErr.Error()
+			}
+
+			if errors.As(err, &attachmentQuotaErr) {
+				statusCode = http.StatusRequestEntityTooLarge
+				code = common.PROBLEM_ATTACHMENT_QUOTA
+				message = attachmentQuotaErr.Error()
+			}
+
+			if errors.As(err, &unsupportedLanguageErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_LANGUAGE_UNSUPPORTED
+				message = unsupportedLanguageErr.Error()
+			}
+
+			if errors.As(err, &unknownThemeErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_THEME_UNKNOWN
+				message = unknownThemeErr.Error()
+			}
+
+			if errors.As(err, &invalidFormatErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_FORMAT_INVALID
+				message = invalidFormatErr.Error()
+			}
+
+			if errors.As(err, &validationErr) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_VALIDATION_FAILED
+				message = "Request is not valid"
+				fields = validationErr.Fields
+			}
+
+			if errors.As(err, &bindingErrs) {
+				statusCode = http.StatusBadRequest
+				code = common.PROBLEM_VALIDATION_FAILED
+				message = "Request is not valid"
+				fields = fieldErrorsFromBinding(bindingErrs)
+			}
+
+			if errors.As(err, &requestTooLargeErr) {
+				statusCode = http.StatusRequestEntityTooLarge
+				code = common.PROBLEM_REQUEST_TOO_LARGE
+				message = requestTooLargeErr.Error()
+			}
+
+			if errors.As(err, &maxBytesErr) {
+				statusCode = http.StatusRequestEntityTooLarge
+				code = common.PROBLEM_REQUEST_TOO_LARGE
+				message = fmt.Sprintf("request body is larger than the limit of %d bytes", maxBytesErr.Limit)
+			}
+
+			if errors.As(err, &storageQuotaErr) {
+				statusCode = http.StatusRequestEntityTooLarge
+				code = common.PROBLEM_STORAGE_QUOTA
+				message = storageQuotaErr.Error()
+			}
+
+			if errors.As(err, &authorMismatchErr) {
+				statusCode = http.StatusForbidden
+				code = common.PROBLEM_AUTHOR_MISMATCH
+				message = authorMismatchErr.Error()
+			}
+
 			if errors.Is(err, sql.ErrNoRows) {
 				statusCode = http.StatusNotFound
+				code = common.PROBLEM_NOT_FOUND
 				message = "Resource not found"
 			}
 
-			sendError(c, statusCode, message, err)
+			sendProblem(c, statusCode, code, message, err, fields)
+		}
+	}
+}
+
+// fieldErrorsFromBinding describes failed binding tags, fields are named as in JSON
+func fieldErrorsFromBinding(errs validator.ValidationErrors) []common.FieldError {
+	fields := make([]common.FieldError, 0, len(errs))
+	for _, fieldErr := range errs {
+		unit := ""
+		if fieldErr.Kind() == reflect.String {
+			unit = " characters"
+		}
+
+		var message string
+		switch fieldErr.Tag() {
+		case "required":
+			message = "is required"
+		case "max":
+			message = fmt.Sprintf("must be at most %s%s", fieldErr.Param(), unit)
+		case "min":
+			message = fmt.Sprintf("must be at least %s%s", fieldErr.Param(), unit)
+		case "required_without":
+			param := fieldErr.Param()
+			message = fmt.Sprintf("is required unless %s is given", strings.ToLower(param[:1])+param[1:])
+		case "oneof":
+			message = fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+		default:
+			message = "is not valid"
 		}
+		fields = append(fields, common.FieldError{Field: fieldErr.Field(), Rule: fieldErr.Tag(), Message: message})
+	}
+	return fields
+}
+
+// registerJsonFieldNames makes binding errors report fields by their JSON names
+func registerJsonFieldNames() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+}
+
+// LimitBodySize rejects request bodies over the limit before they are read
+func LimitBodySize(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.Error(&common.RequestTooLargeError{Limit: limit})
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
 	}
 }
 
@@ -134,26 +544,100 @@ func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			abortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_AUTHORIZATION_REQUIRED, "Authorization header is required")
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+		authenticate(c, authHeader)
+	}
+}
+
+// OptionalAuthMiddleware lets anonymous requests through, but a sent Authorization header still has to be valid
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
 			return
 		}
 
-		sessionId := parts[1]
+		authenticate(c, authHeader)
+	}
+}
+
+func authenticate(c *gin.Context, authHeader string) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		abortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_AUTHORIZATION_INVALID, "Authorization header format must be Bearer {token}")
+		return
+	}
+
+	sessionId := parts[1]
+
+	user, err := userHandler.GetUserFromSession(sessionId)
+	if err != nil {
+		abortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_SESSION_INVALID, fmt.Sprintf("Invalid session token. %s", err))
+		return
+	}
+
+	if user.Disabled {
+		abortWithProblem(c, http.StatusForbidden, common.PROBLEM_ACCOUNT_DISABLED, "Account is disabled")
+		return
+	}
+
+	c.Set("userId", user.Id)
+	c.Set("userRole", user.Role)
+
+	c.Next()
+}
+
+const REQUEST_ID_HEADER = "X-Request-Id"
+
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// RequestIdMiddleware keeps the request ID given by a proxy in front of the API or creates one.
+// It is sent back with the response and ties audit entries to the request. A given ID is taken as
+// the client sent it, so it can't be relied on to tell clients apart.
+func RequestIdMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(REQUEST_ID_HEADER)
+		if !requestIdPattern.MatchString(requestId) {
+			requestId = common.CreateRandomId(16)
+		}
+		c.Set("requestId", requestId)
+		c.Header(REQUEST_ID_HEADER, requestId)
+		c.Next()
+	}
+}
+
+const API_VERSION_PREFIX = "/v1"
+
+// deprecatedSince is when the unversioned paths were replaced by /v1
+var deprecatedSince = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// DeprecatedAliasMiddleware marks responses of unversioned paths as deprecated (RFC 9745) and links to
+// the same path under the version prefix
+func DeprecatedAliasMiddleware(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", deprecatedSince.Unix()))
+		c.Header("Link", fmt.Sprintf(`<%s%s>; rel="successor-version"`, prefix, c.Request.URL.Path))
+		c.Next()
+	}
+}
 
-		user, err := userHandler.GetUserFromSession(sessionId)
+// RequirePermission must run after AuthMiddleware, it relies on the role that middleware puts into the context
+func RequirePermission(permission common.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRole, err := getUserRoleFromContext(c)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("Invalid session token. %s", err)})
+			abortWithProblem(c, http.StatusUnauthorized, common.PROBLEM_AUTHORIZATION_REQUIRED, err.Error())
 			return
 		}
 
-		c.Set("userId", user.Id)
-		c.Set("userRole", user.Role)
+		if !userRole.HasPermission(permission) {
+			abortWithProblem(c, http.StatusForbidden, common.PROBLEM_PERMISSION_REQUIRED, fmt.Sprintf("Permission '%s' is required to access this resource", permission))
+			return
+		}
 
 		c.Next()
 	}
@@ -161,6 +645,12 @@ func AuthMiddleware() gin.HandlerFunc {
 
 var shareHandler shares.ShareDBHandler
 var userHandler users.UserDBHandler
+var teamHandler teams.TeamDBHandler
+var auditHandler audit.AuditDBHandler
+var webhookHandler webhooks.WebhookDBHandler
+
+// viewRecorder is nil when views are not recorded, e.g. in tests
+var viewRecorder *shares.ViewRecorder
 
 func main() {
 	fmt.Println(os.Getenv("DATABASE_URL"))
@@ -171,36 +661,211 @@ func main() {
 	}
 	defer conn.Close(context.Background())
 
-	shareHandler = *shares.NewShareHandler(conn)
-	userHandler = *users.NewUserHandler(conn)
+	linkSigner, err := shares.NewLinkSignerFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load share link keys: %v\n", err)
+		os.Exit(1)
+	}
+
+	keyring, err := envelope.NewKeyringFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load share master keys: %v\n", err)
+		os.Exit(1)
+	}
+
+	limits, err := shares.NewLimitsFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load share limits: %v\n", err)
+		os.Exit(1)
+	}
+	blobs := blob.NewStorageFromEnv()
+
+	mailer, err := mail.NewMailerFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load mail settings: %v\n", err)
+		os.Exit(1)
+	}
+
+	shareHandler = *shares.NewShareHandler(conn, linkSigner, keyring, blobs, limits)
+	userHandler = *users.NewUserHandler(conn, mailer, blobs)
+	teamHandler = *teams.NewTeamHandler(conn)
+	auditHandler = *audit.NewAuditHandler(conn)
+	webhookHandler = *webhooks.NewWebhookHandler(conn)
+
+	// Key rotation runs next to request handling, so it gets a connection of its own
+	if keyring.Enabled() {
+		rotationConn, err := pgx.Connect(context.Background(), os.Getenv("DATABASE_URL"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
+			os.Exit(1)
+		}
+		defer rotationConn.Close(context.Background())
+		go shares.NewShareHandler(rotationConn, linkSigner, keyring, blobs, limits).RunKeyRotation(10 * time.Minute)
+	}
+
+	// Views are written in batches next to request handling, also with a connection of their own
+	viewsConn, err := pgx.Connect(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer viewsConn.Close(context.Background())
+	viewRecorder = shares.NewViewRecorder(viewsConn)
+	viewWebhooks := webhooks.NewWebhookHandler(viewsConn)
+	viewRecorder.AfterWrite(func(views []shares.View) error {
+		events := make([]webhooks.Event, 0, len(views))
+		for _, view := range views {
+			events = append(events, webhooks.Event{Type: webhooks.EVENT_SHARE_VIEWED, At: view.ViewedAt, ShareId: view.ShareId, Agent: view.Agent, FromQr: view.FromQr})
+		}
+		return viewWebhooks.Enqueue(events...)
+	})
+	go viewRecorder.Run(5 * time.Second)
+
+	// Webhook deliveries are sent from the outbox with yet another connection
+	webhooksConn, err := pgx.Connect(context.Background(), os.Getenv("DATABASE_URL"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
+		os.Exit(1)
+	}
+	defer webhooksConn.Close(context.Background())
+	go webhooks.NewDispatcherFromEnv(webhooksConn).Run(5 * time.Second)
+
+	registerJsonFieldNames()
+	openAPIDocument, err = buildOpenAPIDocument()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to build OpenAPI document: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Share bodies may be as large as their content plus some room for the other fields
+	router, err := setupRouter(limits.MaxContentSize + 64<<10)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to load trusted proxies: %v\n", err)
+		os.Exit(1)
+	}
+	router.Run("0.0.0.0:8080")
+}
 
+// setupRouter serves the API under /v1, the unversioned paths used before stay as deprecated aliases
+func setupRouter(shareBodyLimit int64) (*gin.Engine, error) {
 	router := gin.Default()
+	if err := configureClientIp(router); err != nil {
+		return nil, err
+	}
 	router.Use(cors.New(cors.Config{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{"*"},
-		AllowHeaders: []string{"*"},
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{"*"},
+		AllowHeaders:  []string{"*"},
+		ExposeHeaders: []string{"Deprecation", "Link", "Content-Language", "Retry-After", REQUEST_ID_HEADER, UNSEARCHED_SHARES_HEADER},
 	}))
+	router.Use(RequestIdMiddleware())
 	router.Use(ErrorHandlerMiddleware())
+	router.NoRoute(func(c *gin.Context) {
+		abortWithProblem(c, http.StatusNotFound, common.PROBLEM_NOT_FOUND, "Route not found")
+	})
+
+	registerRoutes(router.Group(API_VERSION_PREFIX), shareBodyLimit)
+	registerRoutes(router.Group("/", DeprecatedAliasMiddleware(API_VERSION_PREFIX)), shareBodyLimit)
+	return router, nil
+}
+
+// configureClientIp decides where the client address of audit entries and views comes from. Only
+// proxies listed in TRUSTED_PROXIES (addresses or CIDR ranges, comma separated) may set it with
+// X-Forwarded-For, TRUSTED_PLATFORM names a header set by the platform instead, e.g. CF-Connecting-IP.
+// Without either the address of the connection is used.
+func configureClientIp(router *gin.Engine) error {
+	router.TrustedPlatform = os.Getenv("TRUSTED_PLATFORM")
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return router.SetTrustedProxies(proxies)
+}
 
+// registerRoutes registers every route, each of them has to be described in routeDocs
+func registerRoutes(router *gin.RouterGroup, shareBodyLimit int64) {
 	api := router.Group("/")
 	api.Use(AuthMiddleware())
 	{
 		api.GET("/shares", GetShares)
 		api.DELETE("/share/:id", DeleteShare)
 		api.GET("/share/:id/edit", GetShareForEdit)
-		api.PATCH("/share/:id/edit", UpdateShare)
+		api.PATCH("/share/:id/edit", LimitBodySize(shareBodyLimit), UpdateShare)
+		api.GET("/share/:id/grants", GetShareGrants)
+		api.GET("/share/:id/stats", GetShareStats)
+		api.PUT("/share/:id/grants", GrantShareAccess)
+		api.DELETE("/share/:id/grants/:userId", RevokeShareAccess)
+		api.POST("/share/:id/link", CreateSignedLink)
+		api.POST("/share/:id/attachments", UploadAttachment)
+		api.DELETE("/share/:id/attachments/:attachmentId", DeleteAttachment)
+		api.DELETE("/share/:id/link", RevokeSignedLinks)
+		api.POST("/teams", CreateTeam)
+		api.GET("/teams", GetTeams)
+		api.GET("/teams/:id", GetTeam)
+		api.DELETE("/teams/:id", DeleteTeam)
+		api.GET("/teams/:id/shares", GetTeamShares)
+		api.PUT("/teams/:id/members", SetTeamMember)
+		api.DELETE("/teams/:id/members/:userId", RemoveTeamMember)
+		api.POST("/user/2fa", EnrollTwoFactor)
+		api.POST("/user/2fa/activate", ActivateTwoFactor)
+		api.POST("/user/2fa/disable", DisableTwoFactor)
+		api.POST("/user/2fa/recovery-codes", RegenerateRecoveryCodes)
+		api.PUT("/user/email", ChangeEmail)
+		api.POST("/user/email/verification", ResendVerificationEmail)
+		api.POST("/webhooks", CreateWebhook)
+		api.GET("/webhooks", GetWebhooks)
+		api.DELETE("/webhooks/:id", DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", GetWebhookDeliveries)
+		api.GET("/webhooks/:id/deliveries/:deliveryId", GetWebhookDelivery)
+		api.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", RedeliverWebhook)
+	}
+
+	admin := router.Group("/admin")
+	admin.Use(AuthMiddleware(), RequirePermission(common.USER_MANAGE))
+	{
+		admin.GET("/users", SearchUsers)
+		admin.GET("/users/:id", GetUserForAdmin)
+		admin.GET("/users/:id/shares", GetUserSharesForAdmin)
+		admin.PUT("/users/:id/role", ChangeUserRole)
+		admin.PUT("/users/:id/disabled", ChangeUserDisabled)
+		admin.DELETE("/users/:id/sessions", ExpireUserSessions)
+		admin.DELETE("/users/:id", DeleteUser)
+	}
+
+	auditLog := router.Group("/admin/audit")
+	auditLog.Use(AuthMiddleware(), RequirePermission(common.AUDIT_READ))
+	{
+		auditLog.GET("", GetAuditLog)
+		auditLog.GET("/export", ExportAuditLog)
 	}
 
-	router.POST("/share", CreateShare)
-	router.GET("/share/:id", GetShare)
-	router.POST("/share/:id/protected", GetProtectedShare)
-	router.GET("/share/:id/protected", IsPasswordProtected)
+	moderation := router.Group("/moderation")
+	moderation.Use(AuthMiddleware(), RequirePermission(common.MODERATION_REVIEW))
+	{
+		moderation.GET("/shares", GetSharesForReview)
+	}
+
+	router.POST("/share", LimitBodySize(shareBodyLimit), OptionalAuthMiddleware(), CreateShare)
+	router.GET("/share/:id", OptionalAuthMiddleware(), GetShare)
+	router.GET("/share/:id/raw", OptionalAuthMiddleware(), GetRawShare)
+	router.GET("/share/:id/highlighted", OptionalAuthMiddleware(), GetHighlightedShare)
+	router.GET("/share/:id/rendered", OptionalAuthMiddleware(), GetRenderedShare)
+	router.GET("/themes", GetThemes)
+	router.GET("/share/:id/attachments/:attachmentId", OptionalAuthMiddleware(), DownloadAttachment)
+	router.POST("/share/:id/protected", OptionalAuthMiddleware(), GetProtectedShare)
+	router.GET("/share/:id/protected", OptionalAuthMiddleware(), IsPasswordProtected)
 	router.POST("/user", CreateUser)
 	router.GET("/user/session/:sessionId", GetUser)
 	router.GET("/oauth/github/:userId", GithubUserExists)
 	router.POST("/user/session", CreateSession)
+	router.POST("/user/session/2fa", CompleteTwoFactorSession)
+	router.POST("/user/email/verify", VerifyEmail)
+	router.POST("/user/password/forgot", ForgotPassword)
+	router.POST("/user/password/reset", ResetPassword)
 	router.GET("/health", HealthCheck)
-	router.Run("0.0.0.0:8080")
+	router.GET("/openapi.json", GetOpenAPIDocument)
 }
 
 func HealthCheck(c *gin.Context) {
@@ -212,6 +877,7 @@ func HealthCheck(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, response)
 }
 
+// CreateShare works with and without a session, shares created without one have no author
 func CreateShare(c *gin.Context) {
 	var body shares.ShareRequest
 	if err := c.ShouldBind(&body); err != nil {
@@ -219,64 +885,282 @@ func CreateShare(c *gin.Context) {
 		return
 	}
 
-	response, err := shareHandler.CreateShare(body)
+	userId, userRole := getViewerFromContext(c)
+	response, err := shareHandler.CreateShare(userId, userRole, body)
 	if err != nil {
 		c.Error(err)
 		return
 	}
+	recordAudit(c, audit.ACTION_SHARE_CREATED, userId, audit.TARGET_SHARE, response.ShareId, "")
+	notifyWebhooks(webhooks.EVENT_SHARE_CREATED, response.ShareId)
 	c.IndentedJSON(http.StatusOK, response)
 }
 
 func GetShare(c *gin.Context) {
 	shareId := c.Param("id")
-	response, err := shareHandler.GetShareForPublic(shareId)
-	if err != nil {
+	var link shares.SignedLink
+	if err := c.ShouldBindQuery(&link); err != nil {
 		c.Error(err)
 		return
 	}
-	c.IndentedJSON(http.StatusOK, response)
-}
 
-func GithubUserExists(c *gin.Context) {
-	userIdString := c.Param("userId")
-	userId64, _ := strconv.ParseInt(userIdString, 10, 0)
-	userId := int(userId64)
-	response, err := userHandler.GithubUserExists(userId)
+	userId, userRole := getViewerFromContext(c)
+	response, err := shareHandler.GetShareForPublic(shareId, userId, userRole, link)
 	if err != nil {
 		c.Error(err)
 		return
 	}
+	response.Localize(negotiateLocale(c))
+	recordView(c, response)
+	c.IndentedJSON(http.StatusOK, response)
+}
 
-	type existsResponse struct {
-		Exists bool `json:"exists"`
+// recordView counts a successful read of a share, src=qr in the query marks scans of a QR code.
+// Reads by the author and other privileged viewers are not counted.
+func recordView(c *gin.Context, share *shares.ShareResponse) {
+	if viewRecorder == nil || share.Privileged {
+		return
 	}
-
-	c.IndentedJSON(http.StatusOK, existsResponse{
-		Exists: response,
-	})
+	view := shares.NewView(share.Id, c.Request.UserAgent(), c.Request.Referer(), c.ClientIP(), c.Query("src"), time.Now())
+	viewRecorder.Record(view)
 }
 
-func GetShareForEdit(c *gin.Context) {
+// GetRawShare serves only the content, so it can be fetched with plain curl or wget.
+// Password protected shares take the password in the X-Share-Password header.
+func GetRawShare(c *gin.Context) {
 	shareId := c.Param("id")
-	userId, err := getUserIdFromContext(c)
-	if err != nil {
+	var request shares.RawShareRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
 		c.Error(err)
 		return
 	}
 
-	response, err := shareHandler.GetShareForOwner(shareId, userId)
+	userId, userRole := getViewerFromContext(c)
+	raw, err := shareHandler.GetRawShare(shareId, userId, userRole, request.SignedLink, c.GetHeader("X-Share-Password"))
 	if err != nil {
+		auditUnlockFailure(c, userId, shareId, err)
 		c.Error(err)
 		return
 	}
-	c.IndentedJSON(http.StatusOK, response)
-}
+	if raw.Unlocked {
+		notifyWebhooks(webhooks.EVENT_SHARE_UNLOCKED, shareId)
+	}
 
-func UpdateShare(c *gin.Context) {
-	shareId := c.Param("id")
-	userId, err := getUserIdFromContext(c)
-	if err != nil {
-		c.Error(err)
+	c.Header("ETag", raw.ETag)
+	c.Header("Cache-Control", "private, no-cache")
+	if raw.MatchesETag(c.GetHeader("If-None-Match")) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	disposition := "inline"
+	if request.Download {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": raw.FileName}))
+	// Stored content is never allowed to run as a page on the API origin
+	c.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, raw.MimeType, []byte(raw.Content))
+}
+
+func GetHighlightedShare(c *gin.Context) {
+	shareId := c.Param("id")
+	var request shares.HighlightRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.Error(err)
+		return
+	}
+
+	userId, userRole := getViewerFromContext(c)
+	response, err := shareHandler.GetHighlightedShare(shareId, userId, userRole, request, c.GetHeader("X-Share-Password"))
+	if err != nil {
+		auditUnlockFailure(c, userId, shareId, err)
+		c.Error(err)
+		return
+	}
+	if response.Unlocked {
+		notifyWebhooks(webhooks.EVENT_SHARE_UNLOCKED, shareId)
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetRenderedShare(c *gin.Context) {
+	shareId := c.Param("id")
+	var link shares.SignedLink
+	if err := c.ShouldBindQuery(&link); err != nil {
+		c.Error(err)
+		return
+	}
+
+	userId, userRole := getViewerFromContext(c)
+	response, err := shareHandler.GetRenderedShare(shareId, userId, userRole, link, c.GetHeader("X-Share-Password"))
+	if err != nil {
+		auditUnlockFailure(c, userId, shareId, err)
+		c.Error(err)
+		return
+	}
+	if response.Unlocked {
+		notifyWebhooks(webhooks.EVENT_SHARE_UNLOCKED, shareId)
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetThemes(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, shares.GetThemes())
+}
+
+// UploadAttachment takes a single file from the multipart field "file"
+func UploadAttachment(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Stop reading oversized uploads early, leaving some room for the multipart framing
+	limit := shareHandler.Limits.MaxAttachmentSize
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = &shares.AttachmentTooLargeError{Limit: limit}
+		}
+		c.Error(err)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer file.Close()
+
+	response, err := shareHandler.AddAttachment(shareId, userId, userRole, fileHeader.Filename, fileHeader.Header.Get("Content-Type"), fileHeader.Size, file)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// DownloadAttachment streams the file, unlocking password protected shares like GetRawShare
+func DownloadAttachment(c *gin.Context) {
+	shareId := c.Param("id")
+	var request shares.RawShareRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.Error(err)
+		return
+	}
+
+	userId, userRole := getViewerFromContext(c)
+	attachment, reader, unlocked, err := shareHandler.GetAttachment(shareId, c.Param("attachmentId"), userId, userRole, request.SignedLink, c.GetHeader("X-Share-Password"))
+	if err != nil {
+		auditUnlockFailure(c, userId, shareId, err)
+		c.Error(err)
+		return
+	}
+	defer reader.Close()
+	if unlocked {
+		notifyWebhooks(webhooks.EVENT_SHARE_UNLOCKED, shareId)
+	}
+
+	c.Header("ETag", attachment.ETag())
+	c.Header("Cache-Control", "private, no-cache")
+	if shares.MatchesETag(c.GetHeader("If-None-Match"), attachment.ETag()) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.MimeType, reader, map[string]string{
+		"Content-Disposition":     mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}),
+		"Content-Security-Policy": "default-src 'none'; sandbox",
+		"X-Content-Type-Options":  "nosniff",
+		"X-Checksum-Sha256":       attachment.Checksum,
+	})
+}
+
+func DeleteAttachment(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = shareHandler.DeleteAttachment(shareId, c.Param("attachmentId"), userId, userRole)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func GithubUserExists(c *gin.Context) {
+	userIdString := c.Param("userId")
+	userId64, _ := strconv.ParseInt(userIdString, 10, 0)
+	userId := int(userId64)
+	response, err := userHandler.GithubUserExists(userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	type existsResponse struct {
+		Exists bool `json:"exists"`
+	}
+
+	c.IndentedJSON(http.StatusOK, existsResponse{
+		Exists: response,
+	})
+}
+
+func GetShareForEdit(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := shareHandler.GetShareForOwner(shareId, userId, userRole)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	response.Localize(negotiateLocale(c))
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func UpdateShare(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -286,11 +1170,13 @@ func UpdateShare(c *gin.Context) {
 		return
 	}
 
-	err = shareHandler.UpdateShare(shareId, userId, body)
+	err = shareHandler.UpdateShare(shareId, userId, userRole, body)
 	if err != nil {
 		c.Error(err)
 		return
 	}
+	recordAudit(c, audit.ACTION_SHARE_UPDATED, userId, audit.TARGET_SHARE, shareId, "")
+	notifyWebhooks(webhooks.EVENT_SHARE_UPDATED, shareId)
 	c.IndentedJSON(http.StatusOK, nil)
 }
 
@@ -307,24 +1193,52 @@ func DeleteShare(c *gin.Context) {
 		return
 	}
 
-	permit, err := shareHandler.HasAccessToShare(userId, shareId, userRole)
+	permit, err := shareHandler.HasAccessToShare(userId, shareId, userRole, common.SHARE_DELETE_ANY)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
 	if !permit {
-		c.IndentedJSON(http.StatusUnauthorized, nil)
+		abortWithProblem(c, http.StatusForbidden, common.PROBLEM_FORBIDDEN, "You are not allowed to delete this share")
+		return
+	}
+
+	// Checking again as a plain user tells apart deleting with the permission from deleting an own share
+	detail := ""
+	if userRole.HasPermission(common.SHARE_DELETE_ANY) {
+		own, err := shareHandler.HasAccessToShare(userId, shareId, common.USER, common.SHARE_DELETE_ANY)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if !own {
+			detail = fmt.Sprintf("deleted with permission '%s' as %s", common.SHARE_DELETE_ANY, userRole)
+		}
 	}
 
+	// Built while the share still exists, webhooks are found through its author
+	deliveries, err := webhookHandler.Prepare(webhooks.Event{Type: webhooks.EVENT_SHARE_DELETED, At: time.Now(), ShareId: shareId})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
 	err = shareHandler.DeleteShare(shareId)
 	if err != nil {
 		c.Error(err)
 		return
 	}
+	if deliveries != nil {
+		if err := webhookHandler.EnqueuePrepared(deliveries); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+	}
+	recordAudit(c, audit.ACTION_SHARE_DELETED, userId, audit.TARGET_SHARE, shareId, detail)
 	c.IndentedJSON(http.StatusOK, nil)
 }
 
+// UNSEARCHED_SHARES_HEADER lists end-to-end encrypted shares whose content a search couldn't look at
+const UNSEARCHED_SHARES_HEADER = "X-Unsearched-Shares"
+
 func GetShares(c *gin.Context) {
 	userId, err := getUserIdFromContext(c)
 	if err != nil {
@@ -332,11 +1246,21 @@ func GetShares(c *gin.Context) {
 		return
 	}
 
-	response, err := shareHandler.GetShares(userId)
+	var response []shares.ShareResponse
+	if query := c.Query("query"); query != "" {
+		var unsearched []string
+		response, unsearched, err = shareHandler.SearchShares(userId, query)
+		if len(unsearched) > 0 {
+			c.Header(UNSEARCHED_SHARES_HEADER, strings.Join(unsearched, ","))
+		}
+	} else {
+		response, err = shareHandler.GetShares(userId)
+	}
 	if err != nil {
 		c.Error(err)
 		return
 	}
+	shares.LocalizeShares(response, negotiateLocale(c))
 	c.IndentedJSON(http.StatusOK, response)
 }
 
@@ -356,19 +1280,41 @@ func getUserIdFromContext(c *gin.Context) (int, error) {
 func getUserRoleFromContext(c *gin.Context) (common.Role, error) {
 	userIdInterface, exists := c.Get("userRole")
 	if !exists {
-		return -1, errors.New("userRole not set in context")
+		return "", errors.New("userRole not set in context")
 	}
 
 	userRole, ok := userIdInterface.(common.Role)
 	if !ok {
-		return -1, errors.New("userRole in context is not an int")
+		return "", errors.New("userRole in context is not a role")
 	}
 	return userRole, nil
 }
 
+// negotiateLocale picks the language of texts meant for people from Accept-Language
+func negotiateLocale(c *gin.Context) i18n.Locale {
+	locale := i18n.Negotiate(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", string(locale))
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	return locale
+}
+
+// getViewerFromContext returns -1 and the default role for requests without a session
+func getViewerFromContext(c *gin.Context) (int, common.Role) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		return -1, common.USER
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		return -1, common.USER
+	}
+	return userId, userRole
+}
+
 func IsPasswordProtected(c *gin.Context) {
 	shareId := c.Param("id")
-	response, err := shareHandler.GetShareForPublic(shareId)
+	userId, userRole := getViewerFromContext(c)
+	response, err := shareHandler.IsPasswordProtected(shareId, userId, userRole)
 	if err != nil {
 		c.Error(err)
 		return
@@ -384,11 +1330,16 @@ func GetProtectedShare(c *gin.Context) {
 		return
 	}
 
-	response, err := shareHandler.GetProtectedShare(shareId, body.Password)
+	userId, userRole := getViewerFromContext(c)
+	response, err := shareHandler.GetProtectedShare(shareId, body.Password, userId, userRole)
 	if err != nil {
+		auditUnlockFailure(c, userId, shareId, err)
 		c.Error(err)
 		return
 	}
+	notifyWebhooks(webhooks.EVENT_SHARE_UNLOCKED, shareId)
+	response.Localize(negotiateLocale(c))
+	recordView(c, response)
 	c.IndentedJSON(http.StatusOK, response)
 }
 
@@ -425,9 +1376,890 @@ func CreateSession(c *gin.Context) {
 	}
 
 	response, err := userHandler.CreateSession(body)
+	if err != nil {
+		auditLoginFailure(c, body, err)
+		c.Error(err)
+		return
+	}
+	if response.TwoFactorRequired {
+		recordAudit(c, audit.ACTION_LOGIN, response.UserId, audit.TARGET_USER, strconv.Itoa(response.UserId), "two-factor code required")
+	} else {
+		recordAudit(c, audit.ACTION_LOGIN, response.UserId, audit.TARGET_USER, strconv.Itoa(response.UserId), "")
+		recordAudit(c, audit.ACTION_SESSION_CREATED, response.UserId, audit.TARGET_USER, strconv.Itoa(response.UserId), "")
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// auditLoginFailure records logins refused because of the credentials or the account
+func auditLoginFailure(c *gin.Context, credentials users.UserCredentials, err error) {
+	var passwordErr *common.PasswordIncorrectError
+	var oauthErr *common.UserLoggedInViaOauth
+	var disabledErr *users.AccountDisabledError
+	if !errors.As(err, &passwordErr) && !errors.As(err, &oauthErr) && !errors.As(err, &disabledErr) {
+		return
+	}
+
+	targetId := ""
+	if credentials.Id != nil {
+		targetId = strconv.Itoa(*credentials.Id)
+	} else if user, err := common.GetUserByName(userHandler.DB, credentials.Name); err == nil {
+		targetId = strconv.Itoa(user.Id)
+	}
+	recordAudit(c, audit.ACTION_LOGIN_FAILED, -1, audit.TARGET_USER, targetId, fmt.Sprintf("%s: %s", credentials.Name, err))
+}
+
+func CompleteTwoFactorSession(c *gin.Context) {
+	var body users.TwoFactorLoginRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := userHandler.CompleteTwoFactorLogin(body)
+	if err != nil {
+		var codeErr *users.TwoFactorCodeIncorrectError
+		if errors.As(err, &codeErr) {
+			recordAudit(c, audit.ACTION_LOGIN_FAILED, -1, audit.TARGET_USER, "", err.Error())
+		}
+		c.Error(err)
+		return
+	}
+	recordAudit(c, audit.ACTION_SESSION_CREATED, response.UserId, audit.TARGET_USER, strconv.Itoa(response.UserId), "after two-factor code")
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func EnrollTwoFactor(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := userHandler.EnrollTwoFactor(userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func ActivateTwoFactor(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body users.TwoFactorCodeRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := userHandler.ActivateTwoFactor(userId, body.Code)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 	c.IndentedJSON(http.StatusOK, response)
 }
+
+func DisableTwoFactor(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body users.TwoFactorDisableRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = userHandler.DisableTwoFactor(userId, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body users.TwoFactorCodeRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := userHandler.RegenerateRecoveryCodes(userId, body.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func ChangeEmail(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body users.EmailRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = userHandler.ChangeEmail(userId, body.Email)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func ResendVerificationEmail(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = userHandler.ResendVerificationEmail(userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func VerifyEmail(c *gin.Context) {
+	var body users.TokenRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err := userHandler.VerifyEmail(body.Token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func ForgotPassword(c *gin.Context) {
+	var body users.EmailRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err := userHandler.RequestPasswordReset(body.Email)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func ResetPassword(c *gin.Context) {
+	var body users.ResetPasswordRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err := userHandler.ResetPassword(body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func getIntParam(c *gin.Context, name string) (int, error) {
+	value, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		return -1, &common.NotFoundError{}
+	}
+	return value, nil
+}
+
+func SearchUsers(c *gin.Context) {
+	var query users.UserSearchRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := userHandler.SearchUsers(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetUserForAdmin(c *gin.Context) {
+	userId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := userHandler.GetUserSummary(userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetUserSharesForAdmin(c *gin.Context) {
+	userId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := shareHandler.GetShares(userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	shares.LocalizeShares(response, negotiateLocale(c))
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func ChangeUserRole(c *gin.Context) {
+	adminId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body users.ChangeRoleRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = userHandler.ChangeRole(adminId, userId, body.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	recordAudit(c, audit.ACTION_USER_ROLE_CHANGED, adminId, audit.TARGET_USER, strconv.Itoa(userId), fmt.Sprintf("role set to %s", body.Role))
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func ChangeUserDisabled(c *gin.Context) {
+	adminId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body users.ChangeDisabledRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = userHandler.SetDisabled(adminId, userId, body.Disabled)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	action := audit.ACTION_USER_ENABLED
+	if body.Disabled {
+		action = audit.ACTION_USER_DISABLED
+	}
+	recordAudit(c, action, adminId, audit.TARGET_USER, strconv.Itoa(userId), "")
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func ExpireUserSessions(c *gin.Context) {
+	adminId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = userHandler.ExpireSessions(userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	recordAudit(c, audit.ACTION_USER_SESSIONS_EXPIRED, adminId, audit.TARGET_USER, strconv.Itoa(userId), "")
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func DeleteUser(c *gin.Context) {
+	adminId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	deletedShares, err := userHandler.DeleteUser(adminId, userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	recordAudit(c, audit.ACTION_USER_DELETED, adminId, audit.TARGET_USER, strconv.Itoa(userId), fmt.Sprintf("%d shares deleted", deletedShares))
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func GetSharesForReview(c *gin.Context) {
+	var query shares.ReviewRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := shareHandler.GetSharesForReview(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	shares.LocalizeShares(response, negotiateLocale(c))
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetShareGrants(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := shareHandler.GetGrants(shareId, userId, userRole)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// recordAudit appends to the audit log. The action already happened, so a failed write is reported
+// but doesn't fail the request.
+func recordAudit(c *gin.Context, action string, actorId int, targetType string, targetId string, detail string) {
+	entry := audit.Entry{
+		At:         time.Now(),
+		Action:     action,
+		ActorId:    actorId,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Ip:         c.ClientIP(),
+		RequestId:  c.GetString("requestId"),
+		Detail:     detail,
+	}
+	if err := auditHandler.Record(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
+
+// auditUnlockFailure records wrong passwords given for password protected shares
+func auditUnlockFailure(c *gin.Context, userId int, shareId string, err error) {
+	var passwordErr *common.PasswordIncorrectError
+	if errors.As(err, &passwordErr) {
+		recordAudit(c, audit.ACTION_SHARE_UNLOCK_FAILED, userId, audit.TARGET_SHARE, shareId, fmt.Sprintf("%s %s", c.Request.Method, c.Request.URL.Path))
+	}
+}
+
+func GetAuditLog(c *gin.Context) {
+	var filter audit.Filter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := auditHandler.GetEntries(filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// ExportAuditLog streams every matching entry as JSON lines, limit and offset are ignored
+func ExportAuditLog(c *gin.Context) {
+	var filter audit.Filter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(err)
+		return
+	}
+	filter.Limit, filter.Offset = 0, 0
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="audit-%s.jsonl"`, time.Now().UTC().Format("2006-01-02")))
+	if err := auditHandler.Export(filter, c.Writer); err != nil {
+		// Once lines are written the status can't change anymore, the export just ends early
+		if !c.Writer.Written() {
+			c.Error(err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "audit export failed: %v\n", err)
+	}
+}
+
+func GetShareStats(c *gin.Context) {
+	shareId := c.Param("id")
+	var query shares.StatsRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(err)
+		return
+	}
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := shareHandler.GetShareStats(shareId, userId, userRole, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GrantShareAccess(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body shares.GrantRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = shareHandler.GrantAccess(shareId, userId, userRole, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func RevokeShareAccess(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	granteeId, err := getIntParam(c, "userId")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = shareHandler.RevokeAccess(shareId, userId, userRole, granteeId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func CreateTeam(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body teams.TeamRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := teamHandler.CreateTeam(userId, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetTeams(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := teamHandler.GetTeams(userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetTeam(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	teamId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := teamHandler.GetTeam(teamId, userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func DeleteTeam(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	teamId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = teamHandler.DeleteTeam(teamId, userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func GetTeamShares(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	teamId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	isMember, err := teamHandler.IsMember(teamId, userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if !isMember {
+		c.Error(&common.NotFoundError{})
+		return
+	}
+
+	response, err := shareHandler.GetTeamShares(teamId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	shares.LocalizeShares(response, negotiateLocale(c))
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func SetTeamMember(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	teamId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body teams.MemberRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = teamHandler.SetMember(teamId, userId, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func RemoveTeamMember(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	teamId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	memberId, err := getIntParam(c, "userId")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = teamHandler.RemoveMember(teamId, userId, memberId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func CreateSignedLink(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var body shares.CreateLinkRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := shareHandler.CreateSignedLink(shareId, userId, userRole, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func RevokeSignedLinks(c *gin.Context) {
+	shareId := c.Param("id")
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userRole, err := getUserRoleFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = shareHandler.RevokeSignedLinks(shareId, userId, userRole)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+// notifyWebhooks queues an event for the webhooks of the share's author. Like the audit log, a
+// failure is reported without failing the request that already happened.
+func notifyWebhooks(event string, shareId string) {
+	if err := webhookHandler.Enqueue(webhooks.Event{Type: event, At: time.Now(), ShareId: shareId}); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+	}
+}
+
+func CreateWebhook(c *gin.Context) {
+	var body webhooks.WebhookRequest
+	if err := c.ShouldBind(&body); err != nil {
+		c.Error(err)
+		return
+	}
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := webhookHandler.CreateWebhook(userId, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetWebhooks(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := webhookHandler.GetWebhooks(userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func DeleteWebhook(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	webhookId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = webhookHandler.DeleteWebhook(webhookId, userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
+
+func GetWebhookDeliveries(c *gin.Context) {
+	var query webhooks.DeliveryRequest
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(err)
+		return
+	}
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	webhookId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := webhookHandler.GetDeliveries(webhookId, userId, query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+func GetWebhookDelivery(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	webhookId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	deliveryId, err := getIntParam(c, "deliveryId")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	response, err := webhookHandler.GetDelivery(webhookId, int64(deliveryId), userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, response)
+}
+
+// RedeliverWebhook queues a delivery again, the dispatcher sends it within a few seconds
+func RedeliverWebhook(c *gin.Context) {
+	userId, err := getUserIdFromContext(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	webhookId, err := getIntParam(c, "id")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	deliveryId, err := getIntParam(c, "deliveryId")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	err = webhookHandler.Redeliver(webhookId, int64(deliveryId), userId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, nil)
+}
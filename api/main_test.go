@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"qr-pastebin-api/common"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorsAreProblems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router, err := setupRouter(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		status int
+		code   string
+	}{
+		{"/v1/shares", http.StatusUnauthorized, common.PROBLEM_AUTHORIZATION_REQUIRED},
+		{"/v1/missing", http.StatusNotFound, common.PROBLEM_NOT_FOUND},
+	}
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.path, nil))
+
+		if contentType := recorder.Header().Get("Content-Type"); contentType != common.PROBLEM_CONTENT_TYPE {
+			t.Errorf("%s: expected problem content type, got '%s'", test.path, contentType)
+		}
+		var problem common.Problem
+		if err := json.Unmarshal(recorder.Body.Bytes(), &problem); err != nil {
+			t.Fatal(err)
+		}
+		if recorder.Code != test.status || problem.Status != test.status || problem.Code != test.code {
+			t.Errorf("%s: expected %d %s, got %d %+v", test.path, test.status, test.code, recorder.Code, problem)
+		}
+		if problem.Type != common.ProblemType(test.code) || problem.Instance != test.path {
+			t.Errorf("%s: unexpected type or instance in %+v", test.path, problem)
+		}
+	}
+}
+
+func TestUnversionedPathsAreDeprecated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router, err := setupRouter(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/themes", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Deprecation") == "" {
+		t.Errorf("expected deprecated alias to answer with a Deprecation header, got %d %v", recorder.Code, recorder.Header())
+	}
+	if link := recorder.Header().Get("Link"); link != `</v1/themes>; rel="successor-version"` {
+		t.Errorf("unexpected successor link '%s'", link)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/themes", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Deprecation") != "" {
+		t.Errorf("expected versioned path without Deprecation header, got %d %v", recorder.Code, recorder.Header())
+	}
+}
+
+func TestRequestIdIsSentBack(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router, err := setupRouter(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		given string
+		kept  bool
+	}{
+		{"", false},
+		{"edge-7f3a.91", true},
+		{"not allowed\nheader", false},
+		{strings.Repeat("a", 129), false},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/v1/themes", nil)
+		if test.given != "" {
+			request.Header.Set(REQUEST_ID_HEADER, test.given)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		requestId := recorder.Header().Get(REQUEST_ID_HEADER)
+		if test.kept && requestId != test.given {
+			t.Errorf("expected request id '%s' to be kept, got '%s'", test.given, requestId)
+		}
+		if !test.kept && (requestId == "" || requestId == test.given) {
+			t.Errorf("expected a new request id instead of '%s', got '%s'", test.given, requestId)
+		}
+	}
+}
+
+func TestForwardedForNeedsTrustedProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		proxies string
+		want    string
+	}{
+		{"", "192.0.2.1"},
+		{"198.51.100.0/24", "192.0.2.1"},
+		{"198.51.100.0/24, 192.0.2.1", "203.0.113.7"},
+	}
+	for _, test := range tests {
+		t.Setenv("TRUSTED_PROXIES", test.proxies)
+		router := gin.New()
+		if err := configureClientIp(router); err != nil {
+			t.Fatal(err)
+		}
+		router.GET("/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+		request := httptest.NewRequest(http.MethodGet, "/ip", nil)
+		request.Header.Set("X-Forwarded-For", "203.0.113.7")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if got := recorder.Body.String(); got != test.want {
+			t.Errorf("'%s': expected client address %s, got %s", test.proxies, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"net/http"
+	"qr-pastebin-api/audit"
+	"qr-pastebin-api/common"
+	"qr-pastebin-api/openapi"
+	"qr-pastebin-api/shares"
+	"qr-pastebin-api/teams"
+	"qr-pastebin-api/users"
+	"qr-pastebin-api/webhooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+var openAPIDocument *openapi.Document
+
+var sharePasswordHeader = openapi.Parameter{
+	Name:        "X-Share-Password",
+	In:          "header",
+	Description: "Password of a password protected share",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+var acceptLanguageHeader = openapi.Parameter{
+	Name:        "Accept-Language",
+	In:          "header",
+	Description: "Language of expiresIn, one of `en`, `lt` and `de`. Defaults to English.",
+	Schema:      &openapi.Schema{Type: "string"},
+}
+
+var attachmentUpload = &openapi.Schema{
+	Type:       "object",
+	Required:   []string{"file"},
+	Properties: map[string]*openapi.Schema{"file": {Type: "string", Format: "binary"}},
+}
+
+type githubUserExistsResponse struct {
+	Exists bool `json:"exists"`
+}
+
+// fieldDocs explains properties whose meaning isn't clear from their name and type
+var fieldDocs = map[string]string{
+	"ShareRequest.expireIn": "How long the share is kept: `never`, an ISO 8601 duration like `P1DT12H` or `P2W`, or a count and unit like `30_minutes`, `5_hours`, `1_days`, `2_weeks`, `6_months` or `1_years`. " +
+		"When updating a share, `no-change` keeps its current expiry. Required unless `expiresAt` is given.",
+	"ShareRequest.expiresAt": "Exact expiry as an RFC 3339 timestamp, takes precedence over `expireIn`. " +
+		"Expiries are checked against the lifetime configured for the role of the user, the rejected rule is `min_lifetime` or `max_lifetime`.",
+	"ShareRequest.publishAt": "RFC 3339 time before which only the author, the owning team, users with a grant and moderators can read the share. " +
+		"Others get a 404 `share_not_published` problem with the time in Retry-After. Must be before the expiry. When updating, omit it to keep the schedule or send a past time to publish right away.",
+	"ShareRequest.setPassword":       "Changes the password to `password`. When updating with an empty password, the password is removed. Without it, the password of an updated share stays as it is. Only the owner of the share can change it.",
+	"ShareRequest.hideAuthor":        "Hides the author name from readers. Updates always set it, so send the current value to keep it.",
+	"ShareRequest.authorId":          "Deprecated, the author is taken from the session. Sending another user's id is rejected with 403.",
+	"ShareRequest.visibility":        "Private shares are only readable by the author, the owning team, users with a grant and moderators. Defaults to `public`. Only the owner of the share can change it.",
+	"ShareRequest.teamId":            "Team owning the share, its members may edit it. Only the author, or an owner or admin of the current team when moving the share out of it, can change the team. `-1` removes the team.",
+	"ShareRequest.encryption":        "Set to `AES-256-GCM` for end-to-end encrypted content, which the server stores as it is.",
+	"ShareRequest.mimeType":          "Content type served by the raw endpoint, `text/plain` by default.",
+	"ShareRequest.language":          "Language used for highlighting, detected from the content when empty.",
+	"ShareRequest.format":            "`markdown` shares are rendered as HTML by the rendered endpoint.",
+	"ShareResponse.expiresIn":        "Human readable time until the share expires, in the language picked by Accept-Language.",
+	"ShareResponse.expiresAt":        "When the share expires in RFC 3339, null for shares that never expire.",
+	"Filter.action":                  "One of `login`, `login.failed`, `session.created`, `share.created`, `share.updated`, `share.deleted`, `share.unlock_failed`, `user.role_changed`, `user.disabled`, `user.enabled`, `user.sessions_expired` and `user.deleted`.",
+	"Filter.actorId":                 "User who acted, `-1` for requests without a session.",
+	"Filter.from":                    "Only entries at or after this RFC 3339 time.",
+	"Filter.to":                      "Only entries before this RFC 3339 time.",
+	"Filter.limit":                   "Page size, 50 by default and at most 500.",
+	"Entry.requestId":                "Sent back in the X-Request-Id header of every response, or taken from that header of the request as the client sent it.",
+	"Entry.ip":                       "Address of the client as seen by the API, from X-Forwarded-For only for requests through the proxies in TRUSTED_PROXIES.",
+	"WebhookRequest.url":             "Endpoint receiving the events as POST requests with a JSON body. It has to resolve to a public address.",
+	"WebhookRequest.events":          "Events to send: `share.created`, `share.updated`, `share.viewed`, `share.unlocked`, `share.expired` and `share.deleted`. All of them when empty.",
+	"WebhookResponse.secret":         "Key of the HMAC-SHA256 signature in the X-Webhook-Signature header, only returned when the webhook is created.",
+	"DeliveryResponse.eventId":       "Id of the event, the same for every attempt and redelivery so receivers can skip duplicates.",
+	"DeliveryResponse.status":        "`pending` until the endpoint answers with a 2xx status (`delivered`) or all 10 attempts failed (`failed`).",
+	"DeliveryResponse.nextAttemptAt": "When a pending delivery is tried next. The wait doubles after every failed attempt, starting at 30 seconds.",
+	"DeliveryResponse.log":           "Every attempt of the delivery, oldest first. Only returned for a single delivery.",
+	"AttemptResponse.statusCode":     "Status the endpoint answered with, 0 when it couldn't be reached.",
+	"AttemptResponse.response":       "Start of the body the endpoint answered with.",
+	"Event.agent":                    "Class of the user agent of a `share.viewed` event.",
+	"Event.fromQr":                   "Whether the viewed link came from a QR code.",
+	"StatsRequest.bucket":            "Width of the histogram buckets, `day` by default. Buckets start at full hours or midnight in UTC.",
+	"StatsRequest.days":              "Days covered by the histogram, 30 by default and at most 31 with hourly buckets.",
+	"StatsResponse.total":            "Views since the share was created.",
+	"StatsResponse.fromQr":           "Views of links marked with `?src=qr`, which the QR codes of the website and qrpaste carry.",
+	"StatsResponse.agents":           "Views by class of user agent: `desktop`, `mobile`, `tablet`, `cli`, `bot` or `other`.",
+	"StatsResponse.referrers":        "Hosts of the pages linking to the share with the most views.",
+	"ShareResponse.publishAt":        "When the share becomes readable for everyone in RFC 3339, null for shares published on creation.",
+	"ShareResponse.teamId":           "`-1` when the share belongs to no team.",
+	"ShareResponse.access":           "Access the session user was granted to a share of another user.",
+	"SignedLink.exp":                 "Expiry of the signed link, as Unix time.",
+	"SignedLink.kid":                 "Id of the key the link was signed with.",
+	"SignedLink.sig":                 "Signature of the link, lets anyone holding it read the share without its password.",
+	"RawShareRequest.download":       "Serve the content as an attachment instead of inline.",
+	"CreateLinkRequest.expiresAt":    "Expiry of the link in RFC 3339, takes precedence over `expireIn`.",
+	"CreateLinkRequest.expireIn":     "Expiry of the link like `expireIn` of shares.",
+	"Problem.code":                   "Stable identifier of the problem, e.g. `share_expired`, `password_incorrect` or `oauth_account`.",
+	"Problem.detail":                 "Explanation of the problem for people, its wording may change.",
+	"Problem.instance":               "Path of the request.",
+	"Problem.debug":                  "Cause of the error, only included when the server runs in debug mode.",
+	"Problem.fields":                 "Rejected fields of a request that is not valid.",
+	"SessionData.twoFactorRequired":  "The login has to be completed with POST /user/session/2fa and `challengeId`.",
+}
+
+// routeDocs describes every route registered in setupRouter, TestEveryRouteIsDocumented keeps them in sync
+var routeDocs = []openapi.Route{
+	{Method: http.MethodPost, Path: "/share", Tag: "shares", Summary: "Create a share", Auth: openapi.AuthOptional,
+		Description: "Shares created without a session have no author and can't be edited or deleted.",
+		Body:        shares.ShareRequest{}, Response: shares.CreateShareResponse{}, Errors: []int{403, 413}},
+	{Method: http.MethodGet, Path: "/share/:id", Tag: "shares", Summary: "Read a share", Auth: openapi.AuthOptional,
+		Description: "Password protected shares are read with POST /share/{id}/protected, unless a signed link is given. " +
+			"Each read by someone without privileged access is counted in the share stats, `?src=qr` marks it as a scan of a QR code.",
+		Query: shares.SignedLink{}, Headers: []openapi.Parameter{acceptLanguageHeader}, Response: shares.ShareResponse{}, Errors: []int{401, 403, 404}},
+	{Method: http.MethodGet, Path: "/share/:id/raw", Tag: "shares", Summary: "Read the content of a share as it is", Auth: openapi.AuthOptional,
+		Query: shares.RawShareRequest{}, Headers: []openapi.Parameter{sharePasswordHeader}, ContentType: "text/plain",
+		Description: "Served with the mime type of the share, an ETag and a sandboxing Content-Security-Policy.",
+		Errors:      []int{304, 401, 403, 404}},
+	{Method: http.MethodGet, Path: "/share/:id/highlighted", Tag: "shares", Summary: "Read a share as highlighted HTML", Auth: openapi.AuthOptional,
+		Query: shares.HighlightRequest{}, Headers: []openapi.Parameter{sharePasswordHeader}, Response: shares.HighlightResponse{},
+		Errors: []int{401, 403, 404, 422}},
+	{Method: http.MethodGet, Path: "/share/:id/rendered", Tag: "shares", Summary: "Read a share as rendered Markdown", Auth: openapi.AuthOptional,
+		Query: shares.SignedLink{}, Headers: []openapi.Parameter{sharePasswordHeader}, Response: shares.RenderedResponse{},
+		Errors: []int{401, 403, 404, 422}},
+	{Method: http.MethodGet, Path: "/themes", Tag: "shares", Summary: "List highlighting themes", Response: []string{}},
+	{Method: http.MethodGet, Path: "/share/:id/protected", Tag: "shares", Summary: "Check whether a share needs a password", Auth: openapi.AuthOptional,
+		Description: "False for users who may read the share without its password.",
+		Response:    shares.IsPasswordProtectedResponse{}, Errors: []int{404}},
+	{Method: http.MethodPost, Path: "/share/:id/protected", Tag: "shares", Summary: "Read a password protected share", Auth: openapi.AuthOptional,
+		Description: "Each read is counted in the share stats like GET /share/{id}.",
+		Body:        shares.GetProtectedShareRequest{}, Headers: []openapi.Parameter{acceptLanguageHeader}, Response: shares.ShareResponse{}, Errors: []int{401, 404}},
+	{Method: http.MethodGet, Path: "/shares", Tag: "shares", Summary: "List shares of the session user", Auth: openapi.AuthRequired,
+		Description: "Includes shares other users granted access to. `query` searches titles and contents. " +
+			"The content of end-to-end encrypted shares can't be searched, the ids of those whose title doesn't match are listed in the X-Unsearched-Shares header.",
+		Query: struct {
+			Query string `form:"query"`
+		}{}, Headers: []openapi.Parameter{acceptLanguageHeader}, Response: []shares.ShareResponse{}},
+	{Method: http.MethodGet, Path: "/share/:id/edit", Tag: "shares", Summary: "Read a share for editing", Auth: openapi.AuthRequired,
+		Headers: []openapi.Parameter{acceptLanguageHeader}, Response: shares.ShareResponse{}, Errors: []int{404}},
+	{Method: http.MethodPatch, Path: "/share/:id/edit", Tag: "shares", Summary: "Update a share", Auth: openapi.AuthRequired,
+		Description: "Send `\"expireIn\": \"no-change\"` to keep the current expiry.",
+		Body:        shares.ShareRequest{}, Errors: []int{403, 404, 409, 413}},
+	{Method: http.MethodDelete, Path: "/share/:id", Tag: "shares", Summary: "Delete a share", Auth: openapi.AuthRequired,
+		Errors: []int{403, 404}},
+	{Method: http.MethodGet, Path: "/share/:id/grants", Tag: "sharing", Summary: "List users with access to a share", Auth: openapi.AuthRequired,
+		Response: []shares.GrantResponse{}, Errors: []int{404}},
+	{Method: http.MethodGet, Path: "/share/:id/stats", Tag: "sharing", Summary: "Count views of a share", Auth: openapi.AuthRequired,
+		Description: "Available to users who may edit the share. Views are written in batches, so the latest few seconds may be missing.",
+		Query:       shares.StatsRequest{}, Response: shares.StatsResponse{}, Errors: []int{404}},
+	{Method: http.MethodPut, Path: "/share/:id/grants", Tag: "sharing", Summary: "Give a user view or edit access", Auth: openapi.AuthRequired,
+		Body: shares.GrantRequest{}, Errors: []int{404}},
+	{Method: http.MethodDelete, Path: "/share/:id/grants/:userId", Tag: "sharing", Summary: "Revoke access of a user", Auth: openapi.AuthRequired,
+		Errors: []int{404}},
+	{Method: http.MethodPost, Path: "/share/:id/link", Tag: "sharing", Summary: "Create a signed link", Auth: openapi.AuthRequired,
+		Body: shares.CreateLinkRequest{}, Response: shares.CreateLinkResponse{}, Errors: []int{404, 501}},
+	{Method: http.MethodDelete, Path: "/share/:id/link", Tag: "sharing", Summary: "Revoke every signed link of a share", Auth: openapi.AuthRequired,
+		Errors: []int{404}},
+	{Method: http.MethodPost, Path: "/share/:id/attachments", Tag: "attachments", Summary: "Attach a file to a share", Auth: openapi.AuthRequired,
+		Multipart: attachmentUpload, Response: shares.AttachmentResponse{}, Errors: []int{404, 413}},
+	{Method: http.MethodGet, Path: "/share/:id/attachments/:attachmentId", Tag: "attachments", Summary: "Download an attached file", Auth: openapi.AuthOptional,
+		Description: "The SHA-256 checksum of the file is sent in the X-Checksum-Sha256 header.",
+		Query:       shares.RawShareRequest{}, Headers: []openapi.Parameter{sharePasswordHeader}, ContentType: "application/octet-stream",
+		Errors: []int{304, 401, 403, 404}},
+	{Method: http.MethodDelete, Path: "/share/:id/attachments/:attachmentId", Tag: "attachments", Summary: "Delete an attached file", Auth: openapi.AuthRequired,
+		Errors: []int{404}},
+	{Method: http.MethodPost, Path: "/teams", Tag: "teams", Summary: "Create a team", Auth: openapi.AuthRequired,
+		Body: teams.TeamRequest{}, Response: teams.CreateTeamResponse{}, Errors: []int{409}},
+	{Method: http.MethodGet, Path: "/teams", Tag: "teams", Summary: "List teams of the session user", Auth: openapi.AuthRequired,
+		Response: []teams.TeamResponse{}},
+	{Method: http.MethodGet, Path: "/teams/:id", Tag: "teams", Summary: "Read a team with its members", Auth: openapi.AuthRequired,
+		Response: teams.TeamResponse{}, Errors: []int{404}},
+	{Method: http.MethodDelete, Path: "/teams/:id", Tag: "teams", Summary: "Delete a team", Auth: openapi.AuthRequired,
+		Description: "Shares of the team go back to their authors. Shares whose author was deleted have to be deleted first, until then the team can't be deleted.",
+		Errors:      []int{403, 404, 409}},
+	{Method: http.MethodGet, Path: "/teams/:id/shares", Tag: "teams", Summary: "List shares of a team", Auth: openapi.AuthRequired,
+		Headers: []openapi.Parameter{acceptLanguageHeader}, Response: []shares.ShareResponse{}, Errors: []int{404}},
+	{Method: http.MethodPut, Path: "/teams/:id/members", Tag: "teams", Summary: "Add a member or change their role", Auth: openapi.AuthRequired,
+		Body: teams.MemberRequest{}, Errors: []int{403, 404, 409}},
+	{Method: http.MethodDelete, Path: "/teams/:id/members/:userId", Tag: "teams", Summary: "Remove a member", Auth: openapi.AuthRequired,
+		Errors: []int{403, 404, 409}},
+	{Method: http.MethodPost, Path: "/user", Tag: "users", Summary: "Register a user", Body: users.UserCredentials{}, Errors: []int{409}},
+	{Method: http.MethodPost, Path: "/user/session", Tag: "users", Summary: "Log in", Body: users.UserCredentials{}, Response: users.SessionData{},
+		Errors: []int{401, 403, 404, 406}},
+	{Method: http.MethodPost, Path: "/user/session/2fa", Tag: "users", Summary: "Complete a login with the second factor",
+		Description: "A challenge takes 5 codes. After 5 wrong codes in a row, across logins, the second factor is locked for a minute, twice as long after every further wrong code and at most an hour.",
+		Body:        users.TwoFactorLoginRequest{}, Response: users.SessionData{}, Errors: []int{401, 429}},
+	{Method: http.MethodGet, Path: "/user/session/:sessionId", Tag: "users", Summary: "Read the user of a session", Response: common.User{},
+		Errors: []int{404}},
+	{Method: http.MethodGet, Path: "/oauth/github/:userId", Tag: "users", Summary: "Check whether a GitHub user is registered",
+		Response: githubUserExistsResponse{}},
+	{Method: http.MethodPost, Path: "/user/2fa", Tag: "users", Summary: "Start enrolling a second factor", Auth: openapi.AuthRequired,
+		Response: users.TwoFactorEnrollment{}, Errors: []int{409}},
+	{Method: http.MethodPost, Path: "/user/2fa/activate", Tag: "users", Summary: "Activate the second factor", Auth: openapi.AuthRequired,
+		Body: users.TwoFactorCodeRequest{}, Response: users.RecoveryCodesResponse{}, Errors: []int{401, 409}},
+	{Method: http.MethodPost, Path: "/user/2fa/disable", Tag: "users", Summary: "Disable the second factor", Auth: openapi.AuthRequired,
+		Body: users.TwoFactorDisableRequest{}, Errors: []int{401, 409, 429}},
+	{Method: http.MethodPost, Path: "/user/2fa/recovery-codes", Tag: "users", Summary: "Replace the recovery codes", Auth: openapi.AuthRequired,
+		Body: users.TwoFactorCodeRequest{}, Response: users.RecoveryCodesResponse{}, Errors: []int{401, 409, 429}},
+	{Method: http.MethodPut, Path: "/user/email", Tag: "users", Summary: "Change the email address", Auth: openapi.AuthRequired,
+		Body: users.EmailRequest{}, Errors: []int{409}},
+	{Method: http.MethodPost, Path: "/user/email/verification", Tag: "users", Summary: "Send the verification email again", Auth: openapi.AuthRequired},
+	{Method: http.MethodPost, Path: "/user/email/verify", Tag: "users", Summary: "Verify the email address", Body: users.TokenRequest{}},
+	{Method: http.MethodPost, Path: "/user/password/forgot", Tag: "users", Summary: "Send a password reset email",
+		Description: "Answers the same whether or not the address is known.", Body: users.EmailRequest{}},
+	{Method: http.MethodPost, Path: "/user/password/reset", Tag: "users", Summary: "Reset the password", Body: users.ResetPasswordRequest{}},
+	{Method: http.MethodGet, Path: "/admin/users", Tag: "admin", Summary: "Search users", Auth: openapi.AuthRequired, Permission: string(common.USER_MANAGE),
+		Query: users.UserSearchRequest{}, Response: []users.UserSummary{}},
+	{Method: http.MethodGet, Path: "/admin/users/:id", Tag: "admin", Summary: "Read a user", Auth: openapi.AuthRequired, Permission: string(common.USER_MANAGE),
+		Response: users.UserSummary{}, Errors: []int{404}},
+	{Method: http.MethodGet, Path: "/admin/users/:id/shares", Tag: "admin", Summary: "List shares of a user", Auth: openapi.AuthRequired, Permission: string(common.USER_MANAGE),
+		Headers: []openapi.Parameter{acceptLanguageHeader}, Response: []shares.ShareResponse{}, Errors: []int{404}},
+	{Method: http.MethodPut, Path: "/admin/users/:id/role", Tag: "admin", Summary: "Change the role of a user", Auth: openapi.AuthRequired, Permission: string(common.USER_MANAGE),
+		Body: users.ChangeRoleRequest{}, Errors: []int{404}},
+	{Method: http.MethodPut, Path: "/admin/users/:id/disabled", Tag: "admin", Summary: "Disable or enable a user", Auth: openapi.AuthRequired, Permission: string(common.USER_MANAGE),
+		Body: users.ChangeDisabledRequest{}, Errors: []int{404}},
+	{Method: http.MethodDelete, Path: "/admin/users/:id/sessions", Tag: "admin", Summary: "Log a user out everywhere", Auth: openapi.AuthRequired, Permission: string(common.USER_MANAGE),
+		Errors: []int{404}},
+	{Method: http.MethodDelete, Path: "/admin/users/:id", Tag: "admin", Summary: "Delete a user with their shares", Auth: openapi.AuthRequired, Permission: string(common.USER_MANAGE),
+		Errors: []int{400, 404}},
+	{Method: http.MethodGet, Path: "/admin/audit", Tag: "admin", Summary: "Page through the audit log", Auth: openapi.AuthRequired, Permission: string(common.AUDIT_READ),
+		Description: "Newest entries first. Filters combine, entries are never changed or removed.",
+		Query:       audit.Filter{}, Response: []audit.Entry{}},
+	{Method: http.MethodGet, Path: "/admin/audit/export", Tag: "admin", Summary: "Export the audit log as JSON lines", Auth: openapi.AuthRequired, Permission: string(common.AUDIT_READ),
+		Description: "Takes the filters of GET /admin/audit, but exports every matching entry.",
+		Query:       audit.Filter{}, ContentType: "application/x-ndjson"},
+	{Method: http.MethodGet, Path: "/moderation/shares", Tag: "admin", Summary: "Page through all shares", Auth: openapi.AuthRequired, Permission: string(common.MODERATION_REVIEW),
+		Query: shares.ReviewRequest{}, Headers: []openapi.Parameter{acceptLanguageHeader}, Response: []shares.ShareResponse{}},
+	{Method: http.MethodPost, Path: "/webhooks", Tag: "webhooks", Summary: "Register a webhook for events of own shares", Auth: openapi.AuthRequired,
+		Description: "Events are signed with the returned secret: X-Webhook-Signature is `t=<unix time>,v1=<hex HMAC-SHA256 of '<unix time>.<body>'>`. " +
+			"Deliveries that don't get a 2xx answer are retried with exponential backoff.",
+		Body: webhooks.WebhookRequest{}, Response: webhooks.WebhookResponse{}},
+	{Method: http.MethodGet, Path: "/webhooks", Tag: "webhooks", Summary: "List webhooks of the session user", Auth: openapi.AuthRequired,
+		Response: []webhooks.WebhookResponse{}},
+	{Method: http.MethodDelete, Path: "/webhooks/:id", Tag: "webhooks", Summary: "Delete a webhook with its deliveries", Auth: openapi.AuthRequired,
+		Errors: []int{404}},
+	{Method: http.MethodGet, Path: "/webhooks/:id/deliveries", Tag: "webhooks", Summary: "Page through deliveries of a webhook", Auth: openapi.AuthRequired,
+		Description: "Newest first, with the result of the last attempt.",
+		Query:       webhooks.DeliveryRequest{}, Response: []webhooks.DeliveryResponse{}, Errors: []int{404}},
+	{Method: http.MethodGet, Path: "/webhooks/:id/deliveries/:deliveryId", Tag: "webhooks", Summary: "Read a delivery with its payload and attempts", Auth: openapi.AuthRequired,
+		Response: webhooks.DeliveryResponse{}, Errors: []int{404}},
+	{Method: http.MethodPost, Path: "/webhooks/:id/deliveries/:deliveryId/redeliver", Tag: "webhooks", Summary: "Send a delivery again", Auth: openapi.AuthRequired,
+		Description: "Queues the same payload again right away, also for delivered and failed deliveries. Attempts are counted from zero again.",
+		Errors:      []int{404}},
+	{Method: http.MethodGet, Path: "/health", Tag: "meta", Summary: "Check the health of the API", Response: common.HealthResponse{}},
+	{Method: http.MethodGet, Path: "/openapi.json", Tag: "meta", Summary: "Read this document", Response: map[string]any{}},
+}
+
+func buildOpenAPIDocument() (*openapi.Document, error) {
+	info := openapi.Info{
+		Title:   "qr-pastebin API",
+		Version: "1.0.0",
+		Description: "Errors are answered with RFC 7807 problem details. Routes with optional authentication return more to users who may access the share. " +
+			"The paths are also served without the /v1 prefix, those aliases are deprecated.",
+	}
+	document, err := openapi.Build(info, routeDocs, fieldDocs, common.Problem{})
+	if err != nil {
+		return nil, err
+	}
+	document.Servers = []openapi.Server{{Url: API_VERSION_PREFIX}}
+	return document, nil
+}
+
+func GetOpenAPIDocument(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, openAPIDocument)
+}
@@ -0,0 +1,262 @@
+// Package openapi builds an OpenAPI 3 document from a description of the routes. Schemas of
+// request and response bodies are generated from the Go types, so they follow the types as
+// they change: json tags name the properties and binding tags become constraints.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const Version = "3.0.3"
+
+type Auth int
+
+const (
+	AuthNone Auth = iota
+	// AuthOptional routes behave differently for requests with a session
+	AuthOptional
+	AuthRequired
+)
+
+// Route documents one route of the router
+type Route struct {
+	Method string
+	// Path is written like it is registered with gin, e.g. /share/:id
+	Path        string
+	Tag         string
+	Summary     string
+	Description string
+	Auth        Auth
+	Permission  string
+	// Query is a struct whose form tags are the query parameters
+	Query any
+	// Headers are request headers the route reads
+	Headers []Parameter
+	// Body is sent as JSON, unless Multipart is set
+	Body      any
+	Multipart *Schema
+	// Response is returned as JSON with status 200, nil responses are documented as null
+	Response any
+	// ContentType replaces JSON for routes that return files or raw content
+	ContentType string
+	// Errors lists the statuses the route answers with besides 200
+	Errors []int
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Server is the base path of the documented paths
+type Server struct {
+	Url         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lower case method
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+const bearerScheme = "session"
+
+const problemContentType = "application/problem+json"
+
+// Build describes the routes. Descriptions of schema properties are looked up in fields by
+// "Schema.property", e.g. "ShareRequest.expireIn".
+func Build(info Info, routes []Route, fields map[string]string, errorBody any) (*Document, error) {
+	schemas := newSchemaRegistry(fields)
+	document := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]PathItem{},
+		Components: Components{
+			Schemas: schemas.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				bearerScheme: {Type: "http", Scheme: "bearer", Description: "Session id returned by POST /user/session"},
+			},
+		},
+	}
+	errorSchema := schemas.of(errorBody)
+
+	for _, route := range routes {
+		path, pathParameters := convertPath(route.Path)
+		item, exists := document.Paths[path]
+		if !exists {
+			item = PathItem{}
+			document.Paths[path] = item
+		}
+		method := strings.ToLower(route.Method)
+		if _, exists := item[method]; exists {
+			return nil, fmt.Errorf("route %s %s is documented more than once", route.Method, route.Path)
+		}
+
+		operation := &Operation{
+			OperationId: operationId(route.Method, route.Path),
+			Summary:     route.Summary,
+			Description: route.Description,
+			Responses:   map[string]Response{},
+		}
+		if route.Tag != "" {
+			operation.Tags = []string{route.Tag}
+		}
+		if route.Permission != "" {
+			operation.Description = strings.TrimSpace(fmt.Sprintf("%s\n\nNeeds the permission `%s`.", operation.Description, route.Permission))
+		}
+
+		operation.Parameters = append(operation.Parameters, pathParameters...)
+		if route.Query != nil {
+			operation.Parameters = append(operation.Parameters, schemas.queryParameters(route.Query)...)
+		}
+		operation.Parameters = append(operation.Parameters, route.Headers...)
+
+		switch route.Auth {
+		case AuthRequired:
+			operation.Security = []map[string][]string{{bearerScheme: {}}}
+		case AuthOptional:
+			operation.Security = []map[string][]string{{}, {bearerScheme: {}}}
+		}
+
+		if route.Multipart != nil {
+			operation.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"multipart/form-data": {Schema: route.Multipart}}}
+		} else if route.Body != nil {
+			operation.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{"application/json": {Schema: schemas.of(route.Body)}}}
+		}
+
+		switch {
+		case route.ContentType != "":
+			operation.Responses["200"] = Response{
+				Description: http.StatusText(http.StatusOK),
+				Content:     map[string]MediaType{route.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}},
+			}
+		case route.Response != nil:
+			operation.Responses["200"] = Response{
+				Description: http.StatusText(http.StatusOK),
+				Content:     map[string]MediaType{"application/json": {Schema: schemas.of(route.Response)}},
+			}
+		default:
+			operation.Responses["200"] = Response{
+				Description: "The response body is null",
+				Content:     map[string]MediaType{"application/json": {Schema: &Schema{Nullable: true, Enum: []any{nil}}}},
+			}
+		}
+		for _, status := range errorStatuses(route) {
+			operation.Responses[fmt.Sprint(status)] = Response{
+				Description: http.StatusText(status),
+				Content:     map[string]MediaType{problemContentType: {Schema: errorSchema}},
+			}
+		}
+
+		item[method] = operation
+	}
+	return document, nil
+}
+
+// errorStatuses adds the statuses every route of its kind may answer with
+func errorStatuses(route Route) []int {
+	statuses := map[int]bool{http.StatusInternalServerError: true}
+	for _, status := range route.Errors {
+		statuses[status] = true
+	}
+	if route.Auth == AuthRequired {
+		statuses[http.StatusUnauthorized] = true
+	}
+	if route.Permission != "" {
+		statuses[http.StatusForbidden] = true
+	}
+	if route.Body != nil || route.Query != nil || route.Multipart != nil {
+		statuses[http.StatusBadRequest] = true
+	}
+
+	sorted := make([]int, 0, len(statuses))
+	for status := range statuses {
+		sorted = append(sorted, status)
+	}
+	sort.Ints(sorted)
+	return sorted
+}
+
+var pathParameterPattern = regexp.MustCompile(`:([A-Za-z]+)`)
+
+// convertPath turns gin parameters (:id) into OpenAPI ones ({id})
+func convertPath(path string) (string, []Parameter) {
+	var parameters []Parameter
+	for _, match := range pathParameterPattern.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	return pathParameterPattern.ReplaceAllString(path, "{$1}"), parameters
+}
+
+// ConvertPath is exported for comparing registered routes with the document
+func ConvertPath(path string) string {
+	converted, _ := convertPath(path)
+	return converted
+}
+
+func operationId(method string, path string) string {
+	var builder strings.Builder
+	builder.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.TrimPrefix(segment, ":")
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' }) {
+			builder.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return builder.String()
+}
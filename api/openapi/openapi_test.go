@@ -0,0 +1,105 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+type embedded struct {
+	Page int `form:"page"`
+}
+
+type query struct {
+	embedded
+	Search string `form:"search" binding:"required"`
+}
+
+type request struct {
+	Title   string    `json:"title" binding:"required,max=200"`
+	Kind    string    `json:"kind,omitempty" binding:"omitempty,oneof=plain markdown"`
+	Team    *int      `json:"teamId,omitempty" binding:"omitempty,min=-1"`
+	Created time.Time `json:"createdAt"`
+	Nested  *response `json:"nested"`
+	Ignored string    `json:"-"`
+}
+
+type response struct {
+	Id string `json:"id"`
+}
+
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func TestSchemaFollowsTags(t *testing.T) {
+	registry := newSchemaRegistry(map[string]string{"request.kind": "How the content is shown"})
+	reference := registry.of(request{})
+	if reference.Ref != "#/components/schemas/request" {
+		t.Fatalf("expected reference to component, got '%s'", reference.Ref)
+	}
+
+	schema := registry.components["request"]
+	if len(schema.Required) != 1 || schema.Required[0] != "title" {
+		t.Errorf("expected only title to be required, got %v", schema.Required)
+	}
+	if title := schema.Properties["title"]; title.MaxLength == nil || *title.MaxLength != 200 {
+		t.Errorf("expected title to have a max length of 200")
+	}
+	if kind := schema.Properties["kind"]; len(kind.Enum) != 2 || kind.Description != "How the content is shown" {
+		t.Errorf("expected kind to be an enum with description, got %+v", kind)
+	}
+	if team := schema.Properties["teamId"]; !team.Nullable || team.Minimum == nil || *team.Minimum != -1 {
+		t.Errorf("expected nullable teamId with minimum -1, got %+v", team)
+	}
+	if created := schema.Properties["createdAt"]; created.Format != "date-time" {
+		t.Errorf("expected createdAt to be a date-time, got %+v", created)
+	}
+	if nested := schema.Properties["nested"]; nested.Ref != "#/components/schemas/response" {
+		t.Errorf("expected nested to reference response, got %+v", nested)
+	}
+	if _, exists := schema.Properties["Ignored"]; exists {
+		t.Errorf("expected fields without json name to be skipped")
+	}
+}
+
+func TestQueryParametersIncludeEmbeddedStructs(t *testing.T) {
+	parameters := newSchemaRegistry(nil).queryParameters(query{})
+	if len(parameters) != 2 || parameters[0].Name != "page" || parameters[1].Name != "search" || !parameters[1].Required {
+		t.Errorf("unexpected parameters %+v", parameters)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	routes := []Route{
+		{Method: http.MethodPatch, Path: "/share/:id/grants/:userId", Auth: AuthRequired, Body: request{}, Errors: []int{404}},
+		{Method: http.MethodGet, Path: "/share/:id/grants/:userId", Auth: AuthOptional, Response: response{}},
+	}
+	document, err := Build(Info{Title: "test"}, routes, nil, apiError{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operation := document.Paths["/share/{id}/grants/{userId}"]["patch"]
+	if operation == nil {
+		t.Fatalf("expected path parameters to be converted, got paths %v", document.Paths)
+	}
+	if operation.OperationId != "patchShareIdGrantsUserId" {
+		t.Errorf("unexpected operation id '%s'", operation.OperationId)
+	}
+	if len(operation.Parameters) != 2 || operation.Parameters[1].Name != "userId" {
+		t.Errorf("expected two path parameters, got %+v", operation.Parameters)
+	}
+	for _, status := range []string{"200", "400", "401", "404", "500"} {
+		if _, exists := operation.Responses[status]; !exists {
+			t.Errorf("expected response %s to be documented", status)
+		}
+	}
+	if get := document.Paths["/share/{id}/grants/{userId}"]["get"]; len(get.Security) != 2 {
+		t.Errorf("expected optional authentication to allow requests without a session")
+	}
+
+	if _, err := Build(Info{}, append(routes, routes[0]), nil, apiError{}); err == nil {
+		t.Errorf("expected routes documented twice to be rejected")
+	}
+}
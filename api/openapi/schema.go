@@ -0,0 +1,208 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaRegistry generates schemas of Go types, named structs are added to the components once
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	fields     map[string]string
+}
+
+func newSchemaRegistry(fields map[string]string) *schemaRegistry {
+	return &schemaRegistry{components: map[string]*Schema{}, names: map[reflect.Type]string{}, fields: fields}
+}
+
+func (registry *schemaRegistry) of(value any) *Schema {
+	return registry.schema(reflect.TypeOf(value))
+}
+
+func (registry *schemaRegistry) schema(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		schema := registry.schema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: registry.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: registry.schema(t.Elem())}
+	case reflect.Struct:
+		return registry.structSchema(t)
+	}
+	return &Schema{}
+}
+
+func (registry *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return registry.objectSchema(t, "")
+	}
+	if name, exists := registry.names[t]; exists {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	name := t.Name()
+	if _, taken := registry.components[name]; taken {
+		packagePath := strings.Split(t.PkgPath(), "/")
+		name = strings.ToUpper(packagePath[len(packagePath)-1][:1]) + packagePath[len(packagePath)-1][1:] + name
+	}
+	// Registered before the properties, so types referring to themselves end up as references
+	registry.names[t] = name
+	registry.components[name] = &Schema{}
+	*registry.components[name] = *registry.objectSchema(t, name)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (registry *schemaRegistry) objectSchema(t reflect.Type, name string) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	registry.addProperties(schema, t, name)
+	return schema
+}
+
+func (registry *schemaRegistry) addProperties(schema *Schema, t reflect.Type, name string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			registry.addProperties(schema, field.Type, name)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		property := strings.Split(field.Tag.Get("json"), ",")[0]
+		if property == "-" {
+			continue
+		}
+		if property == "" {
+			property = field.Name
+		}
+
+		fieldSchema := registry.schema(field.Type)
+		if applyBinding(fieldSchema, field.Type, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, property)
+		}
+		if description := registry.fields[name+"."+property]; description != "" {
+			fieldSchema = describe(fieldSchema, description)
+		}
+		schema.Properties[property] = fieldSchema
+	}
+}
+
+// queryParameters documents the form tags of a struct bound with ShouldBindQuery
+func (registry *schemaRegistry) queryParameters(query any) []Parameter {
+	t := reflect.TypeOf(query)
+	parameters := make([]Parameter, 0)
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				collect(field.Type)
+				continue
+			}
+			name := strings.Split(field.Tag.Get("form"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			schema := registry.schema(field.Type)
+			required := applyBinding(schema, field.Type, field.Tag.Get("binding"))
+			parameters = append(parameters, Parameter{
+				Name:        name,
+				In:          "query",
+				Required:    required,
+				Description: registry.fields[t.Name()+"."+name],
+				Schema:      schema,
+			})
+		}
+	}
+	collect(t)
+	return parameters
+}
+
+// applyBinding turns validator rules into constraints and reports whether the field is required
+func applyBinding(schema *Schema, t reflect.Type, binding string) bool {
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "min", "max":
+			limit, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			isString := t.Kind() == reflect.String
+			switch {
+			case name == "min" && isString:
+				schema.MinLength = &limit
+			case name == "max" && isString:
+				schema.MaxLength = &limit
+			case name == "min":
+				schema.Minimum = &limit
+			default:
+				schema.Maximum = &limit
+			}
+		}
+	}
+	return required
+}
+
+// describe adds a description, references can't have siblings so they are wrapped
+func describe(schema *Schema, description string) *Schema {
+	if schema.Ref != "" {
+		return &Schema{Description: description, AllOf: []*Schema{schema}}
+	}
+	schema.Description = description
+	return schema
+}
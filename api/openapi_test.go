@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"qr-pastebin-api/openapi"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	document, err := buildOpenAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router, err := setupRouter(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := router.Routes()
+	registered := map[string]bool{}
+	for _, route := range routes {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for _, route := range routes {
+		versioned, isVersioned := strings.CutPrefix(route.Path, API_VERSION_PREFIX+"/")
+		if !isVersioned {
+			if !registered[route.Method+" "+API_VERSION_PREFIX+route.Path] {
+				t.Errorf("route %s %s has no counterpart under %s", route.Method, route.Path, API_VERSION_PREFIX)
+			}
+			continue
+		}
+		path := openapi.ConvertPath("/" + versioned)
+		if _, exists := document.Paths[path][strings.ToLower(route.Method)]; !exists {
+			t.Errorf("route %s %s is not described in routeDocs", route.Method, route.Path)
+		}
+	}
+	for _, route := range routeDocs {
+		if !registered[route.Method+" "+API_VERSION_PREFIX+route.Path] {
+			t.Errorf("documented route %s %s is not registered", route.Method, route.Path)
+		}
+	}
+}
+
+func TestOpenAPIDocumentIsServed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var err error
+	openAPIDocument, err = buildOpenAPIDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router, err := setupRouter(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+
+	var document struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string `json:"required"`
+				Properties map[string]struct {
+					Description string `json:"description"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+	if document.OpenAPI != openapi.Version {
+		t.Errorf("expected OpenAPI version %s, got '%s'", openapi.Version, document.OpenAPI)
+	}
+	for _, name := range []string{"ShareRequest", "ShareResponse", "UserCredentials", "GetProtectedShareRequest", "Problem"} {
+		if _, exists := document.Components.Schemas[name]; !exists {
+			t.Errorf("expected schema %s to be documented", name)
+		}
+	}
+	expireIn := document.Components.Schemas["ShareRequest"].Properties["expireIn"].Description
+	if !strings.Contains(expireIn, "no-change") {
+		t.Errorf("expected expireIn to document no-change, got '%s'", expireIn)
+	}
+}
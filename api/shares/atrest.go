@@ -0,0 +1,159 @@
+package shares
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qr-pastebin-api/envelope"
+	"time"
+)
+
+const keyRotationBatchSize = 100
+
+// sealedShare holds title and content as they are stored in the database
+type sealedShare struct {
+	Title   string
+	Content string
+	DataKey string
+	KeyId   string
+}
+
+// sealShare encrypts title and content with a new data key. When no master key is configured
+// they are stored unencrypted, with an empty key id.
+func (handler *ShareDBHandler) sealShare(shareId string, title string, content string) (*sealedShare, error) {
+	if !handler.Keys.Enabled() {
+		return &sealedShare{Title: title, Content: content}, nil
+	}
+
+	dataKey, wrapped, keyId, err := handler.Keys.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	sealed := sealedShare{DataKey: wrapped, KeyId: keyId}
+	sealed.Title, err = envelope.Seal(dataKey, []byte(title), fieldAdditionalData(shareId, "title"))
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt title of share '%s': %w", shareId, err)
+	}
+	sealed.Content, err = envelope.Seal(dataKey, []byte(content), fieldAdditionalData(shareId, "content"))
+	if err != nil {
+		return nil, fmt.Errorf("could not encrypt content of share '%s': %w", shareId, err)
+	}
+	return &sealed, nil
+}
+
+// openShare decrypts title and content of a share read from the database in place
+func (handler *ShareDBHandler) openShare(share *Share) error {
+	if share.KeyId == "" {
+		return nil
+	}
+
+	dataKey, err := handler.Keys.UnwrapDataKey(share.KeyId, share.DataKey)
+	if err != nil {
+		return fmt.Errorf("could not decrypt share '%s': %w", share.Id, err)
+	}
+	title, err := envelope.Open(dataKey, share.Title, fieldAdditionalData(share.Id, "title"))
+	if err != nil {
+		return fmt.Errorf("could not decrypt title of share '%s': %w", share.Id, err)
+	}
+	content, err := envelope.Open(dataKey, share.Content, fieldAdditionalData(share.Id, "content"))
+	if err != nil {
+		return fmt.Errorf("could not decrypt content of share '%s': %w", share.Id, err)
+	}
+	share.Title = string(title)
+	share.Content = string(content)
+	return nil
+}
+
+// Binding ciphertext to the share and field it belongs to stops it from being copied elsewhere
+func fieldAdditionalData(shareId string, field string) []byte {
+	return []byte(shareId + "/" + field)
+}
+
+// RotateKeys moves the next batch of shares with an id after the given one to the active master key.
+// It returns how many shares the batch had and the id of the last one, to continue after it.
+// Shares stored unencrypted are encrypted, the others only get their data key re-wrapped.
+// Shares wrapped by a key that is no longer configured are left alone, as they can't be read.
+// A share that can't be rotated is logged and skipped, so it doesn't hold up the others.
+func (handler *ShareDBHandler) RotateKeys(after string, batchSize int) (int, string, error) {
+	if !handler.Keys.Enabled() {
+		return 0, after, nil
+	}
+
+	query := "SELECT id, title, content, data_key, key_id FROM shares WHERE id > $1 AND key_id != $2 AND (key_id = '' OR key_id = ANY($3)) ORDER BY id LIMIT $4;"
+	rows, err := handler.DB.Query(context.Background(), query, after, handler.Keys.ActiveKeyId(), handler.Keys.RetiredKeyIds(), batchSize)
+	if err != nil {
+		return 0, after, fmt.Errorf("error querying shares to rotate: %w", err)
+	}
+	shares := make([]Share, 0)
+	for rows.Next() {
+		var share Share
+		if err := rows.Scan(&share.Id, &share.Title, &share.Content, &share.DataKey, &share.KeyId); err != nil {
+			rows.Close()
+			return 0, after, err
+		}
+		shares = append(shares, share)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, after, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	for _, share := range shares {
+		if err := handler.rotateShareKey(&share); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		after = share.Id
+	}
+	return len(shares), after, nil
+}
+
+// Updates only apply if the share still uses the key it was read with, a concurrent edit has already re-encrypted it
+func (handler *ShareDBHandler) rotateShareKey(share *Share) error {
+	if share.KeyId == "" {
+		sealed, err := handler.sealShare(share.Id, share.Title, share.Content)
+		if err != nil {
+			return err
+		}
+		query := "UPDATE shares SET title = $1, content = $2, data_key = $3, key_id = $4 WHERE id = $5 AND key_id = '';"
+		_, err = handler.DB.Exec(context.Background(), query, sealed.Title, sealed.Content, sealed.DataKey, sealed.KeyId, share.Id)
+		if err != nil {
+			return fmt.Errorf("could not encrypt share '%s': %w", share.Id, err)
+		}
+		return nil
+	}
+
+	dataKey, keyId, err := handler.Keys.RewrapDataKey(share.KeyId, share.DataKey)
+	if err != nil {
+		return fmt.Errorf("could not rotate key of share '%s': %w", share.Id, err)
+	}
+	query := "UPDATE shares SET data_key = $1, key_id = $2 WHERE id = $3 AND key_id = $4;"
+	_, err = handler.DB.Exec(context.Background(), query, dataKey, keyId, share.Id, share.KeyId)
+	if err != nil {
+		return fmt.Errorf("could not rotate key of share '%s': %w", share.Id, err)
+	}
+	return nil
+}
+
+// RunKeyRotation keeps moving shares to the active master key, checking for new work every interval.
+// Every run goes through all shares once, shares that failed are tried again in the next run.
+// It is meant to run in its own goroutine with a handler that has a database connection to itself.
+func (handler *ShareDBHandler) RunKeyRotation(interval time.Duration) {
+	if !handler.Keys.Enabled() {
+		return
+	}
+	for {
+		after := ""
+		for {
+			read, last, err := handler.RotateKeys(after, keyRotationBatchSize)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error rotating share keys: %v\n", err)
+				break
+			}
+			if read < keyRotationBatchSize {
+				break
+			}
+			after = last
+		}
+		time.Sleep(interval)
+	}
+}
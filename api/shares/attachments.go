@@ -0,0 +1,237 @@
+package shares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"qr-pastebin-api/blob"
+	"qr-pastebin-api/common"
+	"time"
+)
+
+type AttachmentResponse struct {
+	Id        string    `json:"id"`
+	FileName  string    `json:"fileName"`
+	MimeType  string    `json:"mimeType"`
+	Size      int64     `json:"size"`
+	Checksum  string    `json:"sha256"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Attachment struct {
+	Id         string
+	ShareId    string
+	FileName   string
+	MimeType   string
+	Size       int64
+	Checksum   string
+	StorageKey string
+	CreatedAt  time.Time
+}
+
+// AddAttachment stores the file and attaches it to the share. The MIME type sent by the client is
+// kept when it is valid, otherwise it is detected from the content.
+func (handler *ShareDBHandler) AddAttachment(shareId string, userId int, role common.Role, fileName string, mimeType string, size int64, file io.ReadSeeker) (*AttachmentResponse, error) {
+	permit, err := handler.CanEditShare(userId, shareId, role)
+	if err != nil {
+		return nil, err
+	}
+	if !permit {
+		return nil, &common.NotFoundError{}
+	}
+
+	if size > handler.Limits.MaxAttachmentSize {
+		return nil, &AttachmentTooLargeError{Limit: handler.Limits.MaxAttachmentSize}
+	}
+	var usedSize int64
+	err = handler.DB.QueryRow(context.Background(), "SELECT COALESCE(SUM(size), 0) FROM attachments WHERE share_id = $1;", shareId).Scan(&usedSize)
+	if err != nil {
+		return nil, err
+	}
+	if usedSize+size > handler.Limits.MaxShareAttachmentsSize {
+		return nil, &AttachmentQuotaExceededError{Limit: handler.Limits.MaxShareAttachmentsSize}
+	}
+	share, err := handler.readShare(shareId)
+	if err != nil {
+		return nil, err
+	}
+	if err := handler.ensureStorageQuota(share.AuthorId, size); err != nil {
+		return nil, err
+	}
+
+	attachment := Attachment{
+		Id:       common.CreateRandomId(12),
+		ShareId:  shareId,
+		FileName: sanitizeFileName(fileName),
+		Size:     size,
+	}
+	if attachment.FileName == "" {
+		attachment.FileName = attachment.Id
+	}
+	attachment.StorageKey = fmt.Sprintf("shares/%s/%s", shareId, attachment.Id)
+
+	attachment.Checksum, attachment.MimeType, err = inspectFile(file, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = handler.Blobs.Put(context.Background(), attachment.StorageKey, file, size, attachment.MimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "INSERT INTO attachments (id, share_id, file_name, mime_type, size, checksum, storage_key) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at;"
+	err = handler.DB.QueryRow(context.Background(), query, attachment.Id, shareId, attachment.FileName, attachment.MimeType, size, attachment.Checksum, attachment.StorageKey).Scan(&attachment.CreatedAt)
+	if err != nil {
+		handler.Blobs.Delete(context.Background(), attachment.StorageKey)
+		return nil, fmt.Errorf("couldn't save attachment of share '%s': %w", shareId, err)
+	}
+	return attachment.toResponse(), nil
+}
+
+// GetAttachment opens the attachment for download, with the same access rules as the share content.
+// unlocked is set when the password of the share was needed and given.
+func (handler *ShareDBHandler) GetAttachment(shareId string, attachmentId string, userId int, role common.Role, link SignedLink, password string) (attachment *Attachment, reader io.ReadCloser, unlocked bool, err error) {
+	share, err := handler.readShare(shareId)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	unlocked, err = handler.authorizeRead(share, userId, role, link, password)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	attachment, err = handler.readAttachment(shareId, attachmentId)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	reader, err = handler.Blobs.Get(context.Background(), attachment.StorageKey)
+	if err != nil {
+		var blobNotFoundErr *blob.NotFoundError
+		if errors.As(err, &blobNotFoundErr) {
+			return nil, nil, false, &common.NotFoundError{}
+		}
+		return nil, nil, false, err
+	}
+	return attachment, reader, unlocked, nil
+}
+
+func (handler *ShareDBHandler) DeleteAttachment(shareId string, attachmentId string, userId int, role common.Role) error {
+	permit, err := handler.CanEditShare(userId, shareId, role)
+	if err != nil {
+		return err
+	}
+	if !permit {
+		return &common.NotFoundError{}
+	}
+
+	attachment, err := handler.readAttachment(shareId, attachmentId)
+	if err != nil {
+		return err
+	}
+	if err := handler.Blobs.Delete(context.Background(), attachment.StorageKey); err != nil {
+		return err
+	}
+	_, err = handler.DB.Exec(context.Background(), "DELETE FROM attachments WHERE id = $1;", attachmentId)
+	return err
+}
+
+// deleteAttachments removes every attachment of the share, used when the share itself is deleted
+func (handler *ShareDBHandler) deleteAttachments(shareId string) error {
+	attachments, err := handler.readAttachments(shareId)
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		if err := handler.Blobs.Delete(context.Background(), attachment.StorageKey); err != nil {
+			return err
+		}
+	}
+	_, err = handler.DB.Exec(context.Background(), "DELETE FROM attachments WHERE share_id = $1;", shareId)
+	return err
+}
+
+const attachmentColumns = "id, share_id, file_name, mime_type, size, checksum, storage_key, created_at"
+
+func scanAttachment(row rowScanner) (*Attachment, error) {
+	var attachment Attachment
+	err := row.Scan(&attachment.Id, &attachment.ShareId, &attachment.FileName, &attachment.MimeType, &attachment.Size, &attachment.Checksum, &attachment.StorageKey, &attachment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (handler *ShareDBHandler) readAttachment(shareId string, attachmentId string) (*Attachment, error) {
+	query := fmt.Sprintf("SELECT %s FROM attachments WHERE id = $1 AND share_id = $2;", attachmentColumns)
+	return scanAttachment(handler.DB.QueryRow(context.Background(), query, attachmentId, shareId))
+}
+
+func (handler *ShareDBHandler) readAttachments(shareId string) ([]Attachment, error) {
+	query := fmt.Sprintf("SELECT %s FROM attachments WHERE share_id = $1 ORDER BY created_at;", attachmentColumns)
+	return handler.queryAttachments(query, shareId)
+}
+
+func (handler *ShareDBHandler) queryAttachments(query string, args ...any) ([]Attachment, error) {
+	rows, err := handler.DB.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := make([]Attachment, 0)
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *attachment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return attachments, nil
+}
+
+func (attachment *Attachment) toResponse() *AttachmentResponse {
+	return &AttachmentResponse{
+		Id:        attachment.Id,
+		FileName:  attachment.FileName,
+		MimeType:  attachment.MimeType,
+		Size:      attachment.Size,
+		Checksum:  attachment.Checksum,
+		CreatedAt: attachment.CreatedAt,
+	}
+}
+
+// ETag of an attachment is its checksum, as the content of an attachment never changes
+func (attachment *Attachment) ETag() string {
+	return fmt.Sprintf(`"%s"`, attachment.Checksum)
+}
+
+// inspectFile hashes the whole file and picks its MIME type, leaving the file rewound for upload
+func inspectFile(file io.ReadSeeker, mimeType string) (string, string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", "", err
+	}
+	if _, _, err := mime.ParseMediaType(mimeType); err != nil || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(head[:n])
+	}
+
+	hash := sha256.New()
+	hash.Write(head[:n])
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), mimeType, nil
+}
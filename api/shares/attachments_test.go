@@ -0,0 +1,41 @@
+package shares
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestInspectFile(t *testing.T) {
+	file := strings.NewReader("hello")
+	checksum, mimeType, err := inspectFile(file, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checksum != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("unexpected checksum %s", checksum)
+	}
+	if mimeType != "text/plain; charset=utf-8" {
+		t.Errorf(`expected detected "text/plain; charset=utf-8", got "%s"`, mimeType)
+	}
+	if rest, _ := io.ReadAll(file); string(rest) != "hello" {
+		t.Errorf("expected file to be rewound, got %q", rest)
+	}
+
+	_, mimeType, _ = inspectFile(strings.NewReader("key: value"), "application/yaml")
+	if mimeType != "application/yaml" {
+		t.Errorf(`expected client MIME type "application/yaml" to be kept, got "%s"`, mimeType)
+	}
+}
+
+func TestAttachmentETagMatchesLikeRaw(t *testing.T) {
+	attachment := &Attachment{Checksum: "9f86d081884c7d659a2feaa0c55ad015"}
+	for _, ifNoneMatch := range []string{attachment.ETag(), "W/" + attachment.ETag(), `"other", ` + attachment.ETag(), "*"} {
+		if !MatchesETag(ifNoneMatch, attachment.ETag()) {
+			t.Errorf("expected '%s' to match %s", ifNoneMatch, attachment.ETag())
+		}
+	}
+	if MatchesETag(`"other"`, attachment.ETag()) {
+		t.Errorf("expected another ETag not to match")
+	}
+}
@@ -0,0 +1,60 @@
+package shares
+
+import (
+	"qr-pastebin-api/e2e"
+	"strings"
+)
+
+// ENCRYPTION_NONE switches an end-to-end encrypted share back to plaintext when updating it
+const ENCRYPTION_NONE = "none"
+
+func (share *Share) IsEncrypted() bool {
+	return share.Encryption != ""
+}
+
+// validateEncryptedContent makes sure shares marked as encrypted only ever store ciphertext
+func validateEncryptedContent(encryption string, content string) error {
+	if encryption == "" {
+		return nil
+	}
+	if encryption != e2e.Algorithm {
+		return &UnsupportedEncryptionError{Encryption: encryption}
+	}
+	if err := e2e.Validate(content); err != nil {
+		return &InvalidCiphertextError{Reason: err.Error()}
+	}
+	return nil
+}
+
+// SearchShares filters the shares of the user by a case-insensitive query. Titles of all shares are
+// searched, but content only of plaintext shares, as the server can't read end-to-end encrypted ones.
+// Ids of encrypted shares whose title didn't match are returned as unsearched, only a client holding
+// their keys can tell whether their content matches.
+func (handler *ShareDBHandler) SearchShares(userId int, query string) (found []ShareResponse, unsearched []string, err error) {
+	shares, err := handler.GetShares(userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, unsearched = searchShares(shares, query)
+	return found, unsearched, nil
+}
+
+func searchShares(shares []ShareResponse, query string) ([]ShareResponse, []string) {
+	query = strings.ToLower(query)
+	found := make([]ShareResponse, 0)
+	unsearched := make([]string, 0)
+	for _, share := range shares {
+		if strings.Contains(strings.ToLower(share.Title), query) {
+			found = append(found, share)
+			continue
+		}
+		if share.IsEncrypted {
+			unsearched = append(unsearched, share.Id)
+			continue
+		}
+		if strings.Contains(strings.ToLower(share.Content), query) {
+			found = append(found, share)
+		}
+	}
+	return found, unsearched
+}
@@ -0,0 +1,54 @@
+package shares
+
+import (
+	"errors"
+	"qr-pastebin-api/e2e"
+	"slices"
+	"testing"
+)
+
+func TestValidateEncryptedContent(t *testing.T) {
+	key, _ := e2e.GenerateKey()
+	ciphertext, err := e2e.Encrypt(key, []byte("kubectl logs output"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := validateEncryptedContent("", "plain text"); err != nil {
+		t.Errorf("expected plaintext of an unencrypted share to be valid, got %v", err)
+	}
+	if err := validateEncryptedContent(e2e.Algorithm, ciphertext); err != nil {
+		t.Errorf("expected ciphertext to be valid, got %v", err)
+	}
+
+	var ciphertextErr *InvalidCiphertextError
+	if err := validateEncryptedContent(e2e.Algorithm, "plain text"); !errors.As(err, &ciphertextErr) {
+		t.Errorf("expected InvalidCiphertextError for plaintext, got %v", err)
+	}
+	var unsupportedErr *UnsupportedEncryptionError
+	if err := validateEncryptedContent("ROT13", ciphertext); !errors.As(err, &unsupportedErr) {
+		t.Errorf("expected UnsupportedEncryptionError, got %v", err)
+	}
+}
+
+func TestSearchShares(t *testing.T) {
+	shares := []ShareResponse{
+		{Id: "a", Title: "Nginx config", Content: "server {}"},
+		{Id: "b", Title: "notes", Content: "restart NGINX after deploy"},
+		{Id: "c", Title: "nginx logs", Content: "AbCdEf", IsEncrypted: true},
+		{Id: "d", Title: "secret", Content: "AbCdEf", IsEncrypted: true},
+		{Id: "e", Title: "other", Content: "nothing"},
+	}
+
+	found, unsearched := searchShares(shares, "nginx")
+	ids := []string{}
+	for _, share := range found {
+		ids = append(ids, share.Id)
+	}
+	if !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("expected shares a, b and c, got %v", ids)
+	}
+	if !slices.Equal(unsearched, []string{"d"}) {
+		t.Errorf("expected the encrypted share d to be unsearched, got %v", unsearched)
+	}
+}
@@ -1,8 +1,190 @@
 package shares
 
+import (
+	"fmt"
+	"time"
+)
+
 type ExpiredShareError struct {
 }
 
 func (e *ExpiredShareError) Error() string {
 	return "share is expired"
 }
+
+type InvalidAccessError struct {
+	Access string
+}
+
+func (e *InvalidAccessError) Error() string {
+	return fmt.Sprintf("access '%s' is not valid, use 'view' or 'edit'", e.Access)
+}
+
+type InvalidVisibilityError struct {
+	Visibility string
+}
+
+func (e *InvalidVisibilityError) Error() string {
+	return fmt.Sprintf("visibility '%s' is not valid, use 'public' or 'private'", e.Visibility)
+}
+
+type OwnerRequiredError struct {
+	Field string
+}
+
+func (e *OwnerRequiredError) Error() string {
+	return fmt.Sprintf("only the owner of the share can change '%s'", e.Field)
+}
+
+type GrantToOwnerError struct {
+}
+
+func (e *GrantToOwnerError) Error() string {
+	return "the author of a share already has full access to it"
+}
+
+type TeamMembershipRequiredError struct {
+}
+
+func (e *TeamMembershipRequiredError) Error() string {
+	return "only members of a team can put shares into it"
+}
+
+type PasswordRequiredError struct {
+}
+
+func (e *PasswordRequiredError) Error() string {
+	return "share is password protected"
+}
+
+type SignedLinkInvalidError struct {
+}
+
+func (e *SignedLinkInvalidError) Error() string {
+	return "access link is invalid or expired"
+}
+
+type TeamMoveNotAllowedError struct {
+}
+
+func (e *TeamMoveNotAllowedError) Error() string {
+	return "only the author, or an owner or admin of the share's team, can move the share between teams"
+}
+
+type OwnerlessShareError struct {
+}
+
+func (e *OwnerlessShareError) Error() string {
+	return "the author of the share was deleted, it has to stay in a team"
+}
+
+type LinkSigningDisabledError struct {
+}
+
+func (e *LinkSigningDisabledError) Error() string {
+	return "signed links are not enabled on this server"
+}
+
+type LinkExpiryInvalidError struct {
+}
+
+func (e *LinkExpiryInvalidError) Error() string {
+	return "access link must expire in the future"
+}
+
+type UnsupportedEncryptionError struct {
+	Encryption string
+}
+
+func (e *UnsupportedEncryptionError) Error() string {
+	return fmt.Sprintf("encryption '%s' is not supported, use 'AES-256-GCM'", e.Encryption)
+}
+
+type InvalidCiphertextError struct {
+	Reason string
+}
+
+func (e *InvalidCiphertextError) Error() string {
+	return fmt.Sprintf("content of an encrypted share must be ciphertext: %s", e.Reason)
+}
+
+type PlaintextRequiredError struct {
+}
+
+func (e *PlaintextRequiredError) Error() string {
+	return "share is end-to-end encrypted, the server can not read its content"
+}
+
+type InvalidMimeTypeError struct {
+	MimeType string
+}
+
+func (e *InvalidMimeTypeError) Error() string {
+	return fmt.Sprintf("'%s' is not a valid MIME type", e.MimeType)
+}
+
+type AttachmentTooLargeError struct {
+	Limit int64
+}
+
+func (e *AttachmentTooLargeError) Error() string {
+	return fmt.Sprintf("attachment is larger than the limit of %d bytes", e.Limit)
+}
+
+type AttachmentQuotaExceededError struct {
+	Limit int64
+}
+
+func (e *AttachmentQuotaExceededError) Error() string {
+	return fmt.Sprintf("attachments of a share can't take more than %d bytes together", e.Limit)
+}
+
+type UnsupportedLanguageError struct {
+	Language string
+}
+
+func (e *UnsupportedLanguageError) Error() string {
+	return fmt.Sprintf("language '%s' is not supported", e.Language)
+}
+
+type UnknownThemeError struct {
+	Theme string
+}
+
+func (e *UnknownThemeError) Error() string {
+	return fmt.Sprintf("theme '%s' does not exist", e.Theme)
+}
+
+type InvalidFormatError struct {
+	Format string
+}
+
+func (e *InvalidFormatError) Error() string {
+	return fmt.Sprintf("format '%s' is not valid, use 'plain' or 'markdown'", e.Format)
+}
+
+type StorageQuotaExceededError struct {
+	Limit int64
+}
+
+func (e *StorageQuotaExceededError) Error() string {
+	return fmt.Sprintf("shares of a user can't take more than %d bytes together", e.Limit)
+}
+
+type AuthorMismatchError struct {
+}
+
+func (e *AuthorMismatchError) Error() string {
+	return "shares can only be created with the logged in user as author"
+}
+
+type NotPublishedError struct {
+	PublishAt time.Time
+}
+
+func (e *NotPublishedError) Error() string {
+	if e.PublishAt.IsZero() {
+		return "share is not available yet"
+	}
+	return fmt.Sprintf("share is not available until %s", e.PublishAt.UTC().Format(time.RFC3339))
+}
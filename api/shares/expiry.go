@@ -0,0 +1,231 @@
+package shares
+
+import (
+	"fmt"
+	"os"
+	"qr-pastebin-api/common"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EXPIRE_NEVER     = "never"
+	EXPIRE_NO_CHANGE = "no-change"
+)
+
+// LIFETIME_ANONYMOUS is the key of the lifetime of shares created without a session
+const LIFETIME_ANONYMOUS = "anonymous"
+
+// Duration is an ISO 8601 duration like P1Y2M or PT30M. Years, months, weeks and days follow the
+// calendar like time.AddDate does, hours, minutes and seconds are exact.
+type Duration struct {
+	Years  int
+	Months int
+	Days   int
+	Clock  time.Duration
+}
+
+var durationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+func ParseDuration(value string) (Duration, error) {
+	match := durationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || strings.HasSuffix(value, "T") {
+		return Duration{}, fmt.Errorf("duration '%s' is not an ISO 8601 duration like P1DT12H", value)
+	}
+
+	parts := make([]int, len(match)-1)
+	for i, part := range match[1:] {
+		if part == "" {
+			continue
+		}
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return Duration{}, fmt.Errorf("duration '%s' is too large", value)
+		}
+		parts[i] = number
+	}
+	return Duration{
+		Years:  parts[0],
+		Months: parts[1],
+		Days:   parts[2]*7 + parts[3],
+		Clock:  time.Duration(parts[4])*time.Hour + time.Duration(parts[5])*time.Minute + time.Duration(parts[6])*time.Second,
+	}, nil
+}
+
+func (d Duration) IsZero() bool {
+	return d == Duration{}
+}
+
+func (d Duration) AddTo(t time.Time) time.Time {
+	return t.AddDate(d.Years, d.Months, d.Days).Add(d.Clock)
+}
+
+func (d Duration) String() string {
+	if d.IsZero() {
+		return "PT0S"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("P")
+	for _, part := range []struct {
+		value int
+		unit  string
+	}{{d.Years, "Y"}, {d.Months, "M"}, {d.Days, "D"}} {
+		if part.value != 0 {
+			fmt.Fprintf(&builder, "%d%s", part.value, part.unit)
+		}
+	}
+	if d.Clock != 0 {
+		builder.WriteString("T")
+		hours, minutes, seconds := int(d.Clock.Hours()), int(d.Clock.Minutes())%60, int(d.Clock.Seconds())%60
+		for _, part := range []struct {
+			value int
+			unit  string
+		}{{hours, "H"}, {minutes, "M"}, {seconds, "S"}} {
+			if part.value != 0 {
+				fmt.Fprintf(&builder, "%d%s", part.value, part.unit)
+			}
+		}
+	}
+	return builder.String()
+}
+
+// parseExpireIn reads the durations of expireIn, both ISO 8601 and the older count and unit like 5_days
+func parseExpireIn(expireIn string) (Duration, error) {
+	if strings.HasPrefix(expireIn, "P") {
+		return ParseDuration(expireIn)
+	}
+
+	parts := strings.Split(expireIn, "_")
+	if len(parts) != 2 {
+		return Duration{}, fmt.Errorf("expiration period is not of correct format '%s', make sure it is formatted as '5_days' or 'P5D'", expireIn)
+	}
+	count, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Duration{}, fmt.Errorf("could not parse duration '%s' to int: %w", parts[0], err)
+	}
+
+	switch parts[1] {
+	case "minutes":
+		return Duration{Clock: time.Minute * time.Duration(count)}, nil
+	case "hours":
+		return Duration{Clock: time.Hour * time.Duration(count)}, nil
+	case "days":
+		return Duration{Days: count}, nil
+	case "weeks":
+		return Duration{Days: count * 7}, nil
+	case "months":
+		return Duration{Months: count}, nil
+	case "years":
+		return Duration{Years: count}, nil
+	}
+	return Duration{}, fmt.Errorf("unknown duration type '%s'", parts[1])
+}
+
+// expirationDate resolves when a share or link expires, the zero time meaning never. expiresAt is
+// an RFC 3339 timestamp and takes precedence over expireIn, which is "never" or a duration.
+// Malformed values are reported as a validation error of the field they came from.
+func expirationDate(expiresAt string, expireIn string, now time.Time) (time.Time, error) {
+	if expiresAt != "" {
+		date, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return time.Time{}, expiryFieldError("expiresAt", "rfc3339", "must be an RFC 3339 timestamp like 2030-01-31T12:00:00Z")
+		}
+		return date, nil
+	}
+	if expireIn == EXPIRE_NEVER {
+		return time.Time{}, nil
+	}
+
+	duration, err := parseExpireIn(expireIn)
+	if err != nil {
+		return time.Time{}, expiryFieldError("expireIn", "duration", "must be never, an ISO 8601 duration like P1DT12H or a count and unit like 5_days")
+	}
+	return duration.AddTo(now), nil
+}
+
+// Lifetime bounds how long shares may live when they are created or their expiry is changed.
+// A zero Min or Max leaves that side open, a Max also rules out shares that never expire.
+type Lifetime struct {
+	Min Duration
+	Max Duration
+}
+
+// ParseLifetime reads lifetimes formatted as "min/max" ISO 8601 durations, e.g. "PT5M/P30D" or "/P1Y"
+func ParseLifetime(value string) (Lifetime, error) {
+	min, max, found := strings.Cut(value, "/")
+	if !found {
+		return Lifetime{}, fmt.Errorf("lifetime '%s' is not of correct format, make sure it is formatted as 'min/max' like 'PT5M/P30D'", value)
+	}
+
+	var lifetime Lifetime
+	var err error
+	if min != "" {
+		if lifetime.Min, err = ParseDuration(min); err != nil {
+			return Lifetime{}, err
+		}
+	}
+	if max != "" {
+		if lifetime.Max, err = ParseDuration(max); err != nil {
+			return Lifetime{}, err
+		}
+	}
+	return lifetime, nil
+}
+
+// readLifetimesFromEnv reads SHARE_LIFETIME_ANONYMOUS and SHARE_LIFETIME_<ROLE> for every role
+func readLifetimesFromEnv() (map[string]Lifetime, error) {
+	lifetimes := map[string]Lifetime{}
+	keys := []string{LIFETIME_ANONYMOUS, string(common.USER), string(common.MODERATOR), string(common.ADMIN)}
+	for _, key := range keys {
+		name := "SHARE_LIFETIME_" + strings.ToUpper(key)
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		lifetime, err := ParseLifetime(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		lifetimes[key] = lifetime
+	}
+	return lifetimes, nil
+}
+
+// checkExpiration makes sure the share expires in the future and within the lifetime configured for
+// the user, field names the request field the expiry came from
+func (limits Limits) checkExpiration(userId int, role common.Role, field string, expireAt time.Time, now time.Time) error {
+	if !expireAt.IsZero() && !expireAt.After(now) {
+		return expiryFieldError(field, "future", "must be in the future")
+	}
+
+	key := string(role)
+	if userId == -1 {
+		key = LIFETIME_ANONYMOUS
+	}
+	lifetime, exists := limits.Lifetimes[key]
+	if !exists {
+		return nil
+	}
+
+	if !lifetime.Max.IsZero() && (expireAt.IsZero() || expireAt.After(lifetime.Max.AddTo(now))) {
+		return expiryFieldError(field, "max_lifetime", fmt.Sprintf("must expire within %s", lifetime.Max))
+	}
+	if !lifetime.Min.IsZero() && !expireAt.IsZero() && expireAt.Before(lifetime.Min.AddTo(now)) {
+		return expiryFieldError(field, "min_lifetime", fmt.Sprintf("must not expire within %s", lifetime.Min))
+	}
+	return nil
+}
+
+func expiryField(request ShareRequest) string {
+	if request.ExpiresAt != "" {
+		return "expiresAt"
+	}
+	return "expireIn"
+}
+
+func expiryFieldError(field string, rule string, message string) *common.ValidationError {
+	return &common.ValidationError{Fields: []common.FieldError{{Field: field, Rule: rule, Message: message}}}
+}
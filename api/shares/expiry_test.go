@@ -0,0 +1,137 @@
+package shares
+
+import (
+	"errors"
+	"qr-pastebin-api/common"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Duration
+	}{
+		{"P1Y2M3D", Duration{Years: 1, Months: 2, Days: 3}},
+		{"P2W", Duration{Days: 14}},
+		{"PT1H30M", Duration{Clock: 90 * time.Minute}},
+		{"P1DT12H", Duration{Days: 1, Clock: 12 * time.Hour}},
+		{"PT45S", Duration{Clock: 45 * time.Second}},
+	}
+	for _, test := range tests {
+		got, err := ParseDuration(test.value)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.value, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.value, test.expected, got)
+		}
+		if test.value != "P2W" && got.String() != test.value {
+			t.Errorf("%s: expected to format back, got %s", test.value, got.String())
+		}
+	}
+
+	for _, value := range []string{"", "P", "PT", "1D", "P1H", "PT1D", "P1.5D", "P-1D"} {
+		if _, err := ParseDuration(value); err == nil {
+			t.Errorf("%s: expected duration to be rejected", value)
+		}
+	}
+}
+
+func TestExpirationDate(t *testing.T) {
+	now := time.Date(2030, time.January, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expiresAt string
+		expireIn  string
+		expected  time.Time
+	}{
+		{"", "never", time.Time{}},
+		{"", "5_days", time.Date(2030, time.February, 5, 12, 0, 0, 0, time.UTC)},
+		{"", "2_weeks", time.Date(2030, time.February, 14, 12, 0, 0, 0, time.UTC)},
+		{"", "30_minutes", now.Add(30 * time.Minute)},
+		{"", "P1M", time.Date(2030, time.March, 3, 12, 0, 0, 0, time.UTC)},
+		{"", "PT36H", now.Add(36 * time.Hour)},
+		{"2030-06-01T08:00:00+02:00", "never", time.Date(2030, time.June, 1, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got, err := expirationDate(test.expiresAt, test.expireIn, now)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error %v", test.expiresAt, test.expireIn, err)
+			continue
+		}
+		if !got.Equal(test.expected) {
+			t.Errorf("%s %s: expected %s, got %s", test.expiresAt, test.expireIn, test.expected, got)
+		}
+	}
+}
+
+func TestExpirationDateReportsField(t *testing.T) {
+	tests := []struct {
+		expiresAt string
+		expireIn  string
+		field     string
+	}{
+		{"", "5_fortnights", "expireIn"},
+		{"", "soon", "expireIn"},
+		{"", "P1X", "expireIn"},
+		{"tomorrow", "5_days", "expiresAt"},
+	}
+	for _, test := range tests {
+		_, err := expirationDate(test.expiresAt, test.expireIn, time.Now())
+		var validationErr *common.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != test.field {
+			t.Errorf("%s %s: expected validation error of %s, got %v", test.expiresAt, test.expireIn, test.field, err)
+		}
+	}
+}
+
+func TestCheckExpiration(t *testing.T) {
+	now := time.Now()
+	limits := Limits{Lifetimes: map[string]Lifetime{
+		LIFETIME_ANONYMOUS: {Min: Duration{Clock: 5 * time.Minute}, Max: Duration{Days: 7}},
+		"user":             {Max: Duration{Years: 1}},
+	}}
+	tests := []struct {
+		userId   int
+		role     common.Role
+		expireAt time.Time
+		rule     string
+	}{
+		{-1, common.USER, now.AddDate(0, 0, 7), ""},
+		{-1, common.USER, now.AddDate(0, 0, 8), "max_lifetime"},
+		{-1, common.USER, time.Time{}, "max_lifetime"},
+		{-1, common.USER, now.Add(time.Minute), "min_lifetime"},
+		{5, common.USER, now.AddDate(0, 0, 8), ""},
+		{5, common.USER, time.Time{}, "max_lifetime"},
+		{5, common.ADMIN, time.Time{}, ""},
+		{5, common.ADMIN, now.Add(-time.Minute), "future"},
+	}
+	for i, test := range tests {
+		err := limits.checkExpiration(test.userId, test.role, "expireIn", test.expireAt, now)
+		if test.rule == "" {
+			if err != nil {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			}
+			continue
+		}
+		var validationErr *common.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Fields[0].Rule != test.rule {
+			t.Errorf("case %d: expected rule %s to fail, got %v", i, test.rule, err)
+		}
+	}
+}
+
+func TestParseLifetime(t *testing.T) {
+	lifetime, err := ParseLifetime("PT5M/P30D")
+	if err != nil || lifetime.Min.Clock != 5*time.Minute || lifetime.Max.Days != 30 {
+		t.Errorf("unexpected lifetime %+v, error %v", lifetime, err)
+	}
+	lifetime, err = ParseLifetime("/P1Y")
+	if err != nil || !lifetime.Min.IsZero() || lifetime.Max.Years != 1 {
+		t.Errorf("unexpected lifetime %+v, error %v", lifetime, err)
+	}
+	if _, err := ParseLifetime("P1Y"); err == nil {
+		t.Errorf("expected lifetime without separator to be rejected")
+	}
+}
@@ -0,0 +1,95 @@
+package shares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// fakeResult is the answer of the fake database to a query, a single row of ints, strings and bools
+type fakeResult struct {
+	columns []string
+	values  []any
+}
+
+// Type oids of int8, text and bool
+var fakeTypeOids = map[string]uint32{"int": 20, "string": 25, "bool": 16}
+
+// newFakeDB connects to an in-process server speaking the Postgres protocol. Queries are sent with the
+// simple protocol, answer returns the row for each of them or nil for no rows.
+func newFakeDB(t *testing.T, answer func(query string) *fakeResult) *pgx.Conn {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go serveFakeDB(listener, answer)
+
+	url := fmt.Sprintf("postgres://test@%s/test?sslmode=disable&default_query_exec_mode=simple_protocol", listener.Addr())
+	conn, err := pgx.Connect(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close(context.Background()) })
+	return conn
+}
+
+func serveFakeDB(listener net.Listener, answer func(query string) *fakeResult) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	backend := pgproto3.NewBackend(conn, conn)
+	if _, err := backend.ReceiveStartupMessage(); err != nil {
+		return
+	}
+	backend.Send(&pgproto3.AuthenticationOk{})
+	backend.Send(&pgproto3.ParameterStatus{Name: "standard_conforming_strings", Value: "on"})
+	backend.Send(&pgproto3.ParameterStatus{Name: "client_encoding", Value: "UTF8"})
+	backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+	if err := backend.Flush(); err != nil {
+		return
+	}
+
+	for {
+		message, err := backend.Receive()
+		if err != nil {
+			return
+		}
+		query, ok := message.(*pgproto3.Query)
+		if !ok {
+			return
+		}
+		rows := 0
+		if result := answer(query.String); result != nil {
+			fields := make([]pgproto3.FieldDescription, 0, len(result.columns))
+			values := make([][]byte, 0, len(result.values))
+			for i, column := range result.columns {
+				value := result.values[i]
+				oid := fakeTypeOids[fmt.Sprintf("%T", value)]
+				fields = append(fields, pgproto3.FieldDescription{Name: []byte(column), DataTypeOID: oid, DataTypeSize: -1, TypeModifier: -1})
+				if boolean, ok := value.(bool); ok {
+					value = map[bool]string{true: "t", false: "f"}[boolean]
+				}
+				values = append(values, []byte(fmt.Sprint(value)))
+			}
+			backend.Send(&pgproto3.RowDescription{Fields: fields})
+			backend.Send(&pgproto3.DataRow{Values: values})
+			rows = 1
+		}
+		tag := strings.ToUpper(strings.Fields(query.String)[0])
+		backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(fmt.Sprintf("%s %d", tag, rows))})
+		backend.Send(&pgproto3.ReadyForQuery{TxStatus: 'I'})
+		if err := backend.Flush(); err != nil {
+			return
+		}
+	}
+}
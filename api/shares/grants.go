@@ -0,0 +1,242 @@
+package shares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"qr-pastebin-api/common"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	VISIBILITY_PUBLIC  = "public"
+	VISIBILITY_PRIVATE = "private"
+)
+
+const (
+	ACCESS_OWNER = "owner"
+	ACCESS_EDIT  = "edit"
+	ACCESS_VIEW  = "view"
+)
+
+type GrantRequest struct {
+	UserName string `json:"userName"`
+	Access   string `json:"access"`
+}
+
+type GrantResponse struct {
+	UserId   int    `json:"userId"`
+	UserName string `json:"userName"`
+	Access   string `json:"access"`
+}
+
+func (handler *ShareDBHandler) GetGrants(shareId string, userId int, role common.Role) ([]GrantResponse, error) {
+	if err := handler.ensureCanManageGrants(shareId, userId, role); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT u.id, u.name, g.access FROM share_grants AS g JOIN users AS u ON u.id = g.user_id WHERE g.share_id = $1 ORDER BY u.name;"
+	rows, err := handler.DB.Query(context.Background(), query, shareId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying grants: %w", err)
+	}
+	defer rows.Close()
+
+	grants := make([]GrantResponse, 0)
+	for rows.Next() {
+		var grant GrantResponse
+		if err := rows.Scan(&grant.UserId, &grant.UserName, &grant.Access); err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return grants, nil
+}
+
+// GrantAccess gives the named user view or edit access to the share, replacing any access they had before
+func (handler *ShareDBHandler) GrantAccess(shareId string, userId int, role common.Role, request GrantRequest) error {
+	if err := handler.ensureCanManageGrants(shareId, userId, role); err != nil {
+		return err
+	}
+	if request.Access != ACCESS_VIEW && request.Access != ACCESS_EDIT {
+		return &InvalidAccessError{Access: request.Access}
+	}
+
+	grantee, err := common.GetUserByName(handler.DB, request.UserName)
+	if err != nil {
+		return &common.NotFoundError{}
+	}
+	share, err := handler.readShare(shareId)
+	if err != nil {
+		return err
+	}
+	if share.AuthorId == grantee.Id {
+		return &GrantToOwnerError{}
+	}
+
+	query := "INSERT INTO share_grants (share_id, user_id, access) VALUES ($1, $2, $3) ON CONFLICT (share_id, user_id) DO UPDATE SET access = EXCLUDED.access;"
+	_, err = handler.DB.Exec(context.Background(), query, shareId, grantee.Id, request.Access)
+	if err != nil {
+		return fmt.Errorf("could not grant access to share '%s': %w", shareId, err)
+	}
+	return nil
+}
+
+func (handler *ShareDBHandler) RevokeAccess(shareId string, userId int, role common.Role, granteeId int) error {
+	if err := handler.ensureCanManageGrants(shareId, userId, role); err != nil {
+		return err
+	}
+
+	tag, err := handler.DB.Exec(context.Background(), "DELETE FROM share_grants WHERE share_id = $1 AND user_id = $2;", shareId, granteeId)
+	if err != nil {
+		return fmt.Errorf("could not revoke access to share '%s': %w", shareId, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &common.NotFoundError{}
+	}
+	return nil
+}
+
+// CanEditShare permits the author, members of the owning team, users with an edit grant and roles
+// that may edit any share. Who may read the share and which team owns it stay with the author and
+// the team's owners and admins.
+func (handler *ShareDBHandler) CanEditShare(userId int, shareId string, role common.Role) (bool, error) {
+	permit, err := handler.HasAccessToShare(userId, shareId, role, common.SHARE_EDIT_ANY)
+	if err != nil || permit {
+		return permit, err
+	}
+	if userId == -1 {
+		return false, nil
+	}
+
+	var count int
+	query := "SELECT COUNT(*) FROM shares AS s JOIN team_members AS m ON m.team_id = s.team_id WHERE s.id = $1 AND m.user_id = $2;"
+	err = handler.DB.QueryRow(context.Background(), query, shareId, userId).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	access, err := handler.getGrantedAccess(shareId, userId)
+	if err != nil {
+		return false, err
+	}
+	return access == ACCESS_EDIT, nil
+}
+
+// canViewShare decides who may read a private share. Public shares are readable by anyone.
+func (handler *ShareDBHandler) canViewShare(share *Share, userId int, role common.Role) (bool, error) {
+	if share.Visibility != VISIBILITY_PRIVATE {
+		return true, nil
+	}
+	return handler.hasPrivilegedAccess(share, userId, role)
+}
+
+// hasPrivilegedAccess is true for the author, members of the owning team, users with any grant
+// and roles that may view any share. They can read private shares and skip share passwords.
+func (handler *ShareDBHandler) hasPrivilegedAccess(share *Share, userId int, role common.Role) (bool, error) {
+	if role.HasPermission(common.SHARE_VIEW_ANY) {
+		return true, nil
+	}
+	if userId == -1 {
+		return false, nil
+	}
+	if share.AuthorId == userId {
+		return true, nil
+	}
+	if share.TeamId != -1 {
+		err := handler.ensureTeamMember(share.TeamId, userId)
+		if err == nil {
+			return true, nil
+		}
+		var membershipErr *TeamMembershipRequiredError
+		if !errors.As(err, &membershipErr) {
+			return false, err
+		}
+	}
+
+	access, err := handler.getGrantedAccess(share.Id, userId)
+	if err != nil {
+		return false, err
+	}
+	return access != "", nil
+}
+
+func (handler *ShareDBHandler) getGrantedAccess(shareId string, userId int) (string, error) {
+	var access string
+	query := "SELECT access FROM share_grants WHERE share_id = $1 AND user_id = $2;"
+	err := handler.DB.QueryRow(context.Background(), query, shareId, userId).Scan(&access)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return access, nil
+}
+
+// readSharedWithUser returns shares other users granted to the user, together with the granted access
+func (handler *ShareDBHandler) readSharedWithUser(userId int) ([]Share, []string, error) {
+	query := fmt.Sprintf("SELECT %s, g.access FROM share_grants AS g JOIN shares AS s ON s.id = g.share_id WHERE g.user_id = $1;", shareColumns)
+	rows, err := handler.DB.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error querying shared shares: %w", err)
+	}
+	defer rows.Close()
+
+	shares := make([]Share, 0)
+	accesses := make([]string, 0)
+	for rows.Next() {
+		var access string
+		share, err := scanShare(rows, &access)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := handler.openShare(share); err != nil {
+			return nil, nil, err
+		}
+		shares = append(shares, *share)
+		accesses = append(accesses, access)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return shares, accesses, nil
+}
+
+// Only the author (or someone allowed to edit any share) decides who else gets access
+func (handler *ShareDBHandler) ensureCanManageGrants(shareId string, userId int, role common.Role) error {
+	permit, err := handler.HasAccessToShare(userId, shareId, role, common.SHARE_EDIT_ANY)
+	if err != nil {
+		return err
+	}
+	if !permit {
+		return &common.NotFoundError{}
+	}
+	return nil
+}
+
+// accessChange returns the field of an update that changes who can read the share, or "" when the
+// update leaves the password and visibility as they are
+func accessChange(share *Share, shareBody ShareRequest) string {
+	if shareBody.SetPassword {
+		return "setPassword"
+	}
+	if shareBody.Visibility != "" && shareBody.Visibility != share.Visibility {
+		return "visibility"
+	}
+	return ""
+}
+
+func validateVisibility(visibility string) error {
+	if visibility != VISIBILITY_PUBLIC && visibility != VISIBILITY_PRIVATE {
+		return &InvalidVisibilityError{Visibility: visibility}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package shares
+
+import (
+	"qr-pastebin-api/common"
+	"testing"
+)
+
+func TestAccessChange(t *testing.T) {
+	share := &Share{Visibility: VISIBILITY_PRIVATE}
+	tests := []struct {
+		name  string
+		body  ShareRequest
+		field string
+	}{
+		{"title and content only", ShareRequest{Title: "title", Content: "content"}, ""},
+		{"same visibility", ShareRequest{Visibility: VISIBILITY_PRIVATE}, ""},
+		{"visibility made public", ShareRequest{Visibility: VISIBILITY_PUBLIC}, "visibility"},
+		{"password removed", ShareRequest{SetPassword: true}, "setPassword"},
+		{"password changed", ShareRequest{SetPassword: true, Password: "secret"}, "setPassword"},
+	}
+	for _, test := range tests {
+		if got := accessChange(share, test.body); got != test.field {
+			t.Errorf(`%s: expected "%s", got "%s"`, test.name, test.field, got)
+		}
+	}
+}
+
+// The cases below are decided before the database is asked for teams or grants
+func TestCanViewShare(t *testing.T) {
+	handler := &ShareDBHandler{}
+	tests := []struct {
+		name   string
+		share  Share
+		userId int
+		role   common.Role
+		want   bool
+	}{
+		{"public share, anonymous", Share{AuthorId: 1, TeamId: -1, Visibility: VISIBILITY_PUBLIC}, -1, common.USER, true},
+		{"private share, anonymous", Share{AuthorId: 1, TeamId: -1, Visibility: VISIBILITY_PRIVATE}, -1, common.USER, false},
+		{"private anonymous share, anonymous", Share{AuthorId: -1, TeamId: -1, Visibility: VISIBILITY_PRIVATE}, -1, common.USER, false},
+		{"private share, author", Share{AuthorId: 1, TeamId: -1, Visibility: VISIBILITY_PRIVATE}, 1, common.USER, true},
+		{"private share, moderator", Share{AuthorId: 1, TeamId: -1, Visibility: VISIBILITY_PRIVATE}, 2, common.MODERATOR, true},
+	}
+	for _, test := range tests {
+		got, err := handler.canViewShare(&test.share, test.userId, test.role)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: expected %t, got %t", test.name, test.want, got)
+		}
+	}
+}
+
+func TestValidateVisibility(t *testing.T) {
+	for _, visibility := range []string{VISIBILITY_PUBLIC, VISIBILITY_PRIVATE} {
+		if err := validateVisibility(visibility); err != nil {
+			t.Errorf("expected '%s' to be valid, got %v", visibility, err)
+		}
+	}
+	if err := validateVisibility("unlisted"); err == nil {
+		t.Errorf("expected 'unlisted' to be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+package shares
+
+import (
+	"fmt"
+	"qr-pastebin-api/common"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// LANGUAGE_PLAINTEXT is stored when the language of the content couldn't be detected
+const LANGUAGE_PLAINTEXT = "text"
+
+const DEFAULT_THEME = "github"
+
+type HighlightRequest struct {
+	SignedLink
+	Theme       string `form:"theme"`
+	LineNumbers bool   `form:"lineNumbers"`
+}
+
+type HighlightResponse struct {
+	Language string `json:"language"`
+	Theme    string `json:"theme"`
+	Html     string `json:"html"`
+	// Unlocked is set when the password of the share was needed and given
+	Unlocked bool `json:"-"`
+}
+
+// GetHighlightedShare renders the content as HTML with inline styles of the chosen theme.
+// Access works as for the raw content, end-to-end encrypted shares can only be highlighted by the client.
+func (handler *ShareDBHandler) GetHighlightedShare(id string, userId int, role common.Role, request HighlightRequest, password string) (*HighlightResponse, error) {
+	theme := request.Theme
+	if theme == "" {
+		theme = DEFAULT_THEME
+	}
+	style, exists := styles.Registry[theme]
+	if !exists {
+		return nil, &UnknownThemeError{Theme: theme}
+	}
+
+	share, err := handler.readShare(id)
+	if err != nil {
+		return nil, err
+	}
+	unlocked, err := handler.authorizeRead(share, userId, role, request.SignedLink, password)
+	if err != nil {
+		return nil, err
+	}
+	if share.IsEncrypted() {
+		return nil, &PlaintextRequiredError{}
+	}
+
+	language := share.Language
+	if language == "" {
+		language = detectLanguage(share.Content)
+	}
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, share.Content)
+	if err != nil {
+		return nil, fmt.Errorf("could not tokenise share '%s' as %s: %w", id, language, err)
+	}
+	formatter := html.New(html.WithLineNumbers(request.LineNumbers), html.TabWidth(4))
+	var highlighted strings.Builder
+	if err := formatter.Format(&highlighted, style, iterator); err != nil {
+		return nil, fmt.Errorf("could not highlight share '%s': %w", id, err)
+	}
+
+	return &HighlightResponse{Language: language, Theme: theme, Html: highlighted.String(), Unlocked: unlocked}, nil
+}
+
+func GetThemes() []string {
+	return styles.Names()
+}
+
+// resolveLanguage validates a language given by the client, or detects it from the content when it's omitted.
+// Languages are stored by the first alias of their lexer, so "golang" and "go" end up the same.
+func resolveLanguage(language string, content string, encryption string) (string, error) {
+	if language == "" {
+		// Ciphertext says nothing about the language
+		if encryption != "" {
+			return "", nil
+		}
+		return detectLanguage(content), nil
+	}
+
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		return "", &UnsupportedLanguageError{Language: language}
+	}
+	return lexerName(lexer), nil
+}
+
+func detectLanguage(content string) string {
+	lexer := lexers.Analyse(content)
+	if lexer == nil {
+		return LANGUAGE_PLAINTEXT
+	}
+	return lexerName(lexer)
+}
+
+func lexerName(lexer chroma.Lexer) string {
+	config := lexer.Config()
+	if len(config.Aliases) > 0 {
+		return config.Aliases[0]
+	}
+	return strings.ToLower(config.Name)
+}
@@ -0,0 +1,33 @@
+package shares
+
+import "testing"
+
+func TestResolveLanguage(t *testing.T) {
+	tests := []struct {
+		language   string
+		content    string
+		encryption string
+		expected   string
+	}{
+		{"", "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n}\n", "", "go"},
+		{"", "#!/bin/bash\necho hello", "", "bash"},
+		{"", "just some words", "", LANGUAGE_PLAINTEXT},
+		{"", "ciphertext", "AES-256-GCM", ""},
+		{"golang", "", "", "go"},
+		{"Python", "", "", "python"},
+	}
+	for _, test := range tests {
+		got, err := resolveLanguage(test.language, test.content, test.encryption)
+		if err != nil {
+			t.Errorf(`language "%s": unexpected error: %v`, test.language, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf(`language "%s", content %q: expected "%s", got "%s"`, test.language, test.content, test.expected, got)
+		}
+	}
+
+	if _, err := resolveLanguage("not-a-language", "", ""); err == nil {
+		t.Errorf("expected unknown language to be rejected")
+	}
+}
@@ -0,0 +1,95 @@
+package shares
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	defaultMaxContentSize          = 1 << 20
+	defaultMaxAttachmentSize       = 10 << 20
+	defaultMaxShareAttachmentsSize = 50 << 20
+	defaultUserStorageQuota        = 100 << 20
+)
+
+// Limits caps how much data shares can hold, sizes are in bytes
+type Limits struct {
+	MaxContentSize          int64
+	MaxAttachmentSize       int64
+	MaxShareAttachmentsSize int64
+	// UserStorageQuota caps content and attachments of all shares authored by one user together
+	UserStorageQuota int64
+	// Lifetimes bound the expiry of shares by the role of the user, or LIFETIME_ANONYMOUS
+	Lifetimes map[string]Lifetime
+}
+
+// NewLimitsFromEnv reads MAX_CONTENT_SIZE, MAX_ATTACHMENT_SIZE, MAX_SHARE_ATTACHMENTS_SIZE and
+// USER_STORAGE_QUOTA, falling back to 1 MiB, 10 MiB, 50 MiB and 100 MiB. Lifetimes are unbounded
+// unless SHARE_LIFETIME_<ROLE> is set.
+func NewLimitsFromEnv() (Limits, error) {
+	limits := Limits{
+		MaxContentSize:          defaultMaxContentSize,
+		MaxAttachmentSize:       defaultMaxAttachmentSize,
+		MaxShareAttachmentsSize: defaultMaxShareAttachmentsSize,
+		UserStorageQuota:        defaultUserStorageQuota,
+	}
+	variables := map[string]*int64{
+		"MAX_CONTENT_SIZE":           &limits.MaxContentSize,
+		"MAX_ATTACHMENT_SIZE":        &limits.MaxAttachmentSize,
+		"MAX_SHARE_ATTACHMENTS_SIZE": &limits.MaxShareAttachmentsSize,
+		"USER_STORAGE_QUOTA":         &limits.UserStorageQuota,
+	}
+	for name, limit := range variables {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return limits, fmt.Errorf("%s must be a positive number of bytes, got '%s'", name, value)
+		}
+		*limit = parsed
+	}
+
+	lifetimes, err := readLifetimesFromEnv()
+	if err != nil {
+		return limits, err
+	}
+	limits.Lifetimes = lifetimes
+	return limits, nil
+}
+
+// GetStorageUsed sums up the content and attachments of every share authored by the user
+func (handler *ShareDBHandler) GetStorageUsed(userId int) (int64, error) {
+	var used int64
+	query := `SELECT
+		(SELECT COALESCE(SUM(content_size), 0) FROM shares WHERE author_id = $1) +
+		(SELECT COALESCE(SUM(a.size), 0) FROM attachments AS a JOIN shares AS s ON s.id = a.share_id WHERE s.author_id = $1);`
+	err := handler.DB.QueryRow(context.Background(), query, userId).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("could not sum up storage of user '%d': %w", userId, err)
+	}
+	return used, nil
+}
+
+// ensureStorageQuota checks that the author can store additional bytes. Anonymous shares have
+// nobody to charge, they are only limited by the size limits.
+func (handler *ShareDBHandler) ensureStorageQuota(authorId int, additional int64) error {
+	if authorId == -1 || additional <= 0 {
+		return nil
+	}
+	used, err := handler.GetStorageUsed(authorId)
+	if err != nil {
+		return err
+	}
+	if used+additional > handler.Limits.UserStorageQuota {
+		return &StorageQuotaExceededError{Limit: handler.Limits.UserStorageQuota}
+	}
+	return nil
+}
+
+func contentSize(title string, content string) int64 {
+	return int64(len(title) + len(content))
+}
@@ -0,0 +1,150 @@
+package shares
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"qr-pastebin-api/common"
+	"strings"
+	"time"
+)
+
+// SignedLink holds the query parameters of a signed access link
+type SignedLink struct {
+	ExpiresAt int64  `form:"exp"`
+	KeyId     string `form:"kid"`
+	Signature string `form:"sig"`
+}
+
+type CreateLinkRequest struct {
+	ExpiresAt string `json:"expiresAt"`
+	ExpireIn  string `json:"expireIn"`
+}
+
+type CreateLinkResponse struct {
+	Url       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// LinkSigner signs share links with the active key and verifies them with any known key,
+// so a new key can be introduced while links signed with the previous one keep working.
+type LinkSigner struct {
+	activeKeyId string
+	keys        map[string][]byte
+}
+
+// NewLinkSigner parses keys formatted as "id:secret,id:secret", the first key is used for signing
+func NewLinkSigner(keys string) (*LinkSigner, error) {
+	signer := &LinkSigner{keys: map[string][]byte{}}
+	if strings.TrimSpace(keys) == "" {
+		return signer, nil
+	}
+
+	for i, key := range strings.Split(keys, ",") {
+		parts := strings.SplitN(strings.TrimSpace(key), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("link key #%d is not of correct format, make sure it is formatted as 'id:secret'", i+1)
+		}
+		if _, exists := signer.keys[parts[0]]; exists {
+			return nil, fmt.Errorf("link key id '%s' is used more than once", parts[0])
+		}
+		signer.keys[parts[0]] = []byte(parts[1])
+		if i == 0 {
+			signer.activeKeyId = parts[0]
+		}
+	}
+	return signer, nil
+}
+
+func NewLinkSignerFromEnv() (*LinkSigner, error) {
+	return NewLinkSigner(os.Getenv("SHARE_LINK_KEYS"))
+}
+
+func (signer *LinkSigner) Sign(shareId string, version int, expiresAt time.Time) (*SignedLink, error) {
+	if signer == nil || signer.activeKeyId == "" {
+		return nil, &LinkSigningDisabledError{}
+	}
+	link := SignedLink{ExpiresAt: expiresAt.Unix(), KeyId: signer.activeKeyId}
+	link.Signature = signer.signature(signer.keys[link.KeyId], shareId, version, link.ExpiresAt)
+	return &link, nil
+}
+
+func (signer *LinkSigner) Verify(shareId string, version int, link SignedLink, now time.Time) bool {
+	if signer == nil {
+		return false
+	}
+	key, exists := signer.keys[link.KeyId]
+	if !exists || now.Unix() >= link.ExpiresAt {
+		return false
+	}
+	expected := signer.signature(key, shareId, version, link.ExpiresAt)
+	return hmac.Equal([]byte(expected), []byte(link.Signature))
+}
+
+func (signer *LinkSigner) signature(key []byte, shareId string, version int, expiresAt int64) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%d\n%d", shareId, version, expiresAt)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (link SignedLink) IsEmpty() bool {
+	return link.Signature == "" && link.KeyId == "" && link.ExpiresAt == 0
+}
+
+func (link SignedLink) QueryValues() url.Values {
+	values := url.Values{}
+	values.Set("exp", fmt.Sprintf("%d", link.ExpiresAt))
+	values.Set("kid", link.KeyId)
+	values.Set("sig", link.Signature)
+	return values
+}
+
+// CreateSignedLink creates a link that lets anyone holding it read the share until the chosen time,
+// without knowing its password. Like the password itself, links are left to the owners of the share.
+func (handler *ShareDBHandler) CreateSignedLink(shareId string, userId int, role common.Role, request CreateLinkRequest) (*CreateLinkResponse, error) {
+	permit, err := handler.HasAccessToShare(userId, shareId, role, common.SHARE_EDIT_ANY)
+	if err != nil {
+		return nil, err
+	}
+	if !permit {
+		return nil, &common.NotFoundError{}
+	}
+
+	expiresAt, err := expirationDate(request.ExpiresAt, request.ExpireIn, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !expiresAt.After(time.Now()) {
+		return nil, &LinkExpiryInvalidError{}
+	}
+
+	share, err := handler.readShare(shareId)
+	if err != nil {
+		return nil, err
+	}
+	link, err := handler.Links.Sign(shareId, share.LinkVersion, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	shareUrl := fmt.Sprintf("%s/%s?%s", strings.TrimSuffix(os.Getenv("PUBLIC_WEB_ADDRESS"), "/"), shareId, link.QueryValues().Encode())
+	return &CreateLinkResponse{Url: shareUrl, ExpiresAt: time.Unix(link.ExpiresAt, 0).UTC()}, nil
+}
+
+// RevokeSignedLinks invalidates every link issued for the share so far
+func (handler *ShareDBHandler) RevokeSignedLinks(shareId string, userId int, role common.Role) error {
+	permit, err := handler.HasAccessToShare(userId, shareId, role, common.SHARE_EDIT_ANY)
+	if err != nil {
+		return err
+	}
+	if !permit {
+		return &common.NotFoundError{}
+	}
+
+	_, err = handler.DB.Exec(context.Background(), "UPDATE shares SET link_version = link_version + 1 WHERE id = $1;", shareId)
+	return err
+}
@@ -0,0 +1,104 @@
+package shares
+
+import (
+	"errors"
+	"qr-pastebin-api/common"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedLinkVerifies(t *testing.T) {
+	signer, _ := NewLinkSigner("k1:first-secret")
+	now := time.Now()
+	link, err := signer.Sign("abc1234", 0, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !signer.Verify("abc1234", 0, *link, now) {
+		t.Errorf("expected freshly signed link to verify")
+	}
+}
+
+func TestSignedLinkExpires(t *testing.T) {
+	signer, _ := NewLinkSigner("k1:first-secret")
+	now := time.Now()
+	link, _ := signer.Sign("abc1234", 0, now.Add(time.Hour))
+	if signer.Verify("abc1234", 0, *link, now.Add(2*time.Hour)) {
+		t.Errorf("expected expired link to be rejected")
+	}
+}
+
+func TestSignedLinkIsBoundToShareAndVersion(t *testing.T) {
+	signer, _ := NewLinkSigner("k1:first-secret")
+	now := time.Now()
+	link, _ := signer.Sign("abc1234", 0, now.Add(time.Hour))
+	if signer.Verify("xyz9876", 0, *link, now) {
+		t.Errorf("expected link of another share to be rejected")
+	}
+	if signer.Verify("abc1234", 1, *link, now) {
+		t.Errorf("expected revoked link to be rejected")
+	}
+
+	link.ExpiresAt += 3600
+	if signer.Verify("abc1234", 0, *link, now) {
+		t.Errorf("expected link with changed expiry to be rejected")
+	}
+}
+
+func TestSignedLinkSurvivesKeyRotation(t *testing.T) {
+	oldSigner, _ := NewLinkSigner("k1:first-secret")
+	now := time.Now()
+	link, _ := oldSigner.Sign("abc1234", 0, now.Add(time.Hour))
+
+	rotatedSigner, _ := NewLinkSigner("k2:second-secret,k1:first-secret")
+	if !rotatedSigner.Verify("abc1234", 0, *link, now) {
+		t.Errorf("expected link signed with previous key to verify")
+	}
+	newLink, _ := rotatedSigner.Sign("abc1234", 0, now.Add(time.Hour))
+	if newLink.KeyId != "k2" {
+		t.Errorf(`expected new links to be signed with "k2", got "%s"`, newLink.KeyId)
+	}
+
+	retiredSigner, _ := NewLinkSigner("k2:second-secret")
+	if retiredSigner.Verify("abc1234", 0, *link, now) {
+		t.Errorf("expected link signed with removed key to be rejected")
+	}
+}
+
+func TestLinkKeysFormat(t *testing.T) {
+	if _, err := NewLinkSigner("missing-secret"); err == nil {
+		t.Errorf("expected key without secret to be rejected")
+	}
+	signer, err := NewLinkSigner("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := signer.Sign("abc1234", 0, time.Now().Add(time.Hour)); err == nil {
+		t.Errorf("expected signing without keys to fail")
+	}
+}
+
+// An edit grant covers title and content, a link would open the share past its password and visibility
+func TestEditGranteeCanNotManageLinks(t *testing.T) {
+	db := newFakeDB(t, func(query string) *fakeResult {
+		if strings.Contains(query, "FROM share_grants") {
+			return &fakeResult{columns: []string{"access"}, values: []any{ACCESS_EDIT}}
+		}
+		if strings.HasPrefix(query, "SELECT COUNT(*)") {
+			return &fakeResult{columns: []string{"count"}, values: []any{0}}
+		}
+		return nil
+	})
+	signer, _ := NewLinkSigner("k1:secret")
+	handler := &ShareDBHandler{DB: db, Links: signer}
+
+	var notFoundErr *common.NotFoundError
+	_, err := handler.CreateSignedLink("abc1234", 2, common.USER, CreateLinkRequest{ExpireIn: "1_days"})
+	if !errors.As(err, &notFoundErr) {
+		t.Errorf("expected creating a link to be refused, got %v", err)
+	}
+	if err := handler.RevokeSignedLinks("abc1234", 2, common.USER); !errors.As(err, &notFoundErr) {
+		t.Errorf("expected revoking links to be refused, got %v", err)
+	}
+}
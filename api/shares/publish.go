@@ -0,0 +1,47 @@
+package shares
+
+import (
+	"qr-pastebin-api/common"
+	"time"
+)
+
+// parsePublishAt reads the not-before time of a share, an empty value means no schedule
+func parsePublishAt(publishAt string) (time.Time, error) {
+	if publishAt == "" {
+		return time.Time{}, nil
+	}
+	date, err := time.Parse(time.RFC3339, publishAt)
+	if err != nil {
+		return time.Time{}, expiryFieldError("publishAt", "rfc3339", "must be an RFC 3339 timestamp like 2030-01-31T12:00:00Z")
+	}
+	return date, nil
+}
+
+// checkPublishAt rejects schedules that would keep a share hidden until it has expired
+func checkPublishAt(publishAt time.Time, expireAt time.Time) error {
+	if !publishAt.IsZero() && !expireAt.IsZero() && !publishAt.Before(expireAt) {
+		return expiryFieldError("publishAt", "before_expiry", "must be before the share expires")
+	}
+	return nil
+}
+
+// IsPublished is false until the publishAt of a scheduled share
+func (share *Share) IsPublished(now time.Time) bool {
+	return share.PublishAt.IsZero() || !now.Before(share.PublishAt)
+}
+
+// ensurePublished lets only privileged viewers read a share before it is published, which is
+// how owners check a scheduled share in advance
+func (handler *ShareDBHandler) ensurePublished(share *Share, userId int, role common.Role, now time.Time) error {
+	if share.IsPublished(now) {
+		return nil
+	}
+	privileged, err := handler.hasPrivilegedAccess(share, userId, role)
+	if err != nil {
+		return err
+	}
+	if !privileged {
+		return &NotPublishedError{PublishAt: share.PublishAt}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package shares
+
+import (
+	"errors"
+	"qr-pastebin-api/common"
+	"testing"
+	"time"
+)
+
+func TestParsePublishAt(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		rule  string
+	}{
+		{"", time.Time{}, ""},
+		{"2030-01-31T12:00:00Z", time.Date(2030, time.January, 31, 12, 0, 0, 0, time.UTC), ""},
+		{"2030-01-31T14:00:00+02:00", time.Date(2030, time.January, 31, 12, 0, 0, 0, time.UTC), ""},
+		{"2030-01-31", time.Time{}, "rfc3339"},
+		{"tomorrow", time.Time{}, "rfc3339"},
+	}
+	for _, test := range tests {
+		got, err := parsePublishAt(test.value)
+		if test.rule != "" {
+			var validationErr *common.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "publishAt" || validationErr.Fields[0].Rule != test.rule {
+				t.Errorf("%s: expected publishAt %s error, got %v", test.value, test.rule, err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(test.want) {
+			t.Errorf("%s: expected %s, got %s, %v", test.value, test.want, got, err)
+		}
+	}
+}
+
+func TestCheckPublishAt(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		publishAt time.Time
+		expireAt  time.Time
+		valid     bool
+	}{
+		{time.Time{}, now, true},
+		{now, time.Time{}, true},
+		{now, now.Add(time.Hour), true},
+		{now.Add(time.Hour), now.Add(time.Hour), false},
+		{now.Add(2 * time.Hour), now.Add(time.Hour), false},
+	}
+	for _, test := range tests {
+		err := checkPublishAt(test.publishAt, test.expireAt)
+		if (err == nil) != test.valid {
+			t.Errorf("publish at %s, expire at %s: expected valid %t, got %v", test.publishAt, test.expireAt, test.valid, err)
+		}
+	}
+}
+
+func TestIsPublished(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		publishAt time.Time
+		want      bool
+	}{
+		{time.Time{}, true},
+		{now.Add(-time.Minute), true},
+		{now, true},
+		{now.Add(time.Second), false},
+	}
+	for _, test := range tests {
+		share := Share{PublishAt: test.publishAt}
+		if got := share.IsPublished(now); got != test.want {
+			t.Errorf("publish at %s: expected %t, got %t", test.publishAt, test.want, got)
+		}
+	}
+}
+
+func TestNotPublishedErrorNamesTime(t *testing.T) {
+	err := &NotPublishedError{PublishAt: time.Date(2030, time.January, 31, 14, 0, 0, 0, time.FixedZone("EET", 2*60*60))}
+	if err.Error() != "share is not available until 2030-01-31T12:00:00Z" {
+		t.Errorf("unexpected message '%s'", err.Error())
+	}
+}
@@ -0,0 +1,168 @@
+package shares
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"qr-pastebin-api/common"
+	"strings"
+	"time"
+)
+
+// DEFAULT_MIME_TYPE is used for shares created without a MIME type
+const DEFAULT_MIME_TYPE = "text/plain"
+
+type RawShareRequest struct {
+	SignedLink
+	Download bool `form:"download"`
+}
+
+// RawShare is the share content as it is served to plain HTTP clients
+type RawShare struct {
+	Content  string
+	MimeType string
+	FileName string
+	ETag     string
+	// Unlocked is set when the password of the share was needed and given
+	Unlocked bool
+}
+
+// GetRawShare returns the content of the share for the raw endpoint. Access works as in GetShareForPublic,
+// except that password protected shares can be unlocked with a password sent along with the request.
+func (handler *ShareDBHandler) GetRawShare(id string, userId int, role common.Role, link SignedLink, password string) (*RawShare, error) {
+	share, err := handler.readShare(id)
+	if err != nil {
+		return nil, err
+	}
+
+	unlocked, err := handler.authorizeRead(share, userId, role, link, password)
+	if err != nil {
+		return nil, err
+	}
+
+	mimeType := contentTypeWithCharset(share.MimeType)
+	return &RawShare{
+		Content:  share.Content,
+		MimeType: mimeType,
+		FileName: createDownloadFileName(share.Id, share.Title, share.MimeType),
+		ETag:     createETag(mimeType, share.Content),
+		Unlocked: unlocked,
+	}, nil
+}
+
+// authorizeRead applies the access rules of reading a share, unlocking password protected shares
+// with the given password. It reports whether the password was used.
+func (handler *ShareDBHandler) authorizeRead(share *Share, userId int, role common.Role, link SignedLink, password string) (bool, error) {
+	unlocked := false
+	if !link.IsEmpty() {
+		if !handler.Links.Verify(share.Id, share.LinkVersion, link, time.Now()) {
+			return false, &SignedLinkInvalidError{}
+		}
+		if err := handler.ensurePublished(share, userId, role, time.Now()); err != nil {
+			return false, err
+		}
+	} else {
+		privileged, err := handler.hasPrivilegedAccess(share, userId, role)
+		if err != nil {
+			return false, err
+		}
+		if !privileged && share.Visibility == VISIBILITY_PRIVATE {
+			return false, &common.NotFoundError{}
+		}
+		if !privileged && !share.IsPublished(time.Now()) {
+			return false, &NotPublishedError{PublishAt: share.PublishAt}
+		}
+		if !privileged && share.PasswordHash != "" {
+			if password == "" {
+				return false, &PasswordRequiredError{}
+			}
+			if !common.IsPasswordCorrect(share.PasswordHash, password) {
+				return false, &common.PasswordIncorrectError{}
+			}
+			unlocked = true
+		}
+	}
+
+	if !share.ExpireAt.IsZero() && time.Now().After(share.ExpireAt) {
+		return false, &ExpiredShareError{}
+	}
+
+	return unlocked, nil
+}
+
+// MatchesETag reports whether an If-None-Match header value covers the ETag. Weak and strong tags are
+// compared the same way, as required for If-None-Match.
+func (raw *RawShare) MatchesETag(ifNoneMatch string) bool {
+	return MatchesETag(ifNoneMatch, raw.ETag)
+}
+
+// MatchesETag reports whether an If-None-Match header value covers etag
+func MatchesETag(ifNoneMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func validateMimeType(mimeType string) error {
+	if _, _, err := mime.ParseMediaType(mimeType); err != nil {
+		return &InvalidMimeTypeError{MimeType: mimeType}
+	}
+	return nil
+}
+
+// Text without an explicit charset is served as UTF-8, which is what the API accepts
+func contentTypeWithCharset(mimeType string) string {
+	if mimeType == "" {
+		mimeType = DEFAULT_MIME_TYPE
+	}
+	mediaType, params, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return DEFAULT_MIME_TYPE + "; charset=utf-8"
+	}
+	if strings.HasPrefix(mediaType, "text/") && params["charset"] == "" {
+		params["charset"] = "utf-8"
+	}
+	return mime.FormatMediaType(mediaType, params)
+}
+
+func createETag(mimeType string, content string) string {
+	hash := sha256.Sum256([]byte(mimeType + "\n" + content))
+	return fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:16]))
+}
+
+// createDownloadFileName names the file after the share title, falling back to the share id,
+// with an extension matching the MIME type
+func createDownloadFileName(shareId string, title string, mimeType string) string {
+	name := sanitizeFileName(title)
+	if name == "" {
+		name = shareId
+	}
+
+	extension := ".txt"
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err == nil && mediaType != DEFAULT_MIME_TYPE {
+		extensions, err := mime.ExtensionsByType(mediaType)
+		if err == nil && len(extensions) > 0 {
+			extension = extensions[0]
+		}
+	}
+	if strings.HasSuffix(strings.ToLower(name), extension) {
+		return name
+	}
+	return name + extension
+}
+
+// sanitizeFileName replaces characters that are not allowed in file names on common systems
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+}
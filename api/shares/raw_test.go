@@ -0,0 +1,55 @@
+package shares
+
+import "testing"
+
+func TestContentTypeWithCharset(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		expected string
+	}{
+		{"", "text/plain; charset=utf-8"},
+		{"text/markdown", "text/markdown; charset=utf-8"},
+		{"text/plain; charset=iso-8859-1", "text/plain; charset=iso-8859-1"},
+		{"application/json", "application/json"},
+	}
+	for _, test := range tests {
+		if got := contentTypeWithCharset(test.mimeType); got != test.expected {
+			t.Errorf(`mime type "%s": expected "%s", got "%s"`, test.mimeType, test.expected, got)
+		}
+	}
+}
+
+func TestMatchesETag(t *testing.T) {
+	raw := RawShare{ETag: createETag("text/plain; charset=utf-8", "hello")}
+	if !raw.MatchesETag(raw.ETag) {
+		t.Errorf("expected identical ETag to match")
+	}
+	if !raw.MatchesETag(`"other", W/` + raw.ETag) {
+		t.Errorf("expected weak ETag in a list to match")
+	}
+	if !raw.MatchesETag("*") {
+		t.Errorf("expected wildcard to match")
+	}
+	if raw.MatchesETag(createETag("text/plain; charset=utf-8", "hello!")) {
+		t.Errorf("expected ETag of different content not to match")
+	}
+}
+
+func TestCreateDownloadFileName(t *testing.T) {
+	tests := []struct {
+		title    string
+		mimeType string
+		expected string
+	}{
+		{"", "text/plain", "abc1234.txt"},
+		{"notes", "", "notes.txt"},
+		{"notes.txt", "text/plain", "notes.txt"},
+		{"a/b: c", "text/plain", "a_b_ c.txt"},
+		{"data", "application/json", "data.json"},
+	}
+	for _, test := range tests {
+		if got := createDownloadFileName("abc1234", test.title, test.mimeType); got != test.expected {
+			t.Errorf(`title "%s": expected "%s", got "%s"`, test.title, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package shares
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"qr-pastebin-api/common"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	FORMAT_PLAIN    = "plain"
+	FORMAT_MARKDOWN = "markdown"
+)
+
+type RenderedResponse struct {
+	Format string `json:"format"`
+	Html   string `json:"html"`
+	// Unlocked is set when the password of the share was needed and given
+	Unlocked bool `json:"-"`
+}
+
+// Markdown is rendered with GitHub flavoured extensions. Raw HTML in the source is left out by the
+// renderer, the sanitiser then only lets through elements safe for user generated content.
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+var htmlSanitiser = createSanitiserPolicy()
+
+func createSanitiserPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	policy.AllowAttrs("type", "checked", "disabled").OnElements("input")
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+	return policy
+}
+
+// GetRenderedShare returns the content as sanitised HTML. Markdown shares are rendered,
+// plain shares are escaped into a preformatted block. Access works as for the raw content.
+func (handler *ShareDBHandler) GetRenderedShare(id string, userId int, role common.Role, link SignedLink, password string) (*RenderedResponse, error) {
+	share, err := handler.readShare(id)
+	if err != nil {
+		return nil, err
+	}
+	unlocked, err := handler.authorizeRead(share, userId, role, link, password)
+	if err != nil {
+		return nil, err
+	}
+	if share.IsEncrypted() {
+		return nil, &PlaintextRequiredError{}
+	}
+
+	rendered, err := renderContent(share.Format, share.Content)
+	if err != nil {
+		return nil, fmt.Errorf("could not render share '%s': %w", id, err)
+	}
+	return &RenderedResponse{Format: share.Format, Html: rendered, Unlocked: unlocked}, nil
+}
+
+func renderContent(format string, content string) (string, error) {
+	if format != FORMAT_MARKDOWN {
+		return fmt.Sprintf("<pre>%s</pre>", html.EscapeString(content)), nil
+	}
+
+	var rendered bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(content), &rendered); err != nil {
+		return "", err
+	}
+	return htmlSanitiser.Sanitize(rendered.String()), nil
+}
+
+func validateFormat(format string) error {
+	if format != FORMAT_PLAIN && format != FORMAT_MARKDOWN {
+		return &InvalidFormatError{Format: format}
+	}
+	return nil
+}
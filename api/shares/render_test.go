@@ -0,0 +1,55 @@
+package shares
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		content  string
+		contains string
+	}{
+		{"# Runbook", "<h1>Runbook</h1>"},
+		{"| a | b |\n|---|---|\n| 1 | 2 |", "<table>"},
+		{"```bash\necho hi\n```", `<code class="language-bash">`},
+		{"- [x] done", `<input checked="" disabled="" type="checkbox"`},
+		{"[site](https://example.com)", `target="_blank"`},
+	}
+	for _, test := range tests {
+		rendered, err := renderContent(FORMAT_MARKDOWN, test.content)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(rendered, test.contains) {
+			t.Errorf("expected %q to contain %q, got %q", test.content, test.contains, rendered)
+		}
+	}
+}
+
+func TestRenderMarkdownSanitises(t *testing.T) {
+	tests := []string{
+		"<script>alert(1)</script>",
+		"[click](javascript:alert(1))",
+		"<img src=x onerror=alert(1)>",
+		"<a href=\"#\" onclick=\"alert(1)\">x</a>",
+	}
+	for _, content := range tests {
+		rendered, err := renderContent(FORMAT_MARKDOWN, content)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, forbidden := range []string{"<script", "javascript:", "onerror", "onclick"} {
+			if strings.Contains(rendered, forbidden) {
+				t.Errorf("expected %q to be sanitised, got %q", content, rendered)
+			}
+		}
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	rendered, _ := renderContent(FORMAT_PLAIN, "<b>not bold</b>")
+	if rendered != "<pre>&lt;b&gt;not bold&lt;/b&gt;</pre>" {
+		t.Errorf("expected escaped content, got %q", rendered)
+	}
+}
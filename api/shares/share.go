@@ -3,32 +3,59 @@ package shares
 import (
 	"context"
 	"fmt"
+	"qr-pastebin-api/blob"
 	"qr-pastebin-api/common"
-	"strconv"
+	"qr-pastebin-api/envelope"
+	"qr-pastebin-api/i18n"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
+// ShareRequest is validated by its binding tags when bound by gin, rules depending on
+// configuration are checked by validateShareRequest
 type ShareRequest struct {
-	Title       string `json:"title"`
-	Content     string `json:"content"`
+	Title       string `json:"title" binding:"required,max=200"`
+	Content     string `json:"content" binding:"required"`
 	SetPassword bool   `json:"setPassword"`
-	Password    string `json:"password"`
-	ExpireIn    string `json:"expireIn"`
+	Password    string `json:"password" binding:"max=72"`
+	ExpireIn    string `json:"expireIn" binding:"required_without=ExpiresAt"`
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+	PublishAt   string `json:"publishAt,omitempty"`
 	HideAuthor  bool   `json:"hideAuthor"`
-	AuthorId    int    `json:"authorId"`
+	AuthorId    int    `json:"authorId" binding:"min=-1"`
+	Visibility  string `json:"visibility" binding:"omitempty,oneof=public private"`
+	TeamId      *int   `json:"teamId,omitempty" binding:"omitempty,min=-1"`
+	Encryption  string `json:"encryption,omitempty"`
+	MimeType    string `json:"mimeType,omitempty" binding:"max=255"`
+	Language    string `json:"language,omitempty" binding:"max=64"`
+	Format      string `json:"format,omitempty" binding:"omitempty,oneof=plain markdown"`
 }
 
 type ShareResponse struct {
-	Id                  string `json:"id"`
-	Title               string `json:"title"`
-	Content             string `json:"content"`
-	IsPasswordProtected bool   `json:"isPasswordProtected"`
-	ExpiresIn           string `json:"expiresIn"`
-	AuthorName          string `json:"authorName"`
-	HideAuthor          bool   `json:"hideAuthor"`
+	Id                  string               `json:"id"`
+	Title               string               `json:"title"`
+	Content             string               `json:"content"`
+	IsPasswordProtected bool                 `json:"isPasswordProtected"`
+	ExpiresIn           string               `json:"expiresIn"`
+	ExpiresAt           *time.Time           `json:"expiresAt"`
+	PublishAt           *time.Time           `json:"publishAt"`
+	AuthorName          string               `json:"authorName"`
+	HideAuthor          bool                 `json:"hideAuthor"`
+	Visibility          string               `json:"visibility"`
+	Access              string               `json:"access,omitempty"`
+	TeamId              int                  `json:"teamId"`
+	TeamName            string               `json:"teamName,omitempty"`
+	IsEncrypted         bool                 `json:"isEncrypted"`
+	Encryption          string               `json:"encryption,omitempty"`
+	MimeType            string               `json:"mimeType"`
+	Attachments         []AttachmentResponse `json:"attachments"`
+	Language            string               `json:"language"`
+	Format              string               `json:"format"`
+	// Privileged is set when the viewer is the author, in the owning team, has a grant or may view
+	// any share, their reads are not counted as views
+	Privileged bool `json:"-"`
 }
 
 type Share struct {
@@ -37,8 +64,19 @@ type Share struct {
 	Content      string
 	PasswordHash string
 	ExpireAt     time.Time
+	PublishAt    time.Time
 	AuthorId     int
 	HideAuthor   bool
+	Visibility   string
+	TeamId       int
+	LinkVersion  int
+	Encryption   string
+	DataKey      string
+	KeyId        string
+	MimeType     string
+	Language     string
+	Format       string
+	ContentSize  int64
 }
 
 type IsPasswordProtectedResponse struct {
@@ -53,15 +91,44 @@ type GetProtectedShareRequest struct {
 	Password string `json:"password"`
 }
 
+type ReviewRequest struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+const (
+	defaultReviewPageSize = 50
+	maxReviewPageSize     = 200
+)
+
 type ShareDBHandler struct {
-	DB *pgx.Conn
+	DB     *pgx.Conn
+	Links  *LinkSigner
+	Keys   *envelope.Keyring
+	Blobs  blob.Storage
+	Limits Limits
 }
 
-func NewShareHandler(db *pgx.Conn) *ShareDBHandler {
-	return &ShareDBHandler{DB: db}
+func NewShareHandler(db *pgx.Conn, links *LinkSigner, keys *envelope.Keyring, blobs blob.Storage, limits Limits) *ShareDBHandler {
+	return &ShareDBHandler{DB: db, Links: links, Keys: keys, Blobs: blobs, Limits: limits}
 }
 
-func (handler *ShareDBHandler) CreateShare(shareBody ShareRequest) (*CreateShareResponse, error) {
+// CreateShare stores a share authored by the user, userId is -1 for anonymous shares. The author
+// always comes from the session, an AuthorId in the request may only confirm it.
+func (handler *ShareDBHandler) CreateShare(userId int, role common.Role, shareBody ShareRequest) (*CreateShareResponse, error) {
+	if shareBody.AuthorId != 0 && shareBody.AuthorId != userId {
+		return nil, &AuthorMismatchError{}
+	}
+	shareBody.AuthorId = userId
+
+	if err := handler.validateShareRequest(shareBody, true); err != nil {
+		return nil, err
+	}
+	size := contentSize(shareBody.Title, shareBody.Content)
+	if err := handler.ensureStorageQuota(shareBody.AuthorId, size); err != nil {
+		return nil, err
+	}
+
 	colNames := []string{}
 	args := []any{}
 	values := []string{}
@@ -73,14 +140,74 @@ func (handler *ShareDBHandler) CreateShare(shareBody ShareRequest) (*CreateShare
 	args = append(args, shareId)
 	argPos++
 
+	if err := validateEncryptedContent(shareBody.Encryption, shareBody.Content); err != nil {
+		return nil, err
+	}
+	sealed, err := handler.sealShare(shareId, shareBody.Title, shareBody.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	language, err := resolveLanguage(shareBody.Language, shareBody.Content, shareBody.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	colNames = append(colNames, "language")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, language)
+	argPos++
+
+	colNames = append(colNames, "content_size")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, size)
+	argPos++
+
+	format := shareBody.Format
+	if format == "" {
+		format = FORMAT_PLAIN
+	}
+	if err := validateFormat(format); err != nil {
+		return nil, err
+	}
+	colNames = append(colNames, "format")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, format)
+	argPos++
+
 	colNames = append(colNames, "title")
 	values = append(values, fmt.Sprintf("$%d", argPos))
-	args = append(args, shareBody.Title)
+	args = append(args, sealed.Title)
 	argPos++
 
 	colNames = append(colNames, "content")
 	values = append(values, fmt.Sprintf("$%d", argPos))
-	args = append(args, shareBody.Content)
+	args = append(args, sealed.Content)
+	argPos++
+
+	colNames = append(colNames, "data_key")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, sealed.DataKey)
+	argPos++
+
+	colNames = append(colNames, "key_id")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, sealed.KeyId)
+	argPos++
+	colNames = append(colNames, "encryption")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, shareBody.Encryption)
+	argPos++
+
+	mimeType := shareBody.MimeType
+	if mimeType == "" {
+		mimeType = DEFAULT_MIME_TYPE
+	}
+	if err := validateMimeType(mimeType); err != nil {
+		return nil, err
+	}
+	colNames = append(colNames, "mime_type")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, mimeType)
 	argPos++
 
 	colNames = append(colNames, "passwordHash")
@@ -96,13 +223,29 @@ func (handler *ShareDBHandler) CreateShare(shareBody ShareRequest) (*CreateShare
 	}
 	argPos++
 
-	expirationDate, err := createExpirationDate(shareBody.ExpireIn)
+	now := time.Now()
+	expireAt, err := expirationDate(shareBody.ExpiresAt, shareBody.ExpireIn, now)
 	if err != nil {
 		return nil, err
 	}
+	if err := handler.Limits.checkExpiration(userId, role, expiryField(shareBody), expireAt, now); err != nil {
+		return nil, err
+	}
 	colNames = append(colNames, "expire_at")
 	values = append(values, fmt.Sprintf("$%d", argPos))
-	args = append(args, expirationDate)
+	args = append(args, expireAt)
+	argPos++
+
+	publishAt, err := parsePublishAt(shareBody.PublishAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPublishAt(publishAt, expireAt); err != nil {
+		return nil, err
+	}
+	colNames = append(colNames, "publish_at")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, publishAt)
 	argPos++
 
 	colNames = append(colNames, "hide_author")
@@ -115,6 +258,30 @@ func (handler *ShareDBHandler) CreateShare(shareBody ShareRequest) (*CreateShare
 	args = append(args, shareBody.AuthorId)
 	argPos++
 
+	visibility := shareBody.Visibility
+	if visibility == "" {
+		visibility = VISIBILITY_PUBLIC
+	}
+	if err := validateVisibility(visibility); err != nil {
+		return nil, err
+	}
+	colNames = append(colNames, "visibility")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, visibility)
+	argPos++
+
+	teamId := -1
+	if shareBody.TeamId != nil && *shareBody.TeamId != -1 {
+		teamId = *shareBody.TeamId
+		if err := handler.ensureTeamMember(teamId, shareBody.AuthorId); err != nil {
+			return nil, err
+		}
+	}
+	colNames = append(colNames, "team_id")
+	values = append(values, fmt.Sprintf("$%d", argPos))
+	args = append(args, teamId)
+	argPos++
+
 	query := fmt.Sprintf("INSERT INTO shares (%s) VALUES (%s);", strings.Join(colNames, ", "), strings.Join(values, ", "))
 
 	_, err = handler.DB.Exec(context.Background(), query, args...)
@@ -124,19 +291,107 @@ func (handler *ShareDBHandler) CreateShare(shareBody ShareRequest) (*CreateShare
 	return &CreateShareResponse{ShareId: shareId}, nil
 }
 
-func (handler *ShareDBHandler) UpdateShare(shareId string, userId int, shareBody ShareRequest) error {
+func (handler *ShareDBHandler) UpdateShare(shareId string, userId int, role common.Role, shareBody ShareRequest) error {
+	permit, err := handler.CanEditShare(userId, shareId, role)
+	if err != nil {
+		return err
+	}
+	if !permit {
+		return &common.NotFoundError{}
+	}
+	if err := handler.validateShareRequest(shareBody, false); err != nil {
+		return err
+	}
+
+	// Encryption is kept as it is unless the request changes it, the content has to match either way
+	share, err := handler.readShare(shareId)
+	if err != nil {
+		return err
+	}
+	// Edit grants cover title and content, who may read the share stays with its owners
+	if field := accessChange(share, shareBody); field != "" {
+		permit, err := handler.HasAccessToShare(userId, shareId, role, common.SHARE_EDIT_ANY)
+		if err != nil {
+			return err
+		}
+		if !permit {
+			return &OwnerRequiredError{Field: field}
+		}
+	}
+	size := contentSize(shareBody.Title, shareBody.Content)
+	if err := handler.ensureStorageQuota(share.AuthorId, size-share.ContentSize); err != nil {
+		return err
+	}
+	encryption := share.Encryption
+	if shareBody.Encryption == ENCRYPTION_NONE {
+		encryption = ""
+	} else if shareBody.Encryption != "" {
+		encryption = shareBody.Encryption
+	}
+	if err := validateEncryptedContent(encryption, shareBody.Content); err != nil {
+		return err
+	}
+	// Every update encrypts with a fresh data key, which also moves the share to the active master key
+	sealed, err := handler.sealShare(shareId, shareBody.Title, shareBody.Content)
+	if err != nil {
+		return err
+	}
+	// Like on creation, an omitted language is detected again from the new content
+	language, err := resolveLanguage(shareBody.Language, shareBody.Content, encryption)
+	if err != nil {
+		return err
+	}
+
 	setParts := []string{}
 	args := []any{}
 	argCount := 1
 
+	setParts = append(setParts, fmt.Sprintf("%s = $%d", "encryption", argCount))
+	args = append(args, encryption)
+	argCount++
+
 	setParts = append(setParts, fmt.Sprintf("%s = $%d", "title", argCount))
-	args = append(args, shareBody.Title)
+	args = append(args, sealed.Title)
 	argCount++
 
 	setParts = append(setParts, fmt.Sprintf("%s = $%d", "content", argCount))
-	args = append(args, shareBody.Content)
+	args = append(args, sealed.Content)
+	argCount++
+
+	setParts = append(setParts, fmt.Sprintf("%s = $%d", "data_key", argCount))
+	args = append(args, sealed.DataKey)
+	argCount++
+
+	setParts = append(setParts, fmt.Sprintf("%s = $%d", "key_id", argCount))
+	args = append(args, sealed.KeyId)
+	argCount++
+
+	setParts = append(setParts, fmt.Sprintf("%s = $%d", "language", argCount))
+	args = append(args, language)
 	argCount++
 
+	setParts = append(setParts, fmt.Sprintf("%s = $%d", "content_size", argCount))
+	args = append(args, size)
+	argCount++
+
+	if shareBody.Format != "" {
+		if err := validateFormat(shareBody.Format); err != nil {
+			return err
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", "format", argCount))
+		args = append(args, shareBody.Format)
+		argCount++
+	}
+
+	if shareBody.MimeType != "" {
+		if err := validateMimeType(shareBody.MimeType); err != nil {
+			return err
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", "mime_type", argCount))
+		args = append(args, shareBody.MimeType)
+		argCount++
+	}
+
 	if shareBody.SetPassword {
 		if shareBody.Password == "" {
 			setParts = append(setParts, fmt.Sprintf("%s = $%d", "passwordHash", argCount))
@@ -153,48 +408,91 @@ func (handler *ShareDBHandler) UpdateShare(shareId string, userId int, shareBody
 		}
 	}
 
-	if shareBody.ExpireIn != "no-change" {
-		expirationDate, err := createExpirationDate(shareBody.ExpireIn)
+	expireAt := share.ExpireAt
+	if shareBody.ExpiresAt != "" || shareBody.ExpireIn != EXPIRE_NO_CHANGE {
+		now := time.Now()
+		expireAt, err = expirationDate(shareBody.ExpiresAt, shareBody.ExpireIn, now)
 		if err != nil {
 			return err
 		}
+		if err := handler.Limits.checkExpiration(userId, role, expiryField(shareBody), expireAt, now); err != nil {
+			return err
+		}
 		setParts = append(setParts, fmt.Sprintf("%s = $%d", "expire_at", argCount))
-		args = append(args, expirationDate)
+		args = append(args, expireAt)
+		argCount++
+	}
+
+	// An omitted publishAt keeps the schedule, a time in the past publishes the share right away
+	publishAt := share.PublishAt
+	if shareBody.PublishAt != "" {
+		publishAt, err = parsePublishAt(shareBody.PublishAt)
+		if err != nil {
+			return err
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", "publish_at", argCount))
+		args = append(args, publishAt)
 		argCount++
 	}
+	if err := checkPublishAt(publishAt, expireAt); err != nil {
+		return err
+	}
 	setParts = append(setParts, fmt.Sprintf("%s = $%d", "hide_author", argCount))
 	args = append(args, shareBody.HideAuthor)
 	argCount++
 
+	if shareBody.Visibility != "" {
+		if err := validateVisibility(shareBody.Visibility); err != nil {
+			return err
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", "visibility", argCount))
+		args = append(args, shareBody.Visibility)
+		argCount++
+	}
+
+	// Moving a share gives another team edit access to it, so it isn't open to every editor
+	if shareBody.TeamId != nil && *shareBody.TeamId != share.TeamId {
+		if err := handler.ensureCanMoveToTeam(share, userId, *shareBody.TeamId); err != nil {
+			return err
+		}
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", "team_id", argCount))
+		args = append(args, *shareBody.TeamId)
+		argCount++
+	}
+
 	setQueryPart := strings.Join(setParts, ", ")
 
 	shareIdIndex := fmt.Sprintf("$%d", argCount)
 	args = append(args, shareId)
 	argCount++
 
-	authorIdIndex := fmt.Sprintf("$%d", argCount)
-	args = append(args, userId)
-	argCount++
-
-	query := fmt.Sprintf("UPDATE shares SET %s WHERE id = %s AND author_id = %s;", setQueryPart, shareIdIndex, authorIdIndex)
-	_, err := handler.DB.Exec(context.Background(), query, args...)
+	query := fmt.Sprintf("UPDATE shares SET %s WHERE id = %s;", setQueryPart, shareIdIndex)
+	_, err = handler.DB.Exec(context.Background(), query, args...)
 	return err
 }
 
-func (handler *ShareDBHandler) GetShareForPublic(id string) (*ShareResponse, error) {
+// GetShareForPublic returns the share to any viewer, userId is -1 for anonymous viewers.
+// Private shares are reported as not found unless the viewer may read them. Password protected
+// shares need a valid signed link, otherwise only privileged viewers can skip the password.
+func (handler *ShareDBHandler) GetShareForPublic(id string, userId int, role common.Role, link SignedLink) (*ShareResponse, error) {
 	share, err := handler.readShare(id)
 	if err != nil {
 		return nil, err
 	}
 
-	if !share.ExpireAt.IsZero() && time.Now().After(share.ExpireAt) {
-		return nil, &ExpiredShareError{}
+	// Without a password protected shares are refused with PasswordRequiredError
+	if _, err := handler.authorizeRead(share, userId, role, link, ""); err != nil {
+		return nil, err
 	}
 
 	shareResponse, err := handler.transformToShareResponse(share)
 	if err != nil {
 		return nil, err
 	}
+	shareResponse.Privileged, err = handler.hasPrivilegedAccess(share, userId, role)
+	if err != nil {
+		return nil, err
+	}
 
 	if shareResponse.HideAuthor {
 		shareResponse.AuthorName = ""
@@ -203,8 +501,8 @@ func (handler *ShareDBHandler) GetShareForPublic(id string) (*ShareResponse, err
 	return shareResponse, nil
 }
 
-func (handler *ShareDBHandler) GetShareForOwner(shareId string, userId int) (*ShareResponse, error) {
-	permit, err := handler.HasAccessToShare(userId, shareId, 0)
+func (handler *ShareDBHandler) GetShareForOwner(shareId string, userId int, role common.Role) (*ShareResponse, error) {
+	permit, err := handler.CanEditShare(userId, shareId, role)
 	if err != nil {
 		return nil, err
 	}
@@ -225,6 +523,7 @@ func (handler *ShareDBHandler) GetShareForOwner(shareId string, userId int) (*Sh
 	return shareResponse, nil
 }
 
+// GetShares lists shares authored by the user followed by shares other users granted them access to
 func (handler *ShareDBHandler) GetShares(userId int) ([]ShareResponse, error) {
 	shares, err := handler.readShares(userId)
 	if err != nil {
@@ -237,18 +536,69 @@ func (handler *ShareDBHandler) GetShares(userId int) ([]ShareResponse, error) {
 		if err != nil {
 			return nil, err
 		}
+		newShareResponse.Access = ACCESS_OWNER
+		shareResponses = append(shareResponses, *newShareResponse)
+	}
+
+	sharedShares, accesses, err := handler.readSharedWithUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	for i, share := range sharedShares {
+		newShareResponse, err := handler.transformToShareResponse(&share)
+		if err != nil {
+			return nil, err
+		}
+		newShareResponse.Access = accesses[i]
 		shareResponses = append(shareResponses, *newShareResponse)
 	}
 
 	return shareResponses, nil
 }
 
-func (handler *ShareDBHandler) GetProtectedShare(id string, password string) (*ShareResponse, error) {
+// GetSharesForReview lists shares of all users, newest first, so moderators can find abusive content
+func (handler *ShareDBHandler) GetSharesForReview(request ReviewRequest) ([]ShareResponse, error) {
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultReviewPageSize
+	}
+	limit = min(limit, maxReviewPageSize)
+	offset := max(request.Offset, 0)
+
+	query := fmt.Sprintf("SELECT %s FROM shares AS s ORDER BY s.created_at DESC LIMIT $1 OFFSET $2;", shareColumns)
+	shares, err := handler.queryShares(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	shareResponses := make([]ShareResponse, 0)
+	for _, share := range shares {
+		newShareResponse, err := handler.transformToShareResponse(&share)
+		if err != nil {
+			return nil, err
+		}
+		shareResponses = append(shareResponses, *newShareResponse)
+	}
+	return shareResponses, nil
+}
+
+func (handler *ShareDBHandler) GetProtectedShare(id string, password string, userId int, role common.Role) (*ShareResponse, error) {
 	share, err := handler.readShare(id)
 	if err != nil {
 		return nil, err
 	}
 
+	permit, err := handler.canViewShare(share, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if !permit {
+		return nil, &common.NotFoundError{}
+	}
+	if err := handler.ensurePublished(share, userId, role, time.Now()); err != nil {
+		return nil, err
+	}
+
 	if !share.ExpireAt.IsZero() && time.Now().After(share.ExpireAt) {
 		return nil, &ExpiredShareError{}
 	}
@@ -262,6 +612,10 @@ func (handler *ShareDBHandler) GetProtectedShare(id string, password string) (*S
 	if err != nil {
 		return nil, err
 	}
+	shareResponse.Privileged, err = handler.hasPrivilegedAccess(share, userId, role)
+	if err != nil {
+		return nil, err
+	}
 
 	if shareResponse.HideAuthor {
 		shareResponse.AuthorName = ""
@@ -270,21 +624,38 @@ func (handler *ShareDBHandler) GetProtectedShare(id string, password string) (*S
 }
 
 func (handler *ShareDBHandler) DeleteShare(shareId string) error {
+	if err := handler.deleteAttachments(shareId); err != nil {
+		return err
+	}
 	query := "DELETE FROM shares WHERE id = $1;"
 	_, err := handler.DB.Exec(context.Background(), query, shareId)
 	if err != nil {
 		return err
 	}
+	_, err = handler.DB.Exec(context.Background(), "DELETE FROM share_grants WHERE share_id = $1;", shareId)
+	if err != nil {
+		return err
+	}
+	_, err = handler.DB.Exec(context.Background(), "DELETE FROM share_views WHERE share_id = $1;", shareId)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
-func (handler *ShareDBHandler) HasAccessToShare(userId int, shareId string, role common.Role) (bool, error) {
-	if role.String() == "admin" {
+// HasAccessToShare permits the author of the share, or anyone whose role grants the given permission over all shares
+func (handler *ShareDBHandler) HasAccessToShare(userId int, shareId string, role common.Role, permission common.Permission) (bool, error) {
+	if role.HasPermission(permission) {
 		return true, nil
 	}
+	// Anonymous shares are stored with author -1, which must not match anonymous users
+	if userId == -1 {
+		return false, nil
+	}
 
+	// Owners and admins of the team that owns the share have the same rights as the author
 	var count int
-	query := "SELECT COUNT(*) FROM shares WHERE author_id = $1 AND id = $2;"
+	query := "SELECT COUNT(*) FROM shares WHERE id = $2 AND (author_id = $1 OR team_id IN (SELECT team_id FROM team_members WHERE user_id = $1 AND role IN ('owner', 'admin')));"
 	err := handler.DB.QueryRow(context.Background(), query, userId, shareId).Scan(&count)
 	if err != nil {
 		return false, err
@@ -297,11 +668,22 @@ func (handler *ShareDBHandler) HasAccessToShare(userId int, shareId string, role
 	}
 }
 
-func (handler *ShareDBHandler) IsPasswordProtected(id string) (*IsPasswordProtectedResponse, error) {
+func (handler *ShareDBHandler) IsPasswordProtected(id string, userId int, role common.Role) (*IsPasswordProtectedResponse, error) {
 	share, err := handler.readShare(id)
 	if err != nil {
 		return nil, err
 	}
+
+	permit, err := handler.canViewShare(share, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if !permit {
+		return nil, &common.NotFoundError{}
+	}
+	if err := handler.ensurePublished(share, userId, role, time.Now()); err != nil {
+		return nil, err
+	}
 	if share.PasswordHash == "" {
 		return &IsPasswordProtectedResponse{IsPasswordProtected: false}, nil
 	} else {
@@ -309,17 +691,43 @@ func (handler *ShareDBHandler) IsPasswordProtected(id string) (*IsPasswordProtec
 	}
 }
 
-func (handler *ShareDBHandler) readShare(shareId string) (*Share, error) {
+// Columns of the shares table (aliased as "s") in the order scanShare expects them
+const shareColumns = "s.id, s.title, s.content, s.expire_at, s.passwordHash, s.author_id, s.hide_author, s.visibility, s.team_id, s.link_version, s.encryption, s.data_key, s.key_id, s.mime_type, s.language, s.format, s.content_size, s.publish_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanShare reads the share columns, extra destinations receive columns selected after them
+func scanShare(row rowScanner, extra ...any) (*Share, error) {
 	var share Share
-	err := handler.DB.QueryRow(context.Background(), "SELECT id, title, content, expire_at, passwordHash, author_id, hide_author FROM shares WHERE id = $1;", shareId).Scan(&share.Id, &share.Title, &share.Content, &share.ExpireAt, &share.PasswordHash, &share.AuthorId, &share.HideAuthor)
+	dest := []any{&share.Id, &share.Title, &share.Content, &share.ExpireAt, &share.PasswordHash, &share.AuthorId, &share.HideAuthor, &share.Visibility, &share.TeamId, &share.LinkVersion, &share.Encryption, &share.DataKey, &share.KeyId, &share.MimeType, &share.Language, &share.Format, &share.ContentSize, &share.PublishAt}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		return nil, err
 	}
 	return &share, nil
 }
 
+func (handler *ShareDBHandler) readShare(shareId string) (*Share, error) {
+	query := fmt.Sprintf("SELECT %s FROM shares AS s WHERE s.id = $1;", shareColumns)
+	share, err := scanShare(handler.DB.QueryRow(context.Background(), query, shareId))
+	if err != nil {
+		return nil, err
+	}
+	if err := handler.openShare(share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
 func (handler *ShareDBHandler) readShares(userId int) ([]Share, error) {
-	rows, err := handler.DB.Query(context.Background(), "SELECT s.id, s.title, s.content, s.expire_at, s.passwordHash, s.author_id, s.hide_author FROM users AS u RIGHT JOIN shares AS s ON u.id = s.author_id WHERE u.id = $1;", userId)
+	query := fmt.Sprintf("SELECT %s FROM users AS u RIGHT JOIN shares AS s ON u.id = s.author_id WHERE u.id = $1;", shareColumns)
+	return handler.queryShares(query, userId)
+}
+
+func (handler *ShareDBHandler) queryShares(query string, args ...any) ([]Share, error) {
+	rows, err := handler.DB.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying shares: %w", err)
 	}
@@ -327,12 +735,14 @@ func (handler *ShareDBHandler) readShares(userId int) ([]Share, error) {
 
 	shares := make([]Share, 0)
 	for rows.Next() {
-		var share Share
-		err := rows.Scan(&share.Id, &share.Title, &share.Content, &share.ExpireAt, &share.PasswordHash, &share.AuthorId, &share.HideAuthor)
+		share, err := scanShare(rows)
 		if err != nil {
 			return nil, err
 		}
-		shares = append(shares, share)
+		if err := handler.openShare(share); err != nil {
+			return nil, err
+		}
+		shares = append(shares, *share)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("row iteration error: %w", err)
@@ -341,54 +751,30 @@ func (handler *ShareDBHandler) readShares(userId int) ([]Share, error) {
 	return shares, nil
 }
 
-func createExpirationDate(expireIn string) (time.Time, error) {
-	if expireIn == "never" {
-		return time.Time{}, nil
-	}
-
-	parts := strings.Split(expireIn, "_")
-	if len(parts) != 2 {
-		return time.Now(), fmt.Errorf("expiration period is not of correct format '%s', make sure it is formatted as '5_days'", expireIn)
-	}
-
-	durationCount, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return time.Now(), fmt.Errorf("could not parse duration '%s' to int: %w", parts[0], err)
-	}
-
-	var duration time.Duration
-	switch parts[1] {
-	case "minutes":
-		duration = time.Minute * time.Duration(durationCount)
-	case "hours":
-		duration = time.Hour * time.Duration(durationCount)
-	case "days":
-		return time.Now().AddDate(0, 0, durationCount), nil
-	case "weeks":
-		return time.Now().AddDate(0, 0, durationCount*7), nil
-	case "months":
-		return time.Now().AddDate(0, durationCount, 0), nil
-	case "years":
-		return time.Now().AddDate(durationCount, 0, 0), nil
-	default:
-		return time.Now(), fmt.Errorf("unknown duration type '%s'", parts[1])
-	}
-
-	return time.Now().Add(duration), nil
-}
-
 func (handler *ShareDBHandler) transformToShareResponse(share *Share) (*ShareResponse, error) {
 	var shareResp ShareResponse
 	shareResp.Id = share.Id
 	shareResp.Content = share.Content
 	shareResp.HideAuthor = share.HideAuthor
 	shareResp.Title = share.Title
+	shareResp.Visibility = share.Visibility
+	shareResp.IsEncrypted = share.IsEncrypted()
+	shareResp.Encryption = share.Encryption
+	shareResp.MimeType = share.MimeType
+	shareResp.Language = share.Language
+	shareResp.Format = share.Format
 
 	if share.PasswordHash != "" {
 		shareResp.IsPasswordProtected = true
 	}
 	if !share.ExpireAt.IsZero() {
-		shareResp.ExpiresIn = createExpireInTextFromDate(share.ExpireAt)
+		shareResp.ExpiresIn = createExpireInTextFromDate(share.ExpireAt, time.Now(), i18n.DEFAULT_LOCALE)
+		expiresAt := share.ExpireAt.UTC()
+		shareResp.ExpiresAt = &expiresAt
+	}
+	if !share.PublishAt.IsZero() {
+		publishAt := share.PublishAt.UTC()
+		shareResp.PublishAt = &publishAt
 	}
 	if share.AuthorId != -1 {
 		author, err := common.GetUserById(handler.DB, share.AuthorId)
@@ -397,22 +783,66 @@ func (handler *ShareDBHandler) transformToShareResponse(share *Share) (*ShareRes
 		}
 		shareResp.AuthorName = author.Name
 	}
+	shareResp.TeamId = share.TeamId
+	if share.TeamId != -1 {
+		err := handler.DB.QueryRow(context.Background(), "SELECT name FROM teams WHERE id = $1;", share.TeamId).Scan(&shareResp.TeamName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attachments, err := handler.readAttachments(share.Id)
+	if err != nil {
+		return nil, err
+	}
+	shareResp.Attachments = make([]AttachmentResponse, 0)
+	for _, attachment := range attachments {
+		shareResp.Attachments = append(shareResp.Attachments, *attachment.toResponse())
+	}
 	return &shareResp, nil
 }
 
-func createExpireInTextFromDate(expireAt time.Time) string {
-	now := time.Now()
-	if now.After(expireAt) {
-		return "Already expired"
+// Localize words ExpiresIn for the locale of the client, responses are built in the default locale
+func (response *ShareResponse) Localize(locale i18n.Locale) {
+	if response.ExpiresAt != nil {
+		response.ExpiresIn = createExpireInTextFromDate(*response.ExpiresAt, time.Now(), locale)
+	}
+}
+
+func LocalizeShares(responses []ShareResponse, locale i18n.Locale) {
+	for i := range responses {
+		responses[i].Localize(locale)
+	}
+}
+
+// createExpireInTextFromDate counts calendar years, months and days until expiry, shares expiring
+// within a day are counted in hours and minutes instead
+func createExpireInTextFromDate(expireAt time.Time, now time.Time, locale i18n.Locale) string {
+	translation := locale.Translation()
+	if !now.Before(expireAt) {
+		return translation.Expired
+	}
+
+	dateComponents := make([]string, 0)
+	if remaining := expireAt.Sub(now); remaining < 24*time.Hour {
+		hours := int(remaining / time.Hour)
+		minutes := int(remaining % time.Hour / time.Minute)
+		if hours == 0 && minutes == 0 {
+			return translation.ExpiresInMinute
+		}
+		if hours > 0 {
+			dateComponents = append(dateComponents, locale.Count(i18n.HOUR, hours))
+		}
+		if minutes > 0 {
+			dateComponents = append(dateComponents, locale.Count(i18n.MINUTE, minutes))
+		}
+		return fmt.Sprintf(translation.ExpiresIn, locale.List(dateComponents))
 	}
 
+	expireAt = expireAt.In(now.Location())
 	years := expireAt.Year() - now.Year()
 	months := expireAt.Month() - now.Month()
 	days := expireAt.Day() - now.Day()
-
-	if years == 0 && months == 0 && days == 0 {
-		return "Expires today"
-	}
 	if days < 0 {
 		prevMonthDays := time.Date(expireAt.Year(), expireAt.Month(), 0, 0, 0, 0, 0, expireAt.Location())
 		days += prevMonthDays.Day()
@@ -423,28 +853,18 @@ func createExpireInTextFromDate(expireAt time.Time) string {
 		years--
 	}
 
-	dateComponents := make([]string, 0)
-	dateComponents = append(dateComponents, "Expires in")
-	if years == 1 {
-		dateComponents = append(dateComponents, fmt.Sprintf("%d year", years))
-	} else if years > 1 {
-		dateComponents = append(dateComponents, fmt.Sprintf("%d years", years))
+	if years > 0 {
+		dateComponents = append(dateComponents, locale.Count(i18n.YEAR, years))
 	}
-	if months == 1 {
-		dateComponents = append(dateComponents, fmt.Sprintf("%d month", months))
-	} else if months > 1 {
-		dateComponents = append(dateComponents, fmt.Sprintf("%d months", months))
+	if months > 0 {
+		dateComponents = append(dateComponents, locale.Count(i18n.MONTH, int(months)))
 	}
-	if days == 1 {
-		dateComponents = append(dateComponents, fmt.Sprintf("%d day", days))
-	} else if days > 1 {
-		dateComponents = append(dateComponents, fmt.Sprintf("%d days", days))
+	if days > 0 {
+		dateComponents = append(dateComponents, locale.Count(i18n.DAY, days))
 	}
-
-	if len(dateComponents) <= 2 {
-		return strings.Join(dateComponents, " ")
+	if len(dateComponents) == 0 {
+		// A day with a daylight saving change can be 25 hours long
+		dateComponents = append(dateComponents, locale.Count(i18n.DAY, 1))
 	}
-
-	allPartsExceptLast := strings.Join(dateComponents[0:len(dateComponents)-1], " ")
-	return fmt.Sprintf("%s and %s", allPartsExceptLast, dateComponents[len(dateComponents)-1])
+	return fmt.Sprintf(translation.ExpiresIn, locale.List(dateComponents))
 }
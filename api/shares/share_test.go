@@ -1,105 +1,91 @@
 package shares
 
 import (
+	"qr-pastebin-api/common"
+	"qr-pastebin-api/i18n"
 	"testing"
 	"time"
 )
 
-func TestExpireInCreation1(t *testing.T) {
-	expireAt := time.Now().AddDate(1, 2, 3)
-	want := "Expires in 1 year 2 months and 3 days"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
-	}
-}
+func TestExpireInCreation(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		locale   i18n.Locale
+		expireAt time.Time
+		want     string
+	}{
+		{i18n.ENGLISH, now.AddDate(1, 2, 3), "Expires in 1 year 2 months and 3 days"},
+		{i18n.ENGLISH, now.AddDate(2, 1, 3), "Expires in 2 years 1 month and 3 days"},
+		{i18n.ENGLISH, now.AddDate(3, 2, 1), "Expires in 3 years 2 months and 1 day"},
+		{i18n.ENGLISH, now.AddDate(0, 1, 2), "Expires in 1 month and 2 days"},
+		{i18n.ENGLISH, now.AddDate(1, 0, 2), "Expires in 1 year and 2 days"},
+		{i18n.ENGLISH, now.AddDate(1, 2, 0), "Expires in 1 year and 2 months"},
+		{i18n.ENGLISH, now.AddDate(1, 0, 0), "Expires in 1 year"},
+		{i18n.ENGLISH, now.AddDate(0, 1, 0), "Expires in 1 month"},
+		{i18n.ENGLISH, now.AddDate(0, 0, 1), "Expires in 1 day"},
+		{i18n.ENGLISH, now.Add(5*time.Hour + 12*time.Minute), "Expires in 5 hours and 12 minutes"},
+		{i18n.ENGLISH, now.Add(50 * time.Minute), "Expires in 50 minutes"},
+		{i18n.ENGLISH, now.Add(time.Hour + time.Minute), "Expires in 1 hour and 1 minute"},
+		{i18n.ENGLISH, now.Add(2 * time.Hour), "Expires in 2 hours"},
+		{i18n.ENGLISH, now.Add(time.Second), "Expires in less than a minute"},
+		{i18n.ENGLISH, now.Add(-time.Second), "Already expired"},
+		{i18n.ENGLISH, now, "Already expired"},
 
-func TestExpireInCreation2(t *testing.T) {
-	expireAt := time.Now().AddDate(2, 1, 3)
-	want := "Expires in 2 years 1 month and 3 days"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
-	}
-}
+		{i18n.GERMAN, now.AddDate(1, 2, 3), "Läuft in 1 Jahr, 2 Monaten und 3 Tagen ab"},
+		{i18n.GERMAN, now.AddDate(2, 1, 1), "Läuft in 2 Jahren, 1 Monat und 1 Tag ab"},
+		{i18n.GERMAN, now.AddDate(0, 0, 14), "Läuft in 14 Tagen ab"},
+		{i18n.GERMAN, now.Add(50 * time.Minute), "Läuft in 50 Minuten ab"},
+		{i18n.GERMAN, now.Add(time.Hour + 30*time.Minute), "Läuft in 1 Stunde und 30 Minuten ab"},
+		{i18n.GERMAN, now.Add(time.Second), "Läuft in weniger als einer Minute ab"},
+		{i18n.GERMAN, now.Add(-time.Second), "Bereits abgelaufen"},
 
-func TestExpireInCreation3(t *testing.T) {
-	expireAt := time.Now().AddDate(3, 2, 1)
-	want := "Expires in 3 years 2 months and 1 day"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
-	}
-}
+		{i18n.LITHUANIAN, now.AddDate(1, 2, 3), "Nustos galioti po 1 metų, 2 mėnesių ir 3 dienų"},
+		{i18n.LITHUANIAN, now.AddDate(0, 1, 21), "Nustos galioti po 1 mėnesio ir 21 dienos"},
+		{i18n.LITHUANIAN, now.AddDate(0, 0, 11), "Nustos galioti po 11 dienų"},
+		{i18n.LITHUANIAN, now.Add(21*time.Hour + time.Minute), "Nustos galioti po 21 valandos ir 1 minutės"},
+		{i18n.LITHUANIAN, now.Add(50 * time.Minute), "Nustos galioti po 50 minučių"},
+		{i18n.LITHUANIAN, now.Add(time.Second), "Nustos galioti greičiau nei po minutės"},
+		{i18n.LITHUANIAN, now.Add(-time.Second), "Jau nebegalioja"},
 
-func TestExpireInCreation4(t *testing.T) {
-	expireAt := time.Now().AddDate(0, 1, 2)
-	want := "Expires in 1 month and 2 days"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
+		{i18n.Locale("fr"), now.AddDate(0, 0, 2), "Expires in 2 days"},
 	}
-}
-
-func TestExpireInCreation5(t *testing.T) {
-	expireAt := time.Now().AddDate(1, 0, 2)
-	want := "Expires in 1 year and 2 days"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
+	for _, test := range tests {
+		got := createExpireInTextFromDate(test.expireAt, now, test.locale)
+		if got != test.want {
+			t.Errorf(`%s, %s: expected "%s", got "%s"`, test.locale, test.expireAt.Sub(now), test.want, got)
+		}
 	}
 }
 
-func TestExpireInCreation6(t *testing.T) {
-	expireAt := time.Now().AddDate(1, 2, 0)
-	want := "Expires in 1 year and 2 months"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
+func TestLocalizeShares(t *testing.T) {
+	expiresAt := time.Now().Add(50 * time.Minute).Add(30 * time.Second)
+	responses := []ShareResponse{
+		{ExpiresIn: "Expires in 50 minutes", ExpiresAt: &expiresAt},
+		{ExpiresIn: ""},
 	}
-}
-
-func TestExpireInCreation7(t *testing.T) {
-	expireAt := time.Now().AddDate(1, 0, 0)
-	want := "Expires in 1 year"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
-	}
-}
-
-func TestExpireInCreation8(t *testing.T) {
-	expireAt := time.Now().AddDate(0, 1, 0)
-	want := "Expires in 1 month"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
+	LocalizeShares(responses, i18n.GERMAN)
+	if responses[0].ExpiresIn != "Läuft in 50 Minuten ab" {
+		t.Errorf(`expected German expiry, got "%s"`, responses[0].ExpiresIn)
 	}
-}
-
-func TestExpireInCreation9(t *testing.T) {
-	expireAt := time.Now().AddDate(0, 0, 1)
-	want := "Expires in 1 day"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
+	if responses[1].ExpiresIn != "" {
+		t.Errorf(`share without expiry got "%s"`, responses[1].ExpiresIn)
 	}
 }
 
-func TestExpireInCreation10(t *testing.T) {
-	expireAt := time.Now().Add(time.Second)
-	want := "Expires today"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
+func TestCreateShareRejectsForeignAuthor(t *testing.T) {
+	handler := ShareDBHandler{}
+	tests := []struct {
+		userId   int
+		authorId int
+	}{
+		{5, 7},
+		{5, -1},
+		{-1, 3},
 	}
-}
-
-func TestExpireInCreation11(t *testing.T) {
-	expireAt := time.Now().Add(-time.Second)
-	want := "Already expired"
-	got := createExpireInTextFromDate(expireAt)
-	if got != want {
-		t.Errorf(`expected "%s", got "%s"`, want, got)
+	for _, test := range tests {
+		_, err := handler.CreateShare(test.userId, common.USER, ShareRequest{AuthorId: test.authorId})
+		if _, ok := err.(*AuthorMismatchError); !ok {
+			t.Errorf("user %d creating share of author %d: expected AuthorMismatchError, got %v", test.userId, test.authorId, err)
+		}
 	}
 }
@@ -0,0 +1,165 @@
+package shares
+
+import (
+	"context"
+	"fmt"
+	"qr-pastebin-api/common"
+	"time"
+)
+
+const (
+	STATS_BUCKET_HOUR = "hour"
+	STATS_BUCKET_DAY  = "day"
+)
+
+const (
+	defaultStatsDays   = 30
+	maxHourlyStatsDays = 31
+	maxStatsReferrers  = 10
+)
+
+// StatsRequest picks the histogram, it covers the last Days days in buckets of an hour or a day
+type StatsRequest struct {
+	Bucket string `form:"bucket" binding:"omitempty,oneof=hour day"`
+	Days   int    `form:"days" binding:"omitempty,min=1,max=365"`
+}
+
+type StatsBucket struct {
+	Start  time.Time `json:"start"`
+	Views  int       `json:"views"`
+	FromQr int       `json:"fromQr"`
+}
+
+type ReferrerCount struct {
+	Host  string `json:"host"`
+	Views int    `json:"views"`
+}
+
+// StatsResponse counts all views of a share, only Histogram is limited to the requested days
+type StatsResponse struct {
+	ShareId      string          `json:"shareId"`
+	Total        int             `json:"total"`
+	FromQr       int             `json:"fromQr"`
+	LastViewedAt *time.Time      `json:"lastViewedAt"`
+	Agents       map[string]int  `json:"agents"`
+	Referrers    []ReferrerCount `json:"referrers"`
+	Bucket       string          `json:"bucket"`
+	Histogram    []StatsBucket   `json:"histogram"`
+}
+
+// GetShareStats is available to the author, owners and admins of the owning team, and roles that may view any share
+func (handler *ShareDBHandler) GetShareStats(shareId string, userId int, role common.Role, request StatsRequest) (*StatsResponse, error) {
+	if request.Bucket == "" {
+		request.Bucket = STATS_BUCKET_DAY
+	}
+	if request.Days == 0 {
+		request.Days = defaultStatsDays
+	}
+	if request.Bucket == STATS_BUCKET_HOUR && request.Days > maxHourlyStatsDays {
+		return nil, &common.ValidationError{Fields: []common.FieldError{{
+			Field:   "days",
+			Rule:    "max_hourly",
+			Message: fmt.Sprintf("must be at most %d with hourly buckets", maxHourlyStatsDays),
+		}}}
+	}
+
+	permit, err := handler.HasAccessToShare(userId, shareId, role, common.SHARE_VIEW_ANY)
+	if err != nil {
+		return nil, err
+	}
+	if !permit {
+		return nil, &common.NotFoundError{}
+	}
+
+	stats := StatsResponse{ShareId: shareId, Bucket: request.Bucket, Agents: map[string]int{}, Referrers: make([]ReferrerCount, 0)}
+	query := "SELECT COUNT(*), COUNT(*) FILTER (WHERE from_qr), MAX(viewed_at) FROM share_views WHERE share_id = $1;"
+	err = handler.DB.QueryRow(context.Background(), query, shareId).Scan(&stats.Total, &stats.FromQr, &stats.LastViewedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error counting share views: %w", err)
+	}
+
+	rows, err := handler.DB.Query(context.Background(), "SELECT agent, COUNT(*) FROM share_views WHERE share_id = $1 GROUP BY agent;", shareId)
+	if err != nil {
+		return nil, fmt.Errorf("error counting share views by agent: %w", err)
+	}
+	for rows.Next() {
+		var agent string
+		var views int
+		if err := rows.Scan(&agent, &views); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.Agents[agent] = views
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	query = "SELECT referrer, COUNT(*) FROM share_views WHERE share_id = $1 AND referrer <> '' GROUP BY referrer ORDER BY COUNT(*) DESC, referrer LIMIT $2;"
+	rows, err = handler.DB.Query(context.Background(), query, shareId, maxStatsReferrers)
+	if err != nil {
+		return nil, fmt.Errorf("error counting share views by referrer: %w", err)
+	}
+	for rows.Next() {
+		var referrer ReferrerCount
+		if err := rows.Scan(&referrer.Host, &referrer.Views); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stats.Referrers = append(stats.Referrers, referrer)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	histogram := newHistogram(request.Bucket, request.Days, time.Now())
+	query = "SELECT date_trunc($2, viewed_at AT TIME ZONE 'UTC'), COUNT(*), COUNT(*) FILTER (WHERE from_qr) FROM share_views WHERE share_id = $1 AND viewed_at >= $3 GROUP BY 1;"
+	rows, err = handler.DB.Query(context.Background(), query, shareId, request.Bucket, histogram[0].Start)
+	if err != nil {
+		return nil, fmt.Errorf("error counting share views over time: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket StatsBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Views, &bucket.FromQr); err != nil {
+			return nil, err
+		}
+		addToHistogram(histogram, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	stats.Histogram = histogram
+	return &stats, nil
+}
+
+// newHistogram returns empty buckets in UTC covering the last days, the last bucket holds now
+func newHistogram(bucket string, days int, now time.Time) []StatsBucket {
+	now = now.UTC()
+	step := 24 * time.Hour
+	last := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	count := days
+	if bucket == STATS_BUCKET_HOUR {
+		step = time.Hour
+		last = now.Truncate(time.Hour)
+		count = days * 24
+	}
+
+	histogram := make([]StatsBucket, count)
+	for i := range histogram {
+		histogram[i].Start = last.Add(-time.Duration(count-1-i) * step)
+	}
+	return histogram
+}
+
+func addToHistogram(histogram []StatsBucket, counted StatsBucket) {
+	for i := range histogram {
+		if histogram[i].Start.Equal(counted.Start) {
+			histogram[i].Views += counted.Views
+			histogram[i].FromQr += counted.FromQr
+			return
+		}
+	}
+}
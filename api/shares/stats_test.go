@@ -0,0 +1,48 @@
+package shares
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewHistogram(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 15, 42, 0, 0, time.FixedZone("EET", 2*60*60))
+	tests := []struct {
+		bucket string
+		days   int
+		count  int
+		first  time.Time
+		last   time.Time
+	}{
+		{STATS_BUCKET_DAY, 1, 1, time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)},
+		{STATS_BUCKET_DAY, 30, 30, time.Date(2026, time.February, 9, 0, 0, 0, 0, time.UTC), time.Date(2026, time.March, 10, 0, 0, 0, 0, time.UTC)},
+		{STATS_BUCKET_HOUR, 2, 48, time.Date(2026, time.March, 8, 14, 0, 0, 0, time.UTC), time.Date(2026, time.March, 10, 13, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		histogram := newHistogram(test.bucket, test.days, now)
+		if len(histogram) != test.count {
+			t.Errorf("%s over %d days: expected %d buckets, got %d", test.bucket, test.days, test.count, len(histogram))
+			continue
+		}
+		if !histogram[0].Start.Equal(test.first) || !histogram[len(histogram)-1].Start.Equal(test.last) {
+			t.Errorf("%s over %d days: expected %s to %s, got %s to %s", test.bucket, test.days, test.first, test.last, histogram[0].Start, histogram[len(histogram)-1].Start)
+		}
+	}
+}
+
+func TestAddToHistogram(t *testing.T) {
+	histogram := newHistogram(STATS_BUCKET_DAY, 3, time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC))
+	addToHistogram(histogram, StatsBucket{Start: time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC), Views: 4, FromQr: 3})
+	// Views older than the histogram are left out
+	addToHistogram(histogram, StatsBucket{Start: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), Views: 9})
+
+	want := []int{0, 4, 0}
+	for i, bucket := range histogram {
+		if bucket.Views != want[i] {
+			t.Errorf("bucket %s: expected %d views, got %d", bucket.Start, want[i], bucket.Views)
+		}
+	}
+	if histogram[1].FromQr != 3 {
+		t.Errorf("expected 3 QR views, got %d", histogram[1].FromQr)
+	}
+}
@@ -0,0 +1,71 @@
+package shares
+
+import (
+	"context"
+	"fmt"
+)
+
+func (handler *ShareDBHandler) GetTeamShares(teamId int) ([]ShareResponse, error) {
+	query := fmt.Sprintf("SELECT %s FROM shares AS s WHERE s.team_id = $1 ORDER BY s.created_at DESC;", shareColumns)
+	shares, err := handler.queryShares(query, teamId)
+	if err != nil {
+		return nil, err
+	}
+
+	shareResponses := make([]ShareResponse, 0)
+	for _, share := range shares {
+		newShareResponse, err := handler.transformToShareResponse(&share)
+		if err != nil {
+			return nil, err
+		}
+		shareResponses = append(shareResponses, *newShareResponse)
+	}
+	return shareResponses, nil
+}
+
+// ensureCanMoveToTeam checks a move of the share to teamId (-1 for no team), moving into a team
+// also requires being a member of it
+func (handler *ShareDBHandler) ensureCanMoveToTeam(share *Share, userId int, teamId int) error {
+	managesTeam := false
+	if userId != -1 && share.AuthorId != userId && share.TeamId != -1 {
+		var count int
+		query := "SELECT COUNT(*) FROM team_members WHERE team_id = $1 AND user_id = $2 AND role IN ('owner', 'admin');"
+		if err := handler.DB.QueryRow(context.Background(), query, share.TeamId, userId).Scan(&count); err != nil {
+			return err
+		}
+		managesTeam = count > 0
+	}
+	if err := checkTeamMove(share, userId, teamId, managesTeam); err != nil {
+		return err
+	}
+	if teamId == -1 {
+		return nil
+	}
+	return handler.ensureTeamMember(teamId, userId)
+}
+
+// checkTeamMove lets the author move a share, and owners and admins of the share's team (managesTeam)
+// move it out of the team. A share whose author was deleted has to stay in a team, it would have no
+// owner otherwise.
+func checkTeamMove(share *Share, userId int, teamId int, managesTeam bool) error {
+	if userId == -1 || (share.AuthorId != userId && !managesTeam) {
+		return &TeamMoveNotAllowedError{}
+	}
+	if teamId == -1 && share.AuthorId == -1 {
+		return &OwnerlessShareError{}
+	}
+	return nil
+}
+
+func (handler *ShareDBHandler) ensureTeamMember(teamId int, userId int) error {
+	var count int
+	query := "SELECT COUNT(*) FROM team_members WHERE team_id = $1 AND user_id = $2;"
+	err := handler.DB.QueryRow(context.Background(), query, teamId, userId).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return &TeamMembershipRequiredError{}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package shares
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckTeamMove(t *testing.T) {
+	var notAllowed *TeamMoveNotAllowedError
+	var ownerless *OwnerlessShareError
+	tests := []struct {
+		name        string
+		share       Share
+		userId      int
+		teamId      int
+		managesTeam bool
+		want        any
+	}{
+		{"author moves into a team", Share{AuthorId: 1, TeamId: -1}, 1, 5, false, nil},
+		{"author moves out of a team", Share{AuthorId: 1, TeamId: 5}, 1, -1, false, nil},
+		{"grantee moves a private share into a team", Share{AuthorId: 1, TeamId: -1}, 2, 7, false, &notAllowed},
+		{"member moves a share out of its team", Share{AuthorId: 1, TeamId: 5}, 2, -1, false, &notAllowed},
+		{"team admin moves a share out of its team", Share{AuthorId: 1, TeamId: 5}, 2, -1, true, nil},
+		{"team admin moves a share of a deleted author out", Share{AuthorId: -1, TeamId: 5}, 2, -1, true, &ownerless},
+		{"team admin moves a share of a deleted author to another team", Share{AuthorId: -1, TeamId: 5}, 2, 6, true, nil},
+		{"anonymous user", Share{AuthorId: -1, TeamId: -1}, -1, 5, false, &notAllowed},
+	}
+	for _, test := range tests {
+		err := checkTeamMove(&test.share, test.userId, test.teamId, test.managesTeam)
+		if test.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		if !errors.As(err, test.want) {
+			t.Errorf("%s: expected %T, got %v", test.name, test.want, err)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package shares
+
+import (
+	"fmt"
+	"qr-pastebin-api/common"
+)
+
+// validateShareRequest checks the rules that depend on configuration or on whether the share is
+// being created. Rules that always apply are declared as binding tags on ShareRequest.
+func (handler *ShareDBHandler) validateShareRequest(request ShareRequest, creating bool) error {
+	fields := make([]common.FieldError, 0)
+	if int64(len(request.Content)) > handler.Limits.MaxContentSize {
+		fields = append(fields, common.FieldError{
+			Field:   "content",
+			Rule:    "max_size",
+			Message: fmt.Sprintf("must be at most %d bytes", handler.Limits.MaxContentSize),
+		})
+	}
+	// When updating, setting an empty password removes it
+	if creating && request.SetPassword && request.Password == "" {
+		fields = append(fields, common.FieldError{
+			Field:   "password",
+			Rule:    "required_with_set_password",
+			Message: "is required when setPassword is set",
+		})
+	}
+
+	if len(fields) > 0 {
+		return &common.ValidationError{Fields: fields}
+	}
+	return nil
+}
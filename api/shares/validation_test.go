@@ -0,0 +1,47 @@
+package shares
+
+import (
+	"errors"
+	"qr-pastebin-api/common"
+	"strings"
+	"testing"
+)
+
+func TestValidateShareRequest(t *testing.T) {
+	handler := ShareDBHandler{Limits: Limits{MaxContentSize: 10}}
+	tests := []struct {
+		request  ShareRequest
+		creating bool
+		fields   []string
+	}{
+		{ShareRequest{Content: "short"}, true, nil},
+		{ShareRequest{Content: strings.Repeat("a", 11)}, true, []string{"content"}},
+		{ShareRequest{Content: "short", SetPassword: true}, true, []string{"password"}},
+		{ShareRequest{Content: "short", SetPassword: true}, false, nil},
+		{ShareRequest{Content: strings.Repeat("a", 11), SetPassword: true}, true, []string{"content", "password"}},
+	}
+	for i, test := range tests {
+		err := handler.validateShareRequest(test.request, test.creating)
+		if test.fields == nil {
+			if err != nil {
+				t.Errorf("case %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+
+		var validationErr *common.ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("case %d: expected ValidationError, got %v", i, err)
+			continue
+		}
+		if len(validationErr.Fields) != len(test.fields) {
+			t.Errorf("case %d: expected fields %v, got %v", i, test.fields, validationErr.Fields)
+			continue
+		}
+		for j, field := range test.fields {
+			if validationErr.Fields[j].Field != field {
+				t.Errorf("case %d: expected field %s, got %s", i, field, validationErr.Fields[j].Field)
+			}
+		}
+	}
+}
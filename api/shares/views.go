@@ -0,0 +1,188 @@
+package shares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Coarse classes of user agents, views keep only the class and never the full user agent
+const (
+	AGENT_BOT     = "bot"
+	AGENT_CLI     = "cli"
+	AGENT_MOBILE  = "mobile"
+	AGENT_TABLET  = "tablet"
+	AGENT_DESKTOP = "desktop"
+	AGENT_OTHER   = "other"
+)
+
+// VIEW_SOURCE_QR is the src query parameter of links printed as QR codes
+const VIEW_SOURCE_QR = "qr"
+
+const (
+	viewQueueSize = 10000
+	viewBatchSize = 500
+)
+
+// View is one successful read of a share by a viewer. The address is anonymised and the
+// referrer reduced to its host before a view is kept.
+type View struct {
+	ShareId  string
+	ViewedAt time.Time
+	Agent    string
+	Referrer string
+	Ip       string
+	FromQr   bool
+}
+
+func NewView(shareId string, userAgent string, referrer string, ip string, source string, viewedAt time.Time) View {
+	return View{
+		ShareId:  shareId,
+		ViewedAt: viewedAt,
+		Agent:    classifyUserAgent(userAgent),
+		Referrer: referrerHost(referrer),
+		Ip:       anonymiseIp(ip),
+		FromQr:   source == VIEW_SOURCE_QR,
+	}
+}
+
+func classifyUserAgent(userAgent string) string {
+	agent := strings.ToLower(userAgent)
+	containsAny := func(parts ...string) bool {
+		for _, part := range parts {
+			if strings.Contains(agent, part) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case agent == "":
+		return AGENT_OTHER
+	case containsAny("bot", "crawl", "spider", "slurp", "preview", "facebookexternalhit"):
+		return AGENT_BOT
+	case containsAny("curl/", "wget/", "httpie/", "python-requests", "go-http-client", "qrpaste"):
+		return AGENT_CLI
+	case containsAny("ipad", "tablet") || (strings.Contains(agent, "android") && !strings.Contains(agent, "mobile")):
+		return AGENT_TABLET
+	case containsAny("mobi", "iphone", "android"):
+		return AGENT_MOBILE
+	case strings.HasPrefix(agent, "mozilla/"):
+		return AGENT_DESKTOP
+	default:
+		return AGENT_OTHER
+	}
+}
+
+// referrerHost keeps only the host of the referring page, paths may contain private data
+func referrerHost(referrer string) string {
+	parsed, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// anonymiseIp zeroes the host part of an address, keeping a /24 of IPv4 and a /48 of IPv6
+func anonymiseIp(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// ViewRecorder writes views in batches in the background, so recording never slows down reading a
+// share. Views are dropped rather than queued without bound when the database falls behind.
+type ViewRecorder struct {
+	views chan View
+	write func(views []View) error
+}
+
+// NewViewRecorder writes views with a database connection of its own, Run has to be started
+// in a goroutine
+func NewViewRecorder(db *pgx.Conn) *ViewRecorder {
+	return &ViewRecorder{
+		views: make(chan View, viewQueueSize),
+		write: func(views []View) error { return insertViews(db, views) },
+	}
+}
+
+// AfterWrite passes every batch to notify once it is written, it has to be called before Run
+func (recorder *ViewRecorder) AfterWrite(notify func(views []View) error) {
+	write := recorder.write
+	recorder.write = func(views []View) error {
+		if err := write(views); err != nil {
+			return err
+		}
+		return notify(views)
+	}
+}
+
+// Record queues a view and reports whether there was room for it
+func (recorder *ViewRecorder) Record(view View) bool {
+	select {
+	case recorder.views <- view:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops Run once the queued views are written
+func (recorder *ViewRecorder) Close() {
+	close(recorder.views)
+}
+
+// Run writes queued views whenever a batch is full or the interval has passed
+func (recorder *ViewRecorder) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]View, 0, viewBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := recorder.write(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing %d share views: %v\n", len(batch), err)
+		}
+		batch = make([]View, 0, viewBatchSize)
+	}
+
+	for {
+		select {
+		case view, open := <-recorder.views:
+			if !open {
+				flush()
+				return
+			}
+			batch = append(batch, view)
+			if len(batch) >= viewBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func insertViews(db *pgx.Conn, views []View) error {
+	rows := make([][]any, 0, len(views))
+	for _, view := range views {
+		rows = append(rows, []any{view.ShareId, view.ViewedAt, view.Agent, view.Referrer, view.Ip, view.FromQr})
+	}
+	columns := []string{"share_id", "viewed_at", "agent", "referrer", "ip", "from_qr"}
+	_, err := db.CopyFrom(context.Background(), pgx.Identifier{"share_views"}, columns, pgx.CopyFromRows(rows))
+	return err
+}
@@ -0,0 +1,119 @@
+package shares
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClassifyUserAgent(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"", AGENT_OTHER},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", AGENT_MOBILE},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36", AGENT_MOBILE},
+		{"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", AGENT_TABLET},
+		{"Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15", AGENT_TABLET},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36", AGENT_DESKTOP},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", AGENT_BOT},
+		{"Slackbot-LinkExpanding 1.0", AGENT_BOT},
+		{"curl/8.5.0", AGENT_CLI},
+		{"Go-http-client/1.1", AGENT_CLI},
+		{"SomethingElse/1.0", AGENT_OTHER},
+	}
+	for _, test := range tests {
+		if got := classifyUserAgent(test.userAgent); got != test.want {
+			t.Errorf(`"%s": expected %s, got %s`, test.userAgent, test.want, got)
+		}
+	}
+}
+
+func TestNewViewAnonymises(t *testing.T) {
+	tests := []struct {
+		ip       string
+		referrer string
+		wantIp   string
+		wantHost string
+	}{
+		{"203.0.113.57", "https://News.example.com/posts/42?token=secret", "203.0.113.0", "news.example.com"},
+		{"2001:db8:85a3:8d3:1319:8a2e:370:7348", "", "2001:db8:85a3::", ""},
+		{"::ffff:198.51.100.7", "not a url at all", "198.51.100.0", ""},
+		{"unknown", "://broken", "", ""},
+	}
+	for _, test := range tests {
+		view := NewView("abc", "curl/8.5.0", test.referrer, test.ip, "", time.Now())
+		if view.Ip != test.wantIp || view.Referrer != test.wantHost {
+			t.Errorf("%s from %s: expected %s from %s, got %s from %s", test.ip, test.referrer, test.wantIp, test.wantHost, view.Ip, view.Referrer)
+		}
+	}
+
+	if !NewView("abc", "", "", "", VIEW_SOURCE_QR, time.Now()).FromQr || NewView("abc", "", "", "", "poster", time.Now()).FromQr {
+		t.Error("only src=qr should mark a view as a QR scan")
+	}
+}
+
+func TestViewRecorderWritesBatches(t *testing.T) {
+	var mutex sync.Mutex
+	batches := make([]int, 0)
+	recorder := &ViewRecorder{
+		views: make(chan View, viewQueueSize),
+		write: func(views []View) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			batches = append(batches, len(views))
+			return nil
+		},
+	}
+
+	for i := 0; i < viewBatchSize+10; i++ {
+		if !recorder.Record(View{ShareId: "abc"}) {
+			t.Fatalf("view %d was dropped", i)
+		}
+	}
+	recorder.Close()
+	// Run returns once the closed queue is written
+	recorder.Run(time.Hour)
+
+	if len(batches) != 2 || batches[0] != viewBatchSize || batches[1] != 10 {
+		t.Errorf("expected a full batch and the rest, got %v", batches)
+	}
+}
+
+func TestViewRecorderDropsWhenFull(t *testing.T) {
+	recorder := &ViewRecorder{views: make(chan View, 1), write: func([]View) error { return nil }}
+	if !recorder.Record(View{}) || recorder.Record(View{}) {
+		t.Error("expected the second view to be dropped by a full queue")
+	}
+}
+
+func TestAfterWriteOnlyNotifiesWrittenBatches(t *testing.T) {
+	failing := errors.New("database is down")
+	written := 0
+	recorder := &ViewRecorder{views: make(chan View, 1), write: func([]View) error { return failing }}
+	recorder.AfterWrite(func(views []View) error {
+		written += len(views)
+		return nil
+	})
+
+	if err := recorder.write([]View{{ShareId: "abc"}}); err != failing {
+		t.Errorf("expected the write error, got %v", err)
+	}
+	if written != 0 {
+		t.Errorf("expected no notification for a batch that wasn't written, got %d views", written)
+	}
+
+	recorder.write = func([]View) error { return nil }
+	recorder.AfterWrite(func(views []View) error {
+		written += len(views)
+		return nil
+	})
+	if err := recorder.write([]View{{ShareId: "abc"}, {ShareId: "def"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if written != 2 {
+		t.Errorf("expected 2 notified views, got %d", written)
+	}
+}
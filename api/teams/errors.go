@@ -0,0 +1,47 @@
+package teams
+
+import "fmt"
+
+type TeamAlreadyExistsError struct {
+}
+
+func (e *TeamAlreadyExistsError) Error() string {
+	return "team already exists"
+}
+
+type TeamNameInvalidError struct {
+}
+
+func (e *TeamNameInvalidError) Error() string {
+	return "team name can not be empty"
+}
+
+type TeamRoleInvalidError struct {
+	Role string
+}
+
+func (e *TeamRoleInvalidError) Error() string {
+	return fmt.Sprintf("team role '%s' is not valid, use 'owner', 'admin' or 'member'", e.Role)
+}
+
+type TeamPermissionError struct {
+}
+
+func (e *TeamPermissionError) Error() string {
+	return "your role in the team does not allow this"
+}
+
+type OwnerlessSharesError struct {
+	Count int
+}
+
+func (e *OwnerlessSharesError) Error() string {
+	return fmt.Sprintf("the team owns %d shares whose author was deleted, delete them before the team", e.Count)
+}
+
+type LastOwnerError struct {
+}
+
+func (e *LastOwnerError) Error() string {
+	return "team must keep at least one owner"
+}
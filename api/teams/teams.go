@@ -0,0 +1,319 @@
+package teams
+
+import (
+	"context"
+	"fmt"
+	"qr-pastebin-api/common"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	TEAM_OWNER  = "owner"
+	TEAM_ADMIN  = "admin"
+	TEAM_MEMBER = "member"
+)
+
+type TeamRequest struct {
+	Name string `json:"name"`
+}
+
+type MemberRequest struct {
+	UserName string `json:"userName"`
+	Role     string `json:"role"`
+}
+
+type Member struct {
+	UserId   int    `json:"userId"`
+	UserName string `json:"userName"`
+	Role     string `json:"role"`
+}
+
+type TeamResponse struct {
+	Id      int      `json:"id"`
+	Name    string   `json:"name"`
+	Role    string   `json:"role"`
+	Members []Member `json:"members,omitempty"`
+}
+
+type CreateTeamResponse struct {
+	TeamId int `json:"id"`
+}
+
+type TeamDBHandler struct {
+	DB *pgx.Conn
+}
+
+func NewTeamHandler(db *pgx.Conn) *TeamDBHandler {
+	return &TeamDBHandler{DB: db}
+}
+
+// CreateTeam creates the team and makes the creating user its owner
+func (handler *TeamDBHandler) CreateTeam(userId int, request TeamRequest) (*CreateTeamResponse, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, &TeamNameInvalidError{}
+	}
+
+	var count int
+	err := handler.DB.QueryRow(context.Background(), "SELECT COUNT(*) FROM teams WHERE name = $1;", name).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, &TeamAlreadyExistsError{}
+	}
+
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	var teamId int
+	err = tx.QueryRow(context.Background(), "INSERT INTO teams (name) VALUES ($1) RETURNING id;", name).Scan(&teamId)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create new team: %w", err)
+	}
+	_, err = tx.Exec(context.Background(), "INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, $3);", teamId, userId, TEAM_OWNER)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't add owner to team: %w", err)
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return nil, err
+	}
+	return &CreateTeamResponse{TeamId: teamId}, nil
+}
+
+func (handler *TeamDBHandler) GetTeams(userId int) ([]TeamResponse, error) {
+	query := "SELECT t.id, t.name, m.role FROM teams AS t JOIN team_members AS m ON m.team_id = t.id WHERE m.user_id = $1 ORDER BY t.name;"
+	rows, err := handler.DB.Query(context.Background(), query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := make([]TeamResponse, 0)
+	for rows.Next() {
+		var team TeamResponse
+		if err := rows.Scan(&team.Id, &team.Name, &team.Role); err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return teams, nil
+}
+
+func (handler *TeamDBHandler) GetTeam(teamId int, userId int) (*TeamResponse, error) {
+	role, err := handler.GetMemberRole(teamId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, &common.NotFoundError{}
+	}
+
+	team := TeamResponse{Id: teamId, Role: role}
+	err = handler.DB.QueryRow(context.Background(), "SELECT name FROM teams WHERE id = $1;", teamId).Scan(&team.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	query := "SELECT u.id, u.name, m.role FROM team_members AS m JOIN users AS u ON u.id = m.user_id WHERE m.team_id = $1 ORDER BY u.name;"
+	rows, err := handler.DB.Query(context.Background(), query, teamId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying team members: %w", err)
+	}
+	defer rows.Close()
+
+	team.Members = make([]Member, 0)
+	for rows.Next() {
+		var member Member
+		if err := rows.Scan(&member.UserId, &member.UserName, &member.Role); err != nil {
+			return nil, err
+		}
+		team.Members = append(team.Members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return &team, nil
+}
+
+// SetMember adds the named user to the team or changes their membership role.
+// Owners and admins manage members, but only owners can hand out or take away ownership.
+func (handler *TeamDBHandler) SetMember(teamId int, userId int, request MemberRequest) error {
+	if request.Role != TEAM_OWNER && request.Role != TEAM_ADMIN && request.Role != TEAM_MEMBER {
+		return &TeamRoleInvalidError{Role: request.Role}
+	}
+
+	actorRole, err := handler.ensureCanManageMembers(teamId, userId)
+	if err != nil {
+		return err
+	}
+
+	member, err := common.GetUserByName(handler.DB, request.UserName)
+	if err != nil {
+		return &common.NotFoundError{}
+	}
+	currentRole, err := handler.GetMemberRole(teamId, member.Id)
+	if err != nil {
+		return err
+	}
+
+	if err := checkMemberChange(actorRole, currentRole, request.Role); err != nil {
+		return err
+	}
+	if err := handler.ensureOwnerRemains(teamId, member.Id, currentRole, request.Role); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role;"
+	_, err = handler.DB.Exec(context.Background(), query, teamId, member.Id, request.Role)
+	if err != nil {
+		return fmt.Errorf("could not set member of team '%d': %w", teamId, err)
+	}
+	return nil
+}
+
+// RemoveMember removes a member from the team. Members can always remove themselves.
+// Shares of the team stay with the team, so they remain editable by everyone left in it.
+func (handler *TeamDBHandler) RemoveMember(teamId int, userId int, memberId int) error {
+	memberRole, err := handler.GetMemberRole(teamId, memberId)
+	if err != nil {
+		return err
+	}
+	if memberRole == "" {
+		return &common.NotFoundError{}
+	}
+
+	if memberId != userId {
+		actorRole, err := handler.ensureCanManageMembers(teamId, userId)
+		if err != nil {
+			return err
+		}
+		if err := checkMemberChange(actorRole, memberRole, ""); err != nil {
+			return err
+		}
+	}
+	if err := handler.ensureOwnerRemains(teamId, memberId, memberRole, ""); err != nil {
+		return err
+	}
+
+	_, err = handler.DB.Exec(context.Background(), "DELETE FROM team_members WHERE team_id = $1 AND user_id = $2;", teamId, memberId)
+	if err != nil {
+		return fmt.Errorf("could not remove member from team '%d': %w", teamId, err)
+	}
+	return nil
+}
+
+// DeleteTeam deletes the team, its shares go back to their authors. Shares whose author was deleted
+// would be left without anyone who can manage them, so they have to be deleted first.
+func (handler *TeamDBHandler) DeleteTeam(teamId int, userId int) error {
+	role, err := handler.GetMemberRole(teamId, userId)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return &common.NotFoundError{}
+	}
+	if role != TEAM_OWNER {
+		return &TeamPermissionError{}
+	}
+
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	var ownerless int
+	err = tx.QueryRow(context.Background(), "SELECT COUNT(*) FROM shares WHERE team_id = $1 AND author_id = -1;", teamId).Scan(&ownerless)
+	if err != nil {
+		return err
+	}
+	if ownerless > 0 {
+		return &OwnerlessSharesError{Count: ownerless}
+	}
+
+	queries := []string{
+		"UPDATE shares SET team_id = -1 WHERE team_id = $1;",
+		"DELETE FROM team_members WHERE team_id = $1;",
+		"DELETE FROM teams WHERE id = $1;",
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query, teamId); err != nil {
+			return fmt.Errorf("could not delete team '%d': %w", teamId, err)
+		}
+	}
+	return tx.Commit(context.Background())
+}
+
+// GetMemberRole returns the role of the user in the team, or an empty string if they are not a member
+func (handler *TeamDBHandler) GetMemberRole(teamId int, userId int) (string, error) {
+	var role string
+	err := handler.DB.QueryRow(context.Background(), "SELECT role FROM team_members WHERE team_id = $1 AND user_id = $2;", teamId, userId).Scan(&role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+func (handler *TeamDBHandler) IsMember(teamId int, userId int) (bool, error) {
+	role, err := handler.GetMemberRole(teamId, userId)
+	return role != "", err
+}
+
+func (handler *TeamDBHandler) ensureCanManageMembers(teamId int, userId int) (string, error) {
+	role, err := handler.GetMemberRole(teamId, userId)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", &common.NotFoundError{}
+	}
+	if role != TEAM_OWNER && role != TEAM_ADMIN {
+		return "", &TeamPermissionError{}
+	}
+	return role, nil
+}
+
+// ensureOwnerRemains refuses to take ownership away from the member when they are the last owner
+func (handler *TeamDBHandler) ensureOwnerRemains(teamId int, memberId int, currentRole string, newRole string) error {
+	if currentRole != TEAM_OWNER || newRole == TEAM_OWNER {
+		return nil
+	}
+	var otherOwners int
+	query := "SELECT COUNT(*) FROM team_members WHERE team_id = $1 AND role = $2 AND user_id != $3;"
+	err := handler.DB.QueryRow(context.Background(), query, teamId, TEAM_OWNER, memberId).Scan(&otherOwners)
+	if err != nil {
+		return err
+	}
+	return checkOwnerRemains(currentRole, newRole, otherOwners)
+}
+
+// checkMemberChange lets owners and admins manage members, but only owners hand out or take away
+// ownership. newRole is empty when the member is removed.
+func checkMemberChange(actorRole string, currentRole string, newRole string) error {
+	if actorRole != TEAM_OWNER && actorRole != TEAM_ADMIN {
+		return &TeamPermissionError{}
+	}
+	if actorRole != TEAM_OWNER && (newRole == TEAM_OWNER || currentRole == TEAM_OWNER) {
+		return &TeamPermissionError{}
+	}
+	return nil
+}
+
+func checkOwnerRemains(currentRole string, newRole string, otherOwners int) error {
+	if currentRole == TEAM_OWNER && newRole != TEAM_OWNER && otherOwners == 0 {
+		return &LastOwnerError{}
+	}
+	return nil
+}
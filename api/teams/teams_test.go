@@ -0,0 +1,61 @@
+package teams
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckMemberChange(t *testing.T) {
+	tests := []struct {
+		actorRole   string
+		currentRole string
+		newRole     string
+		allowed     bool
+	}{
+		{TEAM_OWNER, "", TEAM_MEMBER, true},
+		{TEAM_OWNER, TEAM_MEMBER, TEAM_OWNER, true},
+		{TEAM_OWNER, TEAM_OWNER, TEAM_ADMIN, true},
+		{TEAM_OWNER, TEAM_OWNER, "", true},
+		{TEAM_ADMIN, "", TEAM_MEMBER, true},
+		{TEAM_ADMIN, TEAM_MEMBER, TEAM_ADMIN, true},
+		{TEAM_ADMIN, TEAM_ADMIN, "", true},
+		{TEAM_ADMIN, TEAM_MEMBER, TEAM_OWNER, false},
+		{TEAM_ADMIN, TEAM_OWNER, TEAM_MEMBER, false},
+		{TEAM_ADMIN, TEAM_OWNER, "", false},
+		{TEAM_MEMBER, "", TEAM_MEMBER, false},
+		{TEAM_MEMBER, TEAM_MEMBER, "", false},
+	}
+	for _, test := range tests {
+		err := checkMemberChange(test.actorRole, test.currentRole, test.newRole)
+		var permissionErr *TeamPermissionError
+		if test.allowed && err != nil {
+			t.Errorf("%s changing %q to %q: unexpected error %v", test.actorRole, test.currentRole, test.newRole, err)
+		}
+		if !test.allowed && !errors.As(err, &permissionErr) {
+			t.Errorf("%s changing %q to %q: expected TeamPermissionError, got %v", test.actorRole, test.currentRole, test.newRole, err)
+		}
+	}
+}
+
+func TestCheckOwnerRemains(t *testing.T) {
+	tests := []struct {
+		currentRole string
+		newRole     string
+		otherOwners int
+		lastOwner   bool
+	}{
+		{TEAM_OWNER, TEAM_ADMIN, 0, true},
+		{TEAM_OWNER, "", 0, true},
+		{TEAM_OWNER, TEAM_MEMBER, 1, false},
+		{TEAM_OWNER, "", 2, false},
+		{TEAM_OWNER, TEAM_OWNER, 0, false},
+		{TEAM_ADMIN, "", 0, false},
+	}
+	for _, test := range tests {
+		err := checkOwnerRemains(test.currentRole, test.newRole, test.otherOwners)
+		var lastOwnerErr *LastOwnerError
+		if errors.As(err, &lastOwnerErr) != test.lastOwner {
+			t.Errorf("%s to %q with %d other owners: expected last owner %t, got %v", test.currentRole, test.newRole, test.otherOwners, test.lastOwner, err)
+		}
+	}
+}
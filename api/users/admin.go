@@ -0,0 +1,208 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"qr-pastebin-api/common"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+type UserSummary struct {
+	Id            int         `json:"id"`
+	Name          string      `json:"name"`
+	Role          common.Role `json:"role"`
+	IsOauth       bool        `json:"isOauth"`
+	Disabled      bool        `json:"disabled"`
+	Email         string      `json:"email"`
+	EmailVerified bool        `json:"emailVerified"`
+	ShareCount    int         `json:"shareCount"`
+}
+
+type UserSearchRequest struct {
+	Query  string `form:"query"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type ChangeDisabledRequest struct {
+	Disabled bool `json:"disabled"`
+}
+
+const userSummaryColumns = "u.id, u.name, u.role, u.isoauth, u.disabled, u.email, u.email_verified, (SELECT COUNT(*) FROM shares AS s WHERE s.author_id = u.id)"
+
+func (handler *UserDBHandler) SearchUsers(request UserSearchRequest) ([]UserSummary, error) {
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultUserPageSize
+	}
+	limit = min(limit, maxUserPageSize)
+	offset := max(request.Offset, 0)
+
+	query := fmt.Sprintf("SELECT %s FROM users AS u WHERE u.name ILIKE $1 OR u.email ILIKE $1 ORDER BY u.name LIMIT $2 OFFSET $3;", userSummaryColumns)
+	rows, err := handler.DB.Query(context.Background(), query, "%"+request.Query+"%", limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]UserSummary, 0)
+	for rows.Next() {
+		user, err := scanUserSummary(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return users, nil
+}
+
+func (handler *UserDBHandler) GetUserSummary(userId int) (*UserSummary, error) {
+	query := fmt.Sprintf("SELECT %s FROM users AS u WHERE u.id = $1;", userSummaryColumns)
+	return scanUserSummary(handler.DB.QueryRow(context.Background(), query, userId))
+}
+
+func (handler *UserDBHandler) ChangeRole(adminId int, userId int, roleName string) error {
+	if adminId == userId {
+		return &CannotModifySelfError{}
+	}
+	role, ok := common.ParseRole(roleName)
+	if !ok {
+		return &UnknownRoleError{Role: roleName}
+	}
+	return handler.updateUser(userId, "UPDATE users SET role = $1 WHERE id = $2;", role, userId)
+}
+
+// SetDisabled bans or unbans the user. Banned users lose their sessions immediately.
+func (handler *UserDBHandler) SetDisabled(adminId int, userId int, disabled bool) error {
+	if adminId == userId {
+		return &CannotModifySelfError{}
+	}
+	err := handler.updateUser(userId, "UPDATE users SET disabled = $1 WHERE id = $2;", disabled, userId)
+	if err != nil {
+		return err
+	}
+	if disabled {
+		return handler.deleteSessions(userId)
+	}
+	return nil
+}
+
+func (handler *UserDBHandler) ExpireSessions(userId int) error {
+	if _, err := common.GetUserById(handler.DB, userId); err != nil {
+		return err
+	}
+	return handler.deleteSessions(userId)
+}
+
+// deleteUserQueries clear everything that belongs to a user, rows pointing at the user's shares go
+// before the shares themselves
+var deleteUserQueries = []string{
+	"DELETE FROM share_grants WHERE user_id = $1 OR share_id IN (SELECT id FROM shares WHERE author_id = $1 AND team_id = -1);",
+	"DELETE FROM share_views WHERE share_id IN (SELECT id FROM shares WHERE author_id = $1 AND team_id = -1);",
+	"DELETE FROM attachments WHERE share_id IN (SELECT id FROM shares WHERE author_id = $1 AND team_id = -1);",
+	"DELETE FROM shares WHERE author_id = $1 AND team_id = -1;",
+	"UPDATE shares SET author_id = -1 WHERE author_id = $1;",
+	"DELETE FROM team_members WHERE user_id = $1;",
+	"DELETE FROM sessions WHERE user_id = $1;",
+	"DELETE FROM login_challenges WHERE user_id = $1;",
+	"DELETE FROM recovery_codes WHERE user_id = $1;",
+	"DELETE FROM user_tokens WHERE user_id = $1;",
+	"DELETE FROM webhook_attempts WHERE delivery_id IN (SELECT d.id FROM webhook_deliveries AS d JOIN webhooks AS w ON w.id = d.webhook_id WHERE w.user_id = $1);",
+	"DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE user_id = $1);",
+	"DELETE FROM webhooks WHERE user_id = $1;",
+}
+
+// DeleteUser removes the user together with every share they authored and all of their login state,
+// returning how many shares were deleted. Shares owned by a team are kept for the team, only the author is cleared.
+// No share.deleted webhook events are sent, the webhooks they would go to belong to the user and are deleted as well.
+func (handler *UserDBHandler) DeleteUser(adminId int, userId int) (int, error) {
+	if adminId == userId {
+		return 0, &CannotModifySelfError{}
+	}
+
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(context.Background())
+
+	// The blobs are only removed once the rows are gone for good
+	rows, err := tx.Query(context.Background(),
+		"SELECT storage_key FROM attachments WHERE share_id IN (SELECT id FROM shares WHERE author_id = $1 AND team_id = -1);", userId)
+	if err != nil {
+		return 0, fmt.Errorf("could not read attachments of user '%d': %w", userId, err)
+	}
+	storageKeys, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("could not read attachments of user '%d': %w", userId, err)
+	}
+
+	deletedShares := 0
+	for _, query := range deleteUserQueries {
+		tag, err := tx.Exec(context.Background(), query, userId)
+		if err != nil {
+			return 0, fmt.Errorf("could not delete data of user '%d': %w", userId, err)
+		}
+		if strings.HasPrefix(query, "DELETE FROM shares ") {
+			deletedShares = int(tag.RowsAffected())
+		}
+	}
+
+	tag, err := tx.Exec(context.Background(), "DELETE FROM users WHERE id = $1;", userId)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete user '%d': %w", userId, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, &common.NotFoundError{}
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return 0, err
+	}
+
+	// The user is gone either way, a blob left behind is only logged
+	for _, key := range storageKeys {
+		if err := handler.Blobs.Delete(context.Background(), key); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", fmt.Errorf("could not delete attachment '%s' of user '%d': %w", key, userId, err))
+		}
+	}
+	return deletedShares, nil
+}
+
+func (handler *UserDBHandler) updateUser(userId int, query string, args ...any) error {
+	tag, err := handler.DB.Exec(context.Background(), query, args...)
+	if err != nil {
+		return fmt.Errorf("could not update user '%d': %w", userId, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &common.NotFoundError{}
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUserSummary(row rowScanner) (*UserSummary, error) {
+	var user UserSummary
+	err := row.Scan(&user.Id, &user.Name, &user.Role, &user.IsOauth, &user.Disabled, &user.Email, &user.EmailVerified, &user.ShareCount)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
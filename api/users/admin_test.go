@@ -0,0 +1,73 @@
+package users
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Admins can't lock themselves out, the guard is checked before the database is touched
+func TestAdminsCanNotModifyThemselves(t *testing.T) {
+	handler := &UserDBHandler{}
+	actions := map[string]func() error{
+		"change role": func() error { return handler.ChangeRole(7, 7, "user") },
+		"disable":     func() error { return handler.SetDisabled(7, 7, true) },
+		"delete": func() error {
+			_, err := handler.DeleteUser(7, 7)
+			return err
+		},
+	}
+	for name, action := range actions {
+		var selfErr *CannotModifySelfError
+		if err := action(); !errors.As(err, &selfErr) {
+			t.Errorf("%s: expected CannotModifySelfError, got %v", name, err)
+		}
+	}
+}
+
+func TestChangeRoleRejectsUnknownRoles(t *testing.T) {
+	handler := &UserDBHandler{}
+	for _, role := range []string{"", "superuser", "Admin", "owner"} {
+		var roleErr *UnknownRoleError
+		if err := handler.ChangeRole(1, 2, role); !errors.As(err, &roleErr) {
+			t.Errorf("'%s': expected UnknownRoleError, got %v", role, err)
+		}
+	}
+}
+
+func TestDeleteUserClearsEveryTable(t *testing.T) {
+	schema, err := os.ReadFile("../init.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every table with a user_id column has to be cleared, every table with a share_id column
+	// before the shares of the user are deleted
+	sharesDeleted := slices.Index(deleteUserQueries, "DELETE FROM shares WHERE author_id = $1 AND team_id = -1;")
+	if sharesDeleted == -1 {
+		t.Fatalf("expected the shares of the user to be deleted")
+	}
+	tablePattern := regexp.MustCompile(`(?s)CREATE TABLE public\.(\w+) \((.*?)\n\);`)
+	for _, match := range tablePattern.FindAllStringSubmatch(string(schema), -1) {
+		table, columns := match[1], match[2]
+		clearsTable := func(query string) bool {
+			return strings.HasPrefix(query, "DELETE FROM "+table+" ")
+		}
+		if strings.Contains(columns, "\tuser_id ") && !slices.ContainsFunc(deleteUserQueries, clearsTable) {
+			t.Errorf("table %s is not cleared when a user is deleted", table)
+		}
+		if strings.Contains(columns, "\tshare_id ") && !slices.ContainsFunc(deleteUserQueries[:sharesDeleted], clearsTable) {
+			t.Errorf("expected %s of the user's shares to be deleted before the shares", table)
+		}
+	}
+
+	// Rows pointing at the deleted shares go first
+	for i, query := range deleteUserQueries {
+		if strings.Contains(query, "share_id IN (SELECT id FROM shares") && i > sharesDeleted {
+			t.Errorf("expected '%s' to run before the shares are deleted", query)
+		}
+	}
+}
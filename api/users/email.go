@@ -0,0 +1,211 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	netmail "net/mail"
+	"os"
+	"qr-pastebin-api/common"
+	"qr-pastebin-api/mail"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	tokenPurposeVerifyEmail   = "verify_email"
+	tokenPurposeResetPassword = "reset_password"
+	verifyEmailTokenTTL       = 48 * time.Hour
+	resetPasswordTokenTTL     = 30 * time.Minute
+)
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type TokenRequest struct {
+	Token string `json:"token"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (handler *UserDBHandler) ChangeEmail(userId int, email string) error {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+	if err := handler.ensureEmailIsFree(email, userId); err != nil {
+		return err
+	}
+
+	_, err = handler.DB.Exec(context.Background(), "UPDATE users SET email = $1, email_verified = false WHERE id = $2;", email, userId)
+	if err != nil {
+		return fmt.Errorf("could not change email of user '%d': %w", userId, err)
+	}
+	return handler.sendVerificationEmail(userId, email)
+}
+
+func (handler *UserDBHandler) ResendVerificationEmail(userId int) error {
+	var email string
+	var verified bool
+	err := handler.DB.QueryRow(context.Background(), "SELECT email, email_verified FROM users WHERE id = $1;", userId).Scan(&email, &verified)
+	if err != nil {
+		return err
+	}
+	if email == "" || verified {
+		return nil
+	}
+	return handler.sendVerificationEmail(userId, email)
+}
+
+func (handler *UserDBHandler) VerifyEmail(token string) error {
+	userId, err := handler.consumeToken(token, tokenPurposeVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	_, err = handler.DB.Exec(context.Background(), "UPDATE users SET email_verified = true WHERE id = $1;", userId)
+	return err
+}
+
+// RequestPasswordReset sends a reset link when a verified email matches. It never reports whether
+// the address is known, so that the endpoint can't be used to find out who has an account.
+func (handler *UserDBHandler) RequestPasswordReset(email string) error {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return err
+	}
+
+	var userId int
+	var isOauth bool
+	query := "SELECT id, isoauth FROM users WHERE email = $1 AND email_verified = true;"
+	err = handler.DB.QueryRow(context.Background(), query, email).Scan(&userId, &isOauth)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if isOauth {
+		return nil
+	}
+
+	token, err := handler.createToken(userId, tokenPurposeResetPassword, resetPasswordTokenTTL)
+	if err != nil {
+		return err
+	}
+	return handler.Mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Reset your QR Pastebin password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your QR Pastebin account.\n\n"+
+			"Open this link within %d minutes to choose a new password:\n%s\n\n"+
+			"If it wasn't you, you can ignore this message.", int(resetPasswordTokenTTL.Minutes()), createWebLink("reset-password", token)),
+	})
+}
+
+func (handler *UserDBHandler) ResetPassword(request ResetPasswordRequest) error {
+	if request.Password == "" {
+		return &EmptyPasswordError{}
+	}
+
+	userId, err := handler.consumeToken(request.Token, tokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := common.CreatePasswordHash(request.Password)
+	if err != nil {
+		return err
+	}
+	_, err = handler.DB.Exec(context.Background(), "UPDATE users SET passwordHash = $1 WHERE id = $2;", passwordHash, userId)
+	if err != nil {
+		return fmt.Errorf("could not update password of user '%d': %w", userId, err)
+	}
+
+	// Anyone who knew the old password should lose access as well
+	return handler.deleteSessions(userId)
+}
+
+func (handler *UserDBHandler) sendVerificationEmail(userId int, email string) error {
+	token, err := handler.createToken(userId, tokenPurposeVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	return handler.Mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Verify your QR Pastebin email",
+		Body: fmt.Sprintf("Confirm that this address belongs to your QR Pastebin account by opening:\n%s\n\n"+
+			"The link is valid for %d hours.", createWebLink("verify-email", token), int(verifyEmailTokenTTL.Hours())),
+	})
+}
+
+func (handler *UserDBHandler) ensureEmailIsFree(email string, userId int) error {
+	var count int
+	err := handler.DB.QueryRow(context.Background(), "SELECT COUNT(*) FROM users WHERE email = $1 AND id != $2;", email, userId).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &EmailAlreadyInUseError{}
+	}
+	return nil
+}
+
+// createToken stores only the hash of the token, the plain token is known only to the email recipient.
+// Older tokens of the same purpose are removed, so only the newest link works.
+func (handler *UserDBHandler) createToken(userId int, purpose string, ttl time.Duration) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("could not generate token: %w", err)
+	}
+	token := hex.EncodeToString(random)
+
+	_, err := handler.DB.Exec(context.Background(), "DELETE FROM user_tokens WHERE (user_id = $1 AND purpose = $2) OR expire_at < $3;", userId, purpose, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	query := "INSERT INTO user_tokens (token_hash, user_id, purpose, expire_at) VALUES ($1, $2, $3, $4);"
+	_, err = handler.DB.Exec(context.Background(), query, hashToken(token), userId, purpose, time.Now().Add(ttl))
+	if err != nil {
+		return "", fmt.Errorf("could not store token: %w", err)
+	}
+	return token, nil
+}
+
+func (handler *UserDBHandler) consumeToken(token string, purpose string) (int, error) {
+	var userId int
+	query := "DELETE FROM user_tokens WHERE token_hash = $1 AND purpose = $2 AND expire_at > $3 RETURNING user_id;"
+	err := handler.DB.QueryRow(context.Background(), query, hashToken(token), purpose, time.Now()).Scan(&userId)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return -1, &TokenInvalidError{}
+		}
+		return -1, err
+	}
+	return userId, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeEmail(email string) (string, error) {
+	address, err := netmail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" {
+		return "", &EmailInvalidError{}
+	}
+	return strings.ToLower(address.Address), nil
+}
+
+func createWebLink(path string, token string) string {
+	return fmt.Sprintf("%s/%s?token=%s", strings.TrimSuffix(os.Getenv("PUBLIC_WEB_ADDRESS"), "/"), path, token)
+}
@@ -0,0 +1,51 @@
+package users
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"user@example.com", "user@example.com"},
+		{"  User@Example.COM ", "user@example.com"},
+		{"not an email", ""},
+		{"User <user@example.com>", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		got, err := normalizeEmail(test.email)
+		if test.want == "" {
+			var invalidErr *EmailInvalidError
+			if !errors.As(err, &invalidErr) {
+				t.Errorf("'%s': expected EmailInvalidError, got '%s' and %v", test.email, got, err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("'%s': expected '%s', got '%s' and %v", test.email, test.want, got, err)
+		}
+	}
+}
+
+func TestTokensAreStoredHashed(t *testing.T) {
+	token := "4f2a9c"
+	hash := hashToken(token)
+	if hash == token || len(hash) != 64 {
+		t.Errorf("expected a sha256 hex digest, got '%s'", hash)
+	}
+	if hashToken(token) != hash {
+		t.Errorf("expected the same token to hash the same")
+	}
+}
+
+func TestCreateWebLink(t *testing.T) {
+	t.Setenv("PUBLIC_WEB_ADDRESS", "https://localhost:5173/")
+	want := "https://localhost:5173/reset-password?token=abc"
+	if got := createWebLink("reset-password", "abc"); got != want {
+		t.Errorf(`expected "%s", got "%s"`, want, got)
+	}
+}
@@ -1,8 +1,99 @@
 package users
 
+import (
+	"fmt"
+	"time"
+)
+
 type UserAlreadyExistsError struct {
 }
 
 func (e *UserAlreadyExistsError) Error() string {
 	return "user already exists"
 }
+
+type TwoFactorCodeIncorrectError struct {
+}
+
+func (e *TwoFactorCodeIncorrectError) Error() string {
+	return "two-factor code is incorrect"
+}
+
+type TwoFactorChallengeExpiredError struct {
+}
+
+func (e *TwoFactorChallengeExpiredError) Error() string {
+	return "two-factor login challenge is expired, log in again"
+}
+
+type TwoFactorLockedError struct {
+	Until time.Time
+}
+
+func (e *TwoFactorLockedError) Error() string {
+	return "too many incorrect two-factor codes, try again later"
+}
+
+type TwoFactorAlreadyEnabledError struct {
+}
+
+func (e *TwoFactorAlreadyEnabledError) Error() string {
+	return "two-factor authentication is already enabled"
+}
+
+type TwoFactorNotEnabledError struct {
+}
+
+func (e *TwoFactorNotEnabledError) Error() string {
+	return "two-factor authentication is not enabled"
+}
+
+type TokenInvalidError struct {
+}
+
+func (e *TokenInvalidError) Error() string {
+	return "token is invalid or expired"
+}
+
+type EmailInvalidError struct {
+}
+
+func (e *EmailInvalidError) Error() string {
+	return "email address is not valid"
+}
+
+type EmailAlreadyInUseError struct {
+}
+
+func (e *EmailAlreadyInUseError) Error() string {
+	return "email address is already in use"
+}
+
+type EmptyPasswordError struct {
+}
+
+func (e *EmptyPasswordError) Error() string {
+	return "password can not be empty"
+}
+
+type AccountDisabledError struct {
+}
+
+func (e *AccountDisabledError) Error() string {
+	return "account is disabled"
+}
+
+type CannotModifySelfError struct {
+}
+
+func (e *CannotModifySelfError) Error() string {
+	return "admins can not change their own role, status or account"
+}
+
+type UnknownRoleError struct {
+	Role string
+}
+
+func (e *UnknownRoleError) Error() string {
+	return fmt.Sprintf("unknown role '%s'", e.Role)
+}
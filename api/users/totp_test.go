@@ -0,0 +1,58 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+// Secret from the RFC 6238 test vectors ("12345678901234567890" encoded as base32)
+const rfcTestSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTotpCode1(t *testing.T) {
+	want := "287082"
+	got, err := totpCode(rfcTestSecret, totpStep(time.Unix(59, 0)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf(`expected "%s", got "%s"`, want, got)
+	}
+}
+
+func TestTotpCode2(t *testing.T) {
+	want := "005924"
+	got, err := totpCode(rfcTestSecret, totpStep(time.Unix(1234567890, 0)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf(`expected "%s", got "%s"`, want, got)
+	}
+}
+
+func TestTotpValidationAllowsSkew(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	previousCode, _ := totpCode(rfcTestSecret, totpStep(now)-1)
+	if _, ok := validateTotpCode(rfcTestSecret, previousCode, now, 0); !ok {
+		t.Errorf("expected code from previous time step to be accepted")
+	}
+}
+
+func TestTotpValidationRejectsReplay(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, _ := totpCode(rfcTestSecret, totpStep(now))
+	step, ok := validateTotpCode(rfcTestSecret, code, now, 0)
+	if !ok {
+		t.Fatalf("expected code to be accepted")
+	}
+	if _, ok := validateTotpCode(rfcTestSecret, code, now, step); ok {
+		t.Errorf("expected already used code to be rejected")
+	}
+}
+
+func TestTotpValidationRejectsWrongCode(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	if _, ok := validateTotpCode(rfcTestSecret, "000000", now, 0); ok {
+		t.Errorf("expected wrong code to be rejected")
+	}
+}
@@ -0,0 +1,401 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"qr-pastebin-api/common"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	recoveryCodeCount        = 10
+	recoveryCodeLength       = 10
+	loginChallengeTTL        = 5 * time.Minute
+	loginChallengeMaxRetries = 5
+	loginChallengeIdLength   = 32
+	// Failed codes are counted per user across challenges, after twoFactorFreeFailures every
+	// further failure locks the second factor for twice as long, up to twoFactorMaxLockout
+	twoFactorFreeFailures = 5
+	twoFactorFirstLockout = time.Minute
+	twoFactorMaxLockout   = time.Hour
+)
+
+var recoveryCodeLetters = []byte("abcdefghjkmnpqrstuvwxyz23456789")
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthUri string `json:"otpauthUri"`
+	QrCode     string `json:"qrCode"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeId string `json:"challengeId"`
+	Code        string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type twoFactorState struct {
+	Secret       string
+	Enabled      bool
+	LastUsedStep int64
+}
+
+func (handler *UserDBHandler) EnrollTwoFactor(userId int) (*TwoFactorEnrollment, error) {
+	user, err := common.GetUserById(handler.DB, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsOauth {
+		return nil, &common.UserLoggedInViaOauth{}
+	}
+
+	state, err := handler.getTwoFactorState(userId)
+	if err != nil {
+		return nil, err
+	}
+	if state.Enabled {
+		return nil, &TwoFactorAlreadyEnabledError{}
+	}
+
+	secret, err := generateTotpSecret()
+	if err != nil {
+		return nil, err
+	}
+	_, err = handler.DB.Exec(context.Background(), "UPDATE users SET totp_secret = $1, totp_last_step = 0 WHERE id = $2;", secret, userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not store totp secret: %w", err)
+	}
+
+	uri := createOtpauthUri(secret, user.Name)
+	qrCode, err := createQrCodeDataUri(uri)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrollment{Secret: secret, OtpauthUri: uri, QrCode: qrCode}, nil
+}
+
+func (handler *UserDBHandler) ActivateTwoFactor(userId int, code string) (*RecoveryCodesResponse, error) {
+	state, err := handler.getTwoFactorState(userId)
+	if err != nil {
+		return nil, err
+	}
+	if state.Enabled {
+		return nil, &TwoFactorAlreadyEnabledError{}
+	}
+	if state.Secret == "" {
+		return nil, &TwoFactorNotEnabledError{}
+	}
+
+	step, ok := validateTotpCode(state.Secret, code, time.Now(), state.LastUsedStep)
+	if !ok {
+		return nil, &TwoFactorCodeIncorrectError{}
+	}
+
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), "UPDATE users SET totp_enabled = true, totp_last_step = $1 WHERE id = $2;", step, userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not enable two-factor authentication: %w", err)
+	}
+	codes, err := replaceRecoveryCodes(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (handler *UserDBHandler) DisableTwoFactor(userId int, request TwoFactorDisableRequest) error {
+	user, err := common.GetUserById(handler.DB, userId)
+	if err != nil {
+		return err
+	}
+	if !common.IsPasswordCorrect(user.PasswordHash, request.Password) {
+		return &common.PasswordIncorrectError{}
+	}
+
+	if err := handler.verifySecondFactor(userId, request.Code); err != nil {
+		return err
+	}
+
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	_, err = tx.Exec(context.Background(), "UPDATE users SET totp_enabled = false, totp_secret = '', totp_last_step = 0 WHERE id = $1;", userId)
+	if err != nil {
+		return fmt.Errorf("could not disable two-factor authentication: %w", err)
+	}
+	_, err = tx.Exec(context.Background(), "DELETE FROM recovery_codes WHERE user_id = $1;", userId)
+	if err != nil {
+		return fmt.Errorf("could not delete recovery codes: %w", err)
+	}
+	return tx.Commit(context.Background())
+}
+
+func (handler *UserDBHandler) RegenerateRecoveryCodes(userId int, code string) (*RecoveryCodesResponse, error) {
+	if err := handler.verifySecondFactor(userId, code); err != nil {
+		return nil, err
+	}
+
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(context.Background())
+
+	codes, err := replaceRecoveryCodes(tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (handler *UserDBHandler) CompleteTwoFactorLogin(request TwoFactorLoginRequest) (*SessionData, error) {
+	// The attempt is counted before the code is checked, so parallel requests can't share one
+	var userId int
+	var attempts int
+	query := "UPDATE login_challenges SET attempts = attempts + 1 WHERE challenge_id = $1 AND expire_at > $2 AND attempts < $3 RETURNING user_id, attempts;"
+	err := handler.DB.QueryRow(context.Background(), query, request.ChallengeId, time.Now(), loginChallengeMaxRetries).Scan(&userId, &attempts)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, &TwoFactorChallengeExpiredError{}
+		}
+		return nil, err
+	}
+
+	err = handler.verifySecondFactor(userId, request.Code)
+	var incorrectCodeErr *TwoFactorCodeIncorrectError
+	if errors.As(err, &incorrectCodeErr) && attempts >= loginChallengeMaxRetries {
+		// Too many wrong codes burn the challenge, so the password has to be entered again
+		_, _ = handler.DB.Exec(context.Background(), "DELETE FROM login_challenges WHERE challenge_id = $1;", request.ChallengeId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = handler.DB.Exec(context.Background(), "DELETE FROM login_challenges WHERE challenge_id = $1;", request.ChallengeId)
+	if err != nil {
+		return nil, err
+	}
+	return handler.issueSession(userId)
+}
+
+func (handler *UserDBHandler) createLoginChallenge(userId int) (string, error) {
+	_, err := handler.DB.Exec(context.Background(), "DELETE FROM login_challenges WHERE user_id = $1 OR expire_at < $2;", userId, time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	challengeId, err := generateChallengeId()
+	if err != nil {
+		return "", err
+	}
+	query := "INSERT INTO login_challenges (challenge_id, user_id, expire_at) VALUES ($1, $2, $3);"
+	_, err = handler.DB.Exec(context.Background(), query, challengeId, userId, time.Now().Add(loginChallengeTTL))
+	if err != nil {
+		return "", fmt.Errorf("could not create login challenge: %w", err)
+	}
+	return challengeId, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or one of the unused recovery codes.
+// A matched recovery code is consumed.
+func (handler *UserDBHandler) verifySecondFactor(userId int, code string) error {
+	state, err := handler.getTwoFactorState(userId)
+	if err != nil {
+		return err
+	}
+	if !state.Enabled {
+		return &TwoFactorNotEnabledError{}
+	}
+	if err := handler.countTwoFactorAttempt(userId); err != nil {
+		return err
+	}
+
+	step, ok := validateTotpCode(state.Secret, code, time.Now(), state.LastUsedStep)
+	if ok {
+		_, err = handler.DB.Exec(context.Background(), "UPDATE users SET totp_last_step = $1, totp_failures = 0, totp_locked_until = NULL WHERE id = $2;", step, userId)
+		return err
+	}
+
+	used, err := handler.useRecoveryCode(userId, code)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return &TwoFactorCodeIncorrectError{}
+	}
+	_, err = handler.DB.Exec(context.Background(), "UPDATE users SET totp_failures = 0, totp_locked_until = NULL WHERE id = $1;", userId)
+	return err
+}
+
+// countTwoFactorAttempt counts an attempt as failed until the code turns out to be right, which
+// resets the count. The user row stays locked meanwhile, so parallel attempts are counted one by one.
+func (handler *UserDBHandler) countTwoFactorAttempt(userId int) error {
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	var failures int
+	var lockedUntil *time.Time
+	err = tx.QueryRow(context.Background(), "SELECT totp_failures, totp_locked_until FROM users WHERE id = $1 FOR UPDATE;", userId).Scan(&failures, &lockedUntil)
+	if err != nil {
+		return fmt.Errorf("error getting two-factor failures of user '%d': %w", userId, err)
+	}
+	now := time.Now()
+	if lockedUntil != nil && lockedUntil.After(now) {
+		return &TwoFactorLockedError{Until: *lockedUntil}
+	}
+
+	failures++
+	_, err = tx.Exec(context.Background(), "UPDATE users SET totp_failures = $1, totp_locked_until = $2 WHERE id = $3;", failures, twoFactorLockedUntil(failures, now), userId)
+	if err != nil {
+		return fmt.Errorf("could not count two-factor attempt: %w", err)
+	}
+	return tx.Commit(context.Background())
+}
+
+// twoFactorLockedUntil returns until when the second factor is locked after the given number of
+// failures in a row, nil while the failures are below twoFactorFreeFailures
+func twoFactorLockedUntil(failures int, now time.Time) *time.Time {
+	if failures < twoFactorFreeFailures {
+		return nil
+	}
+	lockout := twoFactorMaxLockout
+	if shift := failures - twoFactorFreeFailures; shift < 16 {
+		lockout = min(twoFactorFirstLockout<<shift, twoFactorMaxLockout)
+	}
+	until := now.Add(lockout)
+	return &until
+}
+
+func (handler *UserDBHandler) useRecoveryCode(userId int, code string) (bool, error) {
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return false, nil
+	}
+
+	rows, err := handler.DB.Query(context.Background(), "SELECT id, code_hash FROM recovery_codes WHERE user_id = $1;", userId)
+	if err != nil {
+		return false, fmt.Errorf("error querying recovery codes: %w", err)
+	}
+	defer rows.Close()
+
+	matchedId := -1
+	for rows.Next() {
+		var id int
+		var codeHash string
+		if err := rows.Scan(&id, &codeHash); err != nil {
+			return false, err
+		}
+		if common.IsPasswordCorrect(codeHash, code) {
+			matchedId = id
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("row iteration error: %w", err)
+	}
+	rows.Close()
+
+	if matchedId == -1 {
+		return false, nil
+	}
+	_, err = handler.DB.Exec(context.Background(), "DELETE FROM recovery_codes WHERE id = $1;", matchedId)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (handler *UserDBHandler) getTwoFactorState(userId int) (*twoFactorState, error) {
+	var state twoFactorState
+	err := handler.DB.QueryRow(context.Background(), "SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE id = $1;", userId).Scan(&state.Secret, &state.Enabled, &state.LastUsedStep)
+	if err != nil {
+		return nil, fmt.Errorf("error getting two-factor state of user '%d': %w", userId, err)
+	}
+	return &state, nil
+}
+
+func replaceRecoveryCodes(tx pgx.Tx, userId int) ([]string, error) {
+	_, err := tx.Exec(context.Background(), "DELETE FROM recovery_codes WHERE user_id = $1;", userId)
+	if err != nil {
+		return nil, fmt.Errorf("could not delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codeHash, err := common.CreatePasswordHash(normalizeRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.Exec(context.Background(), "INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2);", userId, codeHash)
+		if err != nil {
+			return nil, fmt.Errorf("could not store recovery code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	random := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("could not generate recovery code: %w", err)
+	}
+	code := make([]byte, recoveryCodeLength)
+	for i, b := range random {
+		code[i] = recoveryCodeLetters[int(b)%len(recoveryCodeLetters)]
+	}
+	half := recoveryCodeLength / 2
+	return fmt.Sprintf("%s-%s", code[:half], code[half:]), nil
+}
+
+func generateChallengeId() (string, error) {
+	random := make([]byte, loginChallengeIdLength)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("could not generate login challenge: %w", err)
+	}
+	return hex.EncodeToString(random), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
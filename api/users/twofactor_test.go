@@ -0,0 +1,45 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTwoFactorLockout(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		failures int
+		lockout  time.Duration
+	}{
+		{1, 0},
+		{twoFactorFreeFailures - 1, 0},
+		{twoFactorFreeFailures, time.Minute},
+		{twoFactorFreeFailures + 1, 2 * time.Minute},
+		{twoFactorFreeFailures + 5, 32 * time.Minute},
+		{twoFactorFreeFailures + 6, time.Hour},
+		{twoFactorFreeFailures + 100, time.Hour},
+	}
+	for _, test := range tests {
+		until := twoFactorLockedUntil(test.failures, now)
+		if test.lockout == 0 {
+			if until != nil {
+				t.Errorf("%d failures: expected no lockout, got %v", test.failures, until)
+			}
+			continue
+		}
+		if until == nil || until.Sub(now) != test.lockout {
+			t.Errorf("%d failures: expected lockout of %v, got %v", test.failures, test.lockout, until)
+		}
+	}
+}
+
+func TestChallengeIdsAreRandom(t *testing.T) {
+	first, err := generateChallengeId()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := generateChallengeId()
+	if len(first) != 2*loginChallengeIdLength || first == second {
+		t.Errorf("expected two different ids of %d characters, got '%s' and '%s'", 2*loginChallengeIdLength, first, second)
+	}
+}
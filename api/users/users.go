@@ -2,8 +2,12 @@ package users
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
+	"os"
+	"qr-pastebin-api/blob"
 	"qr-pastebin-api/common"
+	"qr-pastebin-api/mail"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -14,18 +18,26 @@ type UserCredentials struct {
 	Password string `json:"password"`
 	Id       *int   `json:"id,omitempty"`
 	IsOauth  *bool  `json:"isOauth,omitempty"`
+	Email    string `json:"email,omitempty"`
 }
 
 type SessionData struct {
-	SessionId string `json:"sessionId"`
+	SessionId         string `json:"sessionId,omitempty"`
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	ChallengeId       string `json:"challengeId,omitempty"`
+	// UserId is who logged in, for the audit log
+	UserId int `json:"-"`
 }
 
 type UserDBHandler struct {
-	DB *pgx.Conn
+	DB     *pgx.Conn
+	Mailer mail.Mailer
+	// Blobs holds the attachments of shares deleted together with their author
+	Blobs blob.Storage
 }
 
-func NewUserHandler(db *pgx.Conn) *UserDBHandler {
-	return &UserDBHandler{DB: db}
+func NewUserHandler(db *pgx.Conn, mailer mail.Mailer, blobs blob.Storage) *UserDBHandler {
+	return &UserDBHandler{DB: db, Mailer: mailer, Blobs: blobs}
 }
 
 func (handler *UserDBHandler) CreateUser(request UserCredentials) error {
@@ -34,11 +46,22 @@ func (handler *UserDBHandler) CreateUser(request UserCredentials) error {
 		return &UserAlreadyExistsError{}
 	}
 
+	email := ""
+	if request.Email != "" {
+		email, err = normalizeEmail(request.Email)
+		if err != nil {
+			return err
+		}
+		if err := handler.ensureEmailIsFree(email, -1); err != nil {
+			return err
+		}
+	}
+
 	hashedPassword, err := common.CreatePasswordHash(request.Password)
 	if err != nil {
 		return err
 	}
-	query := "INSERT INTO users (id, name, passwordHash, role, isoauth) VALUES ($1, $2, $3, $4, $5);"
+	query := "INSERT INTO users (id, name, passwordHash, role, isoauth, email) VALUES ($1, $2, $3, $4, $5, $6);"
 
 	var id int
 	if request.Id != nil {
@@ -54,10 +77,17 @@ func (handler *UserDBHandler) CreateUser(request UserCredentials) error {
 		isOauth = false
 	}
 
-	_, err = handler.DB.Exec(context.Background(), query, id, request.Name, hashedPassword, 0, isOauth)
+	_, err = handler.DB.Exec(context.Background(), query, id, request.Name, hashedPassword, common.USER, isOauth, email)
 	if err != nil {
 		return err
 	}
+
+	// The account exists at this point, a failed mail is sent again with POST /user/email/verification
+	if email != "" {
+		if err := handler.sendVerificationEmail(id, email); err != nil {
+			fmt.Fprintf(os.Stderr, "could not send verification email to user '%d': %v\n", id, err)
+		}
+	}
 	return nil
 }
 
@@ -97,34 +127,60 @@ func (handler *UserDBHandler) CreateSession(request UserCredentials) (*SessionDa
 		if !passwordOk {
 			return nil, &common.PasswordIncorrectError{}
 		}
+
+		// Password is correct, but the session is only issued after the second factor is verified
+		twoFactor, err := handler.getTwoFactorState(user.Id)
+		if err != nil {
+			return nil, err
+		}
+		if twoFactor.Enabled {
+			challengeId, err := handler.createLoginChallenge(user.Id)
+			if err != nil {
+				return nil, err
+			}
+			return &SessionData{TwoFactorRequired: true, ChallengeId: challengeId, UserId: user.Id}, nil
+		}
+	}
+
+	return handler.issueSession(user.Id)
+}
+
+func (handler *UserDBHandler) issueSession(userId int) (*SessionData, error) {
+	user, err := common.GetUserById(handler.DB, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, &AccountDisabledError{}
 	}
 
 	// Try get active session for this user
-	sessionId, err := handler.getActiveSession(user.Id)
+	sessionId, err := handler.getActiveSession(userId)
 	if err == nil {
-		return &SessionData{SessionId: sessionId}, nil
+		return &SessionData{SessionId: sessionId, UserId: userId}, nil
 	}
 
 	// If no active session, then clean all expired sessions
-	err = handler.deleteSessions(user.Id)
+	err = handler.deleteSessions(userId)
 	if err != nil {
 		return nil, err
 	}
 
 	// Create a new session for this user
-	sessionId, err = handler.createNewSession(user.Id)
+	sessionId, err = handler.createNewSession(userId)
 	if err != nil {
 		return nil, err
 	}
-	return &SessionData{SessionId: sessionId}, nil
+	return &SessionData{SessionId: sessionId, UserId: userId}, nil
 }
 
 func (handler *UserDBHandler) GetUserFromSession(sessionId string) (*common.User, error) {
 	var user common.User
-	err := handler.DB.QueryRow(context.Background(), "SELECT u.id, u.name, u.passwordHash, u.role FROM users AS u RIGHT JOIN sessions AS s ON u.id = s.user_id WHERE expire_at > $1 AND s.session_id = $2;", time.Now(), sessionId).Scan(&user.Id, &user.Name, &user.PasswordHash, &user.Role)
+	err := handler.DB.QueryRow(context.Background(), "SELECT u.id, u.name, u.passwordHash, u.role, u.disabled FROM users AS u RIGHT JOIN sessions AS s ON u.id = s.user_id WHERE expire_at > $1 AND s.session_id = $2;", time.Now(), sessionId).Scan(&user.Id, &user.Name, &user.PasswordHash, &user.Role, &user.Disabled)
 	if err != nil {
 		return nil, err
 	}
+	user.Permissions = user.Role.Permissions()
 	return &user, nil
 }
 
@@ -0,0 +1,339 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	SIGNATURE_HEADER = "X-Webhook-Signature"
+	EVENT_HEADER     = "X-Webhook-Event"
+	DELIVERY_HEADER  = "X-Webhook-Delivery"
+)
+
+const (
+	// maxAttempts is how often a delivery is tried before it is marked as failed, with the backoff
+	// below the last attempt happens about eight and a half hours after the first
+	maxAttempts       = 10
+	firstRetryDelay   = 30 * time.Second
+	maxRetryDelay     = 6 * time.Hour
+	deliveryTimeout   = 10 * time.Second
+	dispatchBatchSize = 20
+	parallelSends     = 10
+	// A claimed delivery isn't picked up by other instances until its lease runs out
+	claimLease = time.Minute
+	// Shares that expired longer ago than this, e.g. while the API was down, don't cause an event
+	expirySweepWindow = 24 * time.Hour
+	maxResponseLength = 1024
+)
+
+// Dispatcher sends due deliveries from the outbox and retries failed ones with exponential backoff.
+// Several API instances can run one each, deliveries are claimed before they are sent.
+type Dispatcher struct {
+	handler *WebhookDBHandler
+	client  *http.Client
+}
+
+// NewDispatcher delivers with a database connection of its own, Run has to be started in a goroutine.
+// Endpoints on loopback and private addresses are refused unless allowPrivate is set.
+func NewDispatcher(db *pgx.Conn, allowPrivate bool) *Dispatcher {
+	return &Dispatcher{handler: NewWebhookHandler(db), client: newDeliveryClient(allowPrivate)}
+}
+
+// NewDispatcherFromEnv allows private endpoints when WEBHOOK_ALLOW_PRIVATE_ADDRESSES is true,
+// which is only meant for local development
+func NewDispatcherFromEnv(db *pgx.Conn) *Dispatcher {
+	allowPrivate, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_PRIVATE_ADDRESSES"))
+	return NewDispatcher(db, allowPrivate)
+}
+
+// Run queues events of expired shares and sends due deliveries whenever the interval has passed
+func (dispatcher *Dispatcher) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := dispatcher.handler.enqueueExpired(time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "error queuing webhooks of expired shares: %v\n", err)
+		}
+		for {
+			sent, err := dispatcher.dispatchDue(time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error sending webhooks: %v\n", err)
+			}
+			if sent < dispatchBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// pendingDelivery is a claimed delivery together with the webhook it goes to
+type pendingDelivery struct {
+	id       int64
+	event    string
+	payload  string
+	attempts int
+	url      string
+	secret   string
+}
+
+type attempt struct {
+	at         time.Time
+	statusCode int
+	err        string
+	response   string
+	duration   time.Duration
+}
+
+func (a attempt) succeeded() bool {
+	return a.err == ""
+}
+
+// dispatchDue sends a batch of due deliveries and reports how many it claimed
+func (dispatcher *Dispatcher) dispatchDue(now time.Time) (int, error) {
+	deliveries, err := dispatcher.handler.claimDue(now)
+	if err != nil {
+		return 0, err
+	}
+
+	attempts := make([]attempt, len(deliveries))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelSends)
+	for i, delivery := range deliveries {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			attempts[i] = dispatcher.send(delivery, time.Now())
+			<-slots
+		}()
+	}
+	wg.Wait()
+
+	// The connection isn't safe for concurrent use, so results are stored one after another
+	for i, delivery := range deliveries {
+		if err := dispatcher.handler.recordAttempt(delivery, attempts[i]); err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (dispatcher *Dispatcher) send(delivery pendingDelivery, now time.Time) attempt {
+	result := attempt{at: now}
+	request, err := http.NewRequest(http.MethodPost, delivery.url, strings.NewReader(delivery.payload))
+	if err != nil {
+		result.err = err.Error()
+		return result
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "qr-pastebin-webhooks")
+	request.Header.Set(EVENT_HEADER, delivery.event)
+	request.Header.Set(DELIVERY_HEADER, strconv.FormatInt(delivery.id, 10))
+	request.Header.Set(SIGNATURE_HEADER, Sign(delivery.secret, now, []byte(delivery.payload)))
+
+	response, err := dispatcher.client.Do(request)
+	result.duration = time.Since(now)
+	if err != nil {
+		result.err = err.Error()
+		return result
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxResponseLength))
+	result.statusCode = response.StatusCode
+	result.response = string(bytes.ToValidUTF8(body, nil))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		result.err = fmt.Sprintf("endpoint answered with status %d", response.StatusCode)
+	}
+	return result
+}
+
+// retryDelay is the time to wait after the given number of failed attempts, doubling every time
+func retryDelay(failedAttempts int) time.Duration {
+	delay := firstRetryDelay
+	for i := 1; i < failedAttempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// Sign returns the value of the signature header for a payload sent at the given time:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of '<unix seconds>.<payload>'>". The time is signed too,
+// so receivers can refuse old deliveries that are replayed.
+func Sign(secret string, at time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, signature(secret, timestamp, payload))
+}
+
+// Verify checks a signature header made by Sign, refusing signatures older than tolerance
+func Verify(secret string, header string, payload []byte, now time.Time, tolerance time.Duration) bool {
+	var timestamp, signed string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signed = value
+		}
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signed == "" {
+		return false
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signed), []byte(signature(secret, timestamp, payload)))
+}
+
+func signature(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryClient doesn't follow redirects or use proxies, and checks the address it connects to
+// after name resolution, so webhooks can't be pointed at services inside our own network
+func newDeliveryClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: deliveryTimeout}
+	if !allowPrivate {
+		dialer.Control = func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddress(net.ParseIP(host)) {
+				return fmt.Errorf("webhook endpoint address %s is not public", host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout:   deliveryTimeout,
+		Transport: &http.Transport{Proxy: nil, DialContext: dialer.DialContext},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPublicAddress(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsLinkLocalUnicast() &&
+		!ip.IsMulticast() && !sharedAddressSpace.Contains(ip)
+}
+
+// claimDue leases a batch of due deliveries, so other instances skip them while they are sent
+func (handler *WebhookDBHandler) claimDue(now time.Time) ([]pendingDelivery, error) {
+	query := `WITH claimed AS (
+			UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id IN (
+				SELECT id FROM webhook_deliveries WHERE status = $2 AND next_attempt_at <= $3
+				ORDER BY next_attempt_at LIMIT $4 FOR UPDATE SKIP LOCKED)
+			RETURNING id, webhook_id, event, payload, attempts)
+		SELECT c.id, c.event, c.payload, c.attempts, w.url, w.secret FROM claimed AS c JOIN webhooks AS w ON w.id = c.webhook_id;`
+	rows, err := handler.DB.Query(context.Background(), query, now.Add(claimLease), DELIVERY_PENDING, now, dispatchBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]pendingDelivery, 0)
+	for rows.Next() {
+		var delivery pendingDelivery
+		if err := rows.Scan(&delivery.id, &delivery.event, &delivery.payload, &delivery.attempts, &delivery.url, &delivery.secret); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return deliveries, nil
+}
+
+// recordAttempt writes the attempt to the delivery log and schedules the next one when it failed
+func (handler *WebhookDBHandler) recordAttempt(delivery pendingDelivery, result attempt) error {
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	query := "INSERT INTO webhook_attempts (delivery_id, attempted_at, status_code, error, response, duration_ms) VALUES ($1, $2, $3, $4, $5, $6);"
+	_, err = tx.Exec(context.Background(), query, delivery.id, result.at, result.statusCode, result.err, result.response, result.duration.Milliseconds())
+	if err != nil {
+		return fmt.Errorf("couldn't log attempt of delivery '%d': %w", delivery.id, err)
+	}
+
+	attempts := delivery.attempts + 1
+	status, nextAttemptAt, deliveredAt := DELIVERY_PENDING, result.at.Add(retryDelay(attempts)), time.Time{}
+	if result.succeeded() {
+		status, deliveredAt = DELIVERY_DELIVERED, result.at
+	} else if attempts >= maxAttempts {
+		status = DELIVERY_FAILED
+	}
+	query = "UPDATE webhook_deliveries SET status = $2, attempts = $3, next_attempt_at = $4, delivered_at = $5 WHERE id = $1;"
+	_, err = tx.Exec(context.Background(), query, delivery.id, status, attempts, nextAttemptAt, deliveredAt)
+	if err != nil {
+		return fmt.Errorf("couldn't update delivery '%d': %w", delivery.id, err)
+	}
+	return tx.Commit(context.Background())
+}
+
+// enqueueExpired queues share.expired for shares that expired recently. The event id is derived from
+// the share, so the sweep can see which shares were already handled, also by other instances.
+func (handler *WebhookDBHandler) enqueueExpired(now time.Time) error {
+	query := `SELECT s.id, s.expire_at, w.id FROM shares AS s JOIN webhooks AS w ON w.user_id = s.author_id
+		WHERE s.expire_at <= $1 AND s.expire_at > $2 AND s.expire_at > w.created_at AND $3 = ANY(w.events)
+		AND NOT EXISTS (SELECT 1 FROM webhook_deliveries AS d WHERE d.webhook_id = w.id AND d.event_id = $4 || s.id);`
+	rows, err := handler.DB.Query(context.Background(), query, now, now.Add(-expirySweepWindow), EVENT_SHARE_EXPIRED, expiredEventPrefix)
+	if err != nil {
+		return fmt.Errorf("error querying expired shares: %w", err)
+	}
+	defer rows.Close()
+
+	box := newOutbox()
+	for rows.Next() {
+		var event Event
+		var webhookId int
+		if err := rows.Scan(&event.ShareId, &event.At, &webhookId); err != nil {
+			return err
+		}
+		event.Id = expiredEventPrefix + event.ShareId
+		event.Type = EVENT_SHARE_EXPIRED
+		if err := box.add(webhookId, event); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %w", err)
+	}
+	return handler.insertDeliveries(box, now)
+}
+
+const expiredEventPrefix = "exp_"
@@ -0,0 +1,154 @@
+package webhooks
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignatureVerifies(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","event":"share.viewed","shareId":"abc1234"}`)
+	at := time.Date(2030, 1, 31, 12, 0, 0, 0, time.UTC)
+	header := Sign("whsec_secret", at, payload)
+
+	if !strings.HasPrefix(header, "t=1896091200,v1=") {
+		t.Errorf("unexpected signature header %q", header)
+	}
+	if !Verify("whsec_secret", header, payload, at.Add(time.Minute), 5*time.Minute) {
+		t.Error("expected the signature to verify")
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+	}{
+		{"other secret", "whsec_other", header, payload, at},
+		{"changed payload", "whsec_secret", header, []byte(`{"id":"evt_2"}`), at},
+		{"too old", "whsec_secret", header, payload, at.Add(10 * time.Minute)},
+		{"from the future", "whsec_secret", header, payload, at.Add(-10 * time.Minute)},
+		{"missing signature", "whsec_secret", "t=1896091200", payload, at},
+		{"garbage", "whsec_secret", "nonsense", payload, at},
+	}
+	for _, test := range tests {
+		if Verify(test.secret, test.header, test.payload, test.now, 5*time.Minute) {
+			t.Errorf("%s: expected the signature to be refused", test.name)
+		}
+	}
+}
+
+func TestRetryDelayDoubles(t *testing.T) {
+	tests := []struct {
+		failedAttempts int
+		want           time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{9, 128 * time.Minute},
+		{10, 256 * time.Minute},
+		{20, maxRetryDelay},
+	}
+	for _, test := range tests {
+		if got := retryDelay(test.failedAttempts); got != test.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", test.failedAttempts, got, test.want)
+		}
+	}
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, test := range tests {
+		if got := isPublicAddress(net.ParseIP(test.ip)); got != test.want {
+			t.Errorf("isPublicAddress(%s) = %v, want %v", test.ip, got, test.want)
+		}
+	}
+}
+
+func TestSendSignsPayload(t *testing.T) {
+	payload := `{"id":"evt_1","event":"share.unlocked","shareId":"abc1234"}`
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.Write([]byte("thanks"))
+	}))
+	defer server.Close()
+
+	dispatcher := &Dispatcher{client: newDeliveryClient(true)}
+	delivery := pendingDelivery{id: 7, event: EVENT_SHARE_UNLOCKED, payload: payload, url: server.URL, secret: "whsec_secret"}
+	result := dispatcher.send(delivery, time.Now())
+
+	if !result.succeeded() || result.statusCode != http.StatusOK || result.response != "thanks" {
+		t.Fatalf("expected a successful attempt, got %+v", result)
+	}
+	if string(body) != payload {
+		t.Errorf("expected the stored payload to be sent, got %s", body)
+	}
+	if received.Header.Get(EVENT_HEADER) != EVENT_SHARE_UNLOCKED || received.Header.Get(DELIVERY_HEADER) != "7" {
+		t.Errorf("unexpected headers %v", received.Header)
+	}
+	if !Verify("whsec_secret", received.Header.Get(SIGNATURE_HEADER), body, time.Now(), time.Minute) {
+		t.Error("expected a valid signature")
+	}
+}
+
+func TestSendFailsWithoutSuccessStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	dispatcher := &Dispatcher{client: newDeliveryClient(true)}
+	for path, want := range map[string]int{"/": http.StatusServiceUnavailable, "/moved": http.StatusFound} {
+		result := dispatcher.send(pendingDelivery{payload: "{}", url: server.URL + path}, time.Now())
+		if result.succeeded() || result.statusCode != want {
+			t.Errorf("%s: expected a failed attempt with status %d, got %+v", path, want, result)
+		}
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	dispatcher := &Dispatcher{client: newDeliveryClient(false)}
+	result := dispatcher.send(pendingDelivery{payload: "{}", url: server.URL}, time.Now())
+	if result.succeeded() || result.statusCode != 0 || !strings.Contains(result.err, "not public") {
+		t.Errorf("expected the loopback endpoint to be refused, got %+v", result)
+	}
+	if called {
+		t.Error("expected no request to reach the endpoint")
+	}
+}
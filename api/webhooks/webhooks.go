@@ -0,0 +1,439 @@
+// Package webhooks lets users register endpoints that are told about events of their shares.
+// Events are written to an outbox table first and delivered by a Dispatcher in the background,
+// so a slow or unreachable endpoint never slows down the request that caused the event.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"qr-pastebin-api/common"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	EVENT_SHARE_CREATED  = "share.created"
+	EVENT_SHARE_UPDATED  = "share.updated"
+	EVENT_SHARE_VIEWED   = "share.viewed"
+	EVENT_SHARE_UNLOCKED = "share.unlocked"
+	EVENT_SHARE_EXPIRED  = "share.expired"
+	EVENT_SHARE_DELETED  = "share.deleted"
+)
+
+// EVENTS are sent to webhooks registered without a list of events
+var EVENTS = []string{EVENT_SHARE_CREATED, EVENT_SHARE_UPDATED, EVENT_SHARE_VIEWED, EVENT_SHARE_UNLOCKED, EVENT_SHARE_EXPIRED, EVENT_SHARE_DELETED}
+
+const (
+	DELIVERY_PENDING   = "pending"
+	DELIVERY_DELIVERED = "delivered"
+	DELIVERY_FAILED    = "failed"
+)
+
+const (
+	maxWebhooksPerUser      = 10
+	defaultDeliveryPageSize = 50
+	maxDeliveryPageSize     = 200
+)
+
+type WebhookRequest struct {
+	Url    string   `json:"url" binding:"required,http_url,max=2048"`
+	Events []string `json:"events" binding:"omitempty,dive,oneof=share.created share.updated share.viewed share.unlocked share.expired share.deleted"`
+}
+
+// WebhookResponse carries the secret only when the webhook is created
+type WebhookResponse struct {
+	Id        int       `json:"id"`
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type DeliveryRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending delivered failed"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+}
+
+type DeliveryResponse struct {
+	Id             int64             `json:"id"`
+	WebhookId      int               `json:"webhookId"`
+	EventId        string            `json:"eventId"`
+	Event          string            `json:"event"`
+	Status         string            `json:"status"`
+	Attempts       int               `json:"attempts"`
+	NextAttemptAt  *time.Time        `json:"nextAttemptAt"`
+	DeliveredAt    *time.Time        `json:"deliveredAt"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	LastStatusCode int               `json:"lastStatusCode,omitempty"`
+	LastError      string            `json:"lastError,omitempty"`
+	Payload        *Event            `json:"payload,omitempty"`
+	Log            []AttemptResponse `json:"log,omitempty"`
+}
+
+// AttemptResponse is one entry of the delivery log. StatusCode is 0 when the endpoint couldn't be reached.
+type AttemptResponse struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode"`
+	Error      string    `json:"error,omitempty"`
+	Response   string    `json:"response,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Event is the JSON body of a delivery. Agent and FromQr are only set for share.viewed.
+type Event struct {
+	Id      string    `json:"id"`
+	Type    string    `json:"event"`
+	At      time.Time `json:"at"`
+	ShareId string    `json:"shareId"`
+	Agent   string    `json:"agent,omitempty"`
+	FromQr  bool      `json:"fromQr,omitempty"`
+}
+
+type WebhookDBHandler struct {
+	DB *pgx.Conn
+}
+
+func NewWebhookHandler(db *pgx.Conn) *WebhookDBHandler {
+	return &WebhookDBHandler{DB: db}
+}
+
+// CreateWebhook registers an endpoint of the user and returns it with its signing secret, which
+// isn't shown again
+func (handler *WebhookDBHandler) CreateWebhook(userId int, request WebhookRequest) (*WebhookResponse, error) {
+	var count int
+	err := handler.DB.QueryRow(context.Background(), "SELECT COUNT(*) FROM webhooks WHERE user_id = $1;", userId).Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxWebhooksPerUser {
+		return nil, &common.ValidationError{Fields: []common.FieldError{{
+			Field:   "url",
+			Rule:    "max_webhooks",
+			Message: fmt.Sprintf("at most %d webhooks can be registered", maxWebhooksPerUser),
+		}}}
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	events := subscribedEvents(request.Events)
+
+	response := WebhookResponse{Url: request.Url, Events: events, Secret: secret, CreatedAt: time.Now()}
+	query := "INSERT INTO webhooks (user_id, url, secret, events, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id;"
+	err = handler.DB.QueryRow(context.Background(), query, userId, response.Url, secret, events, response.CreatedAt).Scan(&response.Id)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create webhook: %w", err)
+	}
+	return &response, nil
+}
+
+func (handler *WebhookDBHandler) GetWebhooks(userId int) ([]WebhookResponse, error) {
+	rows, err := handler.DB.Query(context.Background(), "SELECT id, url, events, created_at FROM webhooks WHERE user_id = $1 ORDER BY id;", userId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]WebhookResponse, 0)
+	for rows.Next() {
+		var webhook WebhookResponse
+		if err := rows.Scan(&webhook.Id, &webhook.Url, &webhook.Events, &webhook.CreatedAt); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook together with its outbox and delivery log
+func (handler *WebhookDBHandler) DeleteWebhook(webhookId int, userId int) error {
+	if err := handler.ensureOwner(webhookId, userId); err != nil {
+		return err
+	}
+
+	tx, err := handler.DB.Begin(context.Background())
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(context.Background())
+
+	queries := []string{
+		"DELETE FROM webhook_attempts WHERE delivery_id IN (SELECT id FROM webhook_deliveries WHERE webhook_id = $1);",
+		"DELETE FROM webhook_deliveries WHERE webhook_id = $1;",
+		"DELETE FROM webhooks WHERE id = $1;",
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(context.Background(), query, webhookId); err != nil {
+			return fmt.Errorf("could not delete webhook '%d': %w", webhookId, err)
+		}
+	}
+	return tx.Commit(context.Background())
+}
+
+// GetDeliveries returns a page of deliveries of the webhook, newest first
+func (handler *WebhookDBHandler) GetDeliveries(webhookId int, userId int, request DeliveryRequest) ([]DeliveryResponse, error) {
+	if err := handler.ensureOwner(webhookId, userId); err != nil {
+		return nil, err
+	}
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultDeliveryPageSize
+	}
+	limit = min(limit, maxDeliveryPageSize)
+	offset := max(request.Offset, 0)
+
+	query := fmt.Sprintf("SELECT %s FROM webhook_deliveries AS d%s WHERE d.webhook_id = $1 AND ($2 = '' OR d.status = $2) ORDER BY d.id DESC LIMIT $3 OFFSET $4;", deliveryColumns, lastAttemptJoin)
+	rows, err := handler.DB.Query(context.Background(), query, webhookId, request.Status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]DeliveryResponse, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetDelivery returns a delivery with its payload and the log of every attempt to send it
+func (handler *WebhookDBHandler) GetDelivery(webhookId int, deliveryId int64, userId int) (*DeliveryResponse, error) {
+	if err := handler.ensureOwner(webhookId, userId); err != nil {
+		return nil, err
+	}
+
+	var payload string
+	query := fmt.Sprintf("SELECT %s, d.payload FROM webhook_deliveries AS d%s WHERE d.webhook_id = $1 AND d.id = $2;", deliveryColumns, lastAttemptJoin)
+	delivery, err := scanDelivery(handler.DB.QueryRow(context.Background(), query, webhookId, deliveryId), &payload)
+	if err == pgx.ErrNoRows {
+		return nil, &common.NotFoundError{}
+	}
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = &Event{}
+	if err := json.Unmarshal([]byte(payload), delivery.Payload); err != nil {
+		return nil, fmt.Errorf("payload of delivery '%d' is not valid: %w", deliveryId, err)
+	}
+
+	rows, err := handler.DB.Query(context.Background(), "SELECT attempted_at, status_code, error, response, duration_ms FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id;", deliveryId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook attempts: %w", err)
+	}
+	defer rows.Close()
+
+	delivery.Log = make([]AttemptResponse, 0)
+	for rows.Next() {
+		var attempt AttemptResponse
+		if err := rows.Scan(&attempt.At, &attempt.StatusCode, &attempt.Error, &attempt.Response, &attempt.DurationMs); err != nil {
+			return nil, err
+		}
+		delivery.Log = append(delivery.Log, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return delivery, nil
+}
+
+// Redeliver queues a delivery to be sent again right away with the same payload, whatever its status.
+// Attempts start from zero again, the log of earlier attempts is kept.
+func (handler *WebhookDBHandler) Redeliver(webhookId int, deliveryId int64, userId int) error {
+	if err := handler.ensureOwner(webhookId, userId); err != nil {
+		return err
+	}
+	query := "UPDATE webhook_deliveries SET status = $3, attempts = 0, next_attempt_at = $4 WHERE webhook_id = $1 AND id = $2;"
+	tag, err := handler.DB.Exec(context.Background(), query, webhookId, deliveryId, DELIVERY_PENDING, time.Now())
+	if err != nil {
+		return fmt.Errorf("could not redeliver '%d': %w", deliveryId, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &common.NotFoundError{}
+	}
+	return nil
+}
+
+// Enqueue writes a delivery of every event to the outbox of each webhook of the share's author
+// that subscribed to it. Shares without an author have no webhooks.
+func (handler *WebhookDBHandler) Enqueue(events ...Event) error {
+	box, err := handler.Prepare(events...)
+	if err != nil {
+		return err
+	}
+	return handler.EnqueuePrepared(box)
+}
+
+// Prepare builds the deliveries of the events without queuing them yet. Webhooks are found through
+// the author of the share, so events about a share that is about to be deleted are prepared while it
+// still exists and queued with EnqueuePrepared once it is gone.
+func (handler *WebhookDBHandler) Prepare(events ...Event) (*Outbox, error) {
+	outbox := newOutbox()
+	if len(events) == 0 {
+		return outbox, nil
+	}
+	shareIds := make([]string, 0, len(events))
+	for _, event := range events {
+		shareIds = append(shareIds, event.ShareId)
+	}
+
+	query := "SELECT s.id, w.id, w.events FROM shares AS s JOIN webhooks AS w ON w.user_id = s.author_id WHERE s.id = ANY($1);"
+	rows, err := handler.DB.Query(context.Background(), query, shareIds)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhooks of shares: %w", err)
+	}
+	defer rows.Close()
+
+	subscribers := map[string][]subscriber{}
+	for rows.Next() {
+		var shareId string
+		var webhook subscriber
+		if err := rows.Scan(&shareId, &webhook.id, &webhook.events); err != nil {
+			return nil, err
+		}
+		subscribers[shareId] = append(subscribers[shareId], webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	for _, event := range events {
+		if event.Id == "" {
+			event.Id = "evt_" + common.CreateRandomId(16)
+		}
+		for _, webhook := range subscribers[event.ShareId] {
+			if !slices.Contains(webhook.events, event.Type) {
+				continue
+			}
+			if err := outbox.add(webhook.id, event); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return outbox, nil
+}
+
+// EnqueuePrepared queues the deliveries built by Prepare
+func (handler *WebhookDBHandler) EnqueuePrepared(box *Outbox) error {
+	return handler.insertDeliveries(box, time.Now())
+}
+
+type subscriber struct {
+	id     int
+	events []string
+}
+
+// outbox collects deliveries column by column, so they are inserted with a single statement
+// Outbox holds deliveries that are not queued yet
+type Outbox struct {
+	webhookIds []int
+	eventIds   []string
+	events     []string
+	payloads   []string
+}
+
+func newOutbox() *Outbox {
+	return &Outbox{}
+}
+
+func (box *Outbox) add(webhookId int, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	box.webhookIds = append(box.webhookIds, webhookId)
+	box.eventIds = append(box.eventIds, event.Id)
+	box.events = append(box.events, event.Type)
+	box.payloads = append(box.payloads, string(payload))
+	return nil
+}
+
+// insertDeliveries skips events a webhook already got, so queuing the same event twice is harmless
+func (handler *WebhookDBHandler) insertDeliveries(box *Outbox, now time.Time) error {
+	if len(box.webhookIds) == 0 {
+		return nil
+	}
+	query := `INSERT INTO webhook_deliveries (webhook_id, event_id, event, payload, status, next_attempt_at, created_at)
+		SELECT u.webhook_id, u.event_id, u.event, u.payload, $5, $6, $6
+		FROM unnest($1::int[], $2::text[], $3::text[], $4::text[]) AS u(webhook_id, event_id, event, payload)
+		ON CONFLICT (webhook_id, event_id) DO NOTHING;`
+	_, err := handler.DB.Exec(context.Background(), query, box.webhookIds, box.eventIds, box.events, box.payloads, DELIVERY_PENDING, now)
+	if err != nil {
+		return fmt.Errorf("couldn't queue %d webhook deliveries: %w", len(box.webhookIds), err)
+	}
+	return nil
+}
+
+func (handler *WebhookDBHandler) ensureOwner(webhookId int, userId int) error {
+	var count int
+	err := handler.DB.QueryRow(context.Background(), "SELECT COUNT(*) FROM webhooks WHERE id = $1 AND user_id = $2;", webhookId, userId).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return &common.NotFoundError{}
+	}
+	return nil
+}
+
+const deliveryColumns = "d.id, d.webhook_id, d.event_id, d.event, d.status, d.attempts, d.next_attempt_at, d.delivered_at, d.created_at, COALESCE(a.status_code, 0), COALESCE(a.error, '')"
+
+const lastAttemptJoin = " LEFT JOIN LATERAL (SELECT status_code, error FROM webhook_attempts WHERE delivery_id = d.id ORDER BY id DESC LIMIT 1) AS a ON true"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDelivery(row rowScanner, extra ...any) (*DeliveryResponse, error) {
+	var delivery DeliveryResponse
+	var nextAttemptAt, deliveredAt time.Time
+	columns := []any{&delivery.Id, &delivery.WebhookId, &delivery.EventId, &delivery.Event, &delivery.Status, &delivery.Attempts, &nextAttemptAt, &deliveredAt, &delivery.CreatedAt, &delivery.LastStatusCode, &delivery.LastError}
+	if err := row.Scan(append(columns, extra...)...); err != nil {
+		return nil, err
+	}
+	// Only pending deliveries wait for an attempt, deliveries not delivered yet store the zero time
+	if delivery.Status == DELIVERY_PENDING {
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+	if !deliveredAt.IsZero() {
+		delivery.DeliveredAt = &deliveredAt
+	}
+	return &delivery, nil
+}
+
+func subscribedEvents(events []string) []string {
+	if len(events) == 0 {
+		return EVENTS
+	}
+	subscribed := make([]string, 0, len(events))
+	for _, event := range events {
+		if !slices.Contains(subscribed, event) {
+			subscribed = append(subscribed, event)
+		}
+	}
+	return subscribed
+}
+
+func newSecret() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("could not generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(random), nil
+}
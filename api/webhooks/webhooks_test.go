@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSubscribedEvents(t *testing.T) {
+	if got := subscribedEvents(nil); !slices.Equal(got, EVENTS) {
+		t.Errorf("expected every event without a list, got %v", got)
+	}
+	got := subscribedEvents([]string{EVENT_SHARE_VIEWED, EVENT_SHARE_DELETED, EVENT_SHARE_VIEWED})
+	if !slices.Equal(got, []string{EVENT_SHARE_VIEWED, EVENT_SHARE_DELETED}) {
+		t.Errorf("expected duplicates to be dropped, got %v", got)
+	}
+}
+
+func TestOutboxKeepsEventsAsPayload(t *testing.T) {
+	box := newOutbox()
+	event := Event{Id: "evt_1", Type: EVENT_SHARE_VIEWED, At: time.Date(2030, 1, 31, 12, 0, 0, 0, time.UTC), ShareId: "abc1234", Agent: "mobile", FromQr: true}
+	if err := box.add(3, event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if box.webhookIds[0] != 3 || box.eventIds[0] != "evt_1" || box.events[0] != EVENT_SHARE_VIEWED {
+		t.Errorf("unexpected outbox row %+v", box)
+	}
+	want := `{"id":"evt_1","event":"share.viewed","at":"2030-01-31T12:00:00Z","shareId":"abc1234","agent":"mobile","fromQr":true}`
+	if box.payloads[0] != want {
+		t.Errorf("expected payload %s, got %s", want, box.payloads[0])
+	}
+
+	var decoded Event
+	if err := json.Unmarshal([]byte(box.payloads[0]), &decoded); err != nil || decoded != event {
+		t.Errorf("expected the payload to decode into the event, got %+v (%v)", decoded, err)
+	}
+}
+
+func TestNewSecretIsRandom(t *testing.T) {
+	first, err := newSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := newSecret()
+	if len(first) != len("whsec_")+64 || first == second {
+		t.Errorf("expected two different 32 byte secrets, got %s and %s", first, second)
+	}
+}
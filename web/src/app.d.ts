@@ -8,7 +8,8 @@ declare global {
 			user?: {
 				id: number;
 				name: string;
-				role: number;
+				role: string;
+				permissions: string[];
 			};
 		}
 		// interface PageData {}
@@ -11,7 +11,8 @@ export const handle: Handle = async ({ event, resolve }) => {
 			event.locals.user = {
 				id: user.id,
 				name: user.name,
-				role: user.role
+				role: user.role,
+				permissions: user.permissions ?? []
 			};
 		} catch (err) {
 			if (err instanceof Error) {
@@ -7,7 +7,8 @@ export interface ShareRequest {
 	password: string;
 	expireIn: string;
 	hideAuthor: boolean;
-	authorId: number;
+	authorId?: number;
+	visibility?: string;
 }
 
 interface CreateShareResponse {
@@ -22,6 +23,8 @@ export interface Share {
 	isPasswordProtected?: boolean;
 	authorName?: string;
 	hideAuthor: boolean;
+	visibility?: string;
+	access?: string;
 }
 
 export interface GetPasswordProtectedShareRequest {
@@ -42,19 +45,20 @@ export class WrongPasswordError extends Error {
 	}
 }
 
-export async function createShare(request: ShareRequest): Promise<string> {
+export async function createShare(request: ShareRequest, sessionId?: string): Promise<string> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/share`, {
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/share`, {
 			body: JSON.stringify(request),
 			headers: {
-				'Content-Type': 'application/json'
+				'Content-Type': 'application/json',
+				...optionalAuthHeaders(sessionId)
 			},
 			method: 'POST'
 		});
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error creating share ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error creating share ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		const parsedResponse: CreateShareResponse = await response.json();
@@ -67,13 +71,45 @@ export async function createShare(request: ShareRequest): Promise<string> {
 	}
 }
 
-export async function getShare(id: string): Promise<Share> {
+function optionalAuthHeaders(sessionId?: string): Record<string, string> {
+	return sessionId ? { Authorization: `Bearer ${sessionId}` } : {};
+}
+
+// Viewer is the visitor a share is loaded for, passed on so the API counts their view and not the website's
+export type Viewer = {
+	source?: string;
+	userAgent?: string;
+	referrer?: string;
+	address?: string;
+};
+
+function viewerHeaders(viewer?: Viewer): Record<string, string> {
+	const headers: Record<string, string> = {};
+	if (viewer?.userAgent) headers['User-Agent'] = viewer.userAgent;
+	if (viewer?.referrer) headers['Referer'] = viewer.referrer;
+	if (viewer?.address) headers['X-Forwarded-For'] = viewer.address;
+	return headers;
+}
+
+export async function getShare(
+	id: string,
+	sessionId?: string,
+	signedLink?: URLSearchParams,
+	viewer?: Viewer
+): Promise<Share> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/share/${id}`);
+		const params = new URLSearchParams(signedLink);
+		if (viewer?.source) {
+			params.set('src', viewer.source);
+		}
+		const query = params.toString() ? `?${params.toString()}` : '';
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/share/${id}${query}`, {
+			headers: { ...optionalAuthHeaders(sessionId), ...viewerHeaders(viewer) }
+		});
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error getting share ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error getting share ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		return await response.json();
@@ -87,15 +123,15 @@ export async function getShare(id: string): Promise<Share> {
 
 export async function getShareForEdit(id: string, sessionId: string): Promise<Share> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/share/${id}/edit`, {
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/share/${id}/edit`, {
 			headers: {
 				Authorization: `Bearer ${sessionId}`
 			}
 		});
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error getting share ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error getting share ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		return await response.json();
@@ -107,13 +143,15 @@ export async function getShareForEdit(id: string, sessionId: string): Promise<Sh
 	}
 }
 
-export async function isSharePasswordProtected(id: string): Promise<boolean> {
+export async function isSharePasswordProtected(id: string, sessionId?: string): Promise<boolean> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/share/${id}/protected`);
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/share/${id}/protected`, {
+			headers: optionalAuthHeaders(sessionId)
+		});
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error fetching password status ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error fetching password status ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		const parsedResponse: { isPasswordProtected: boolean } = await response.json();
@@ -130,13 +168,15 @@ export async function isSharePasswordProtected(id: string): Promise<boolean> {
 
 export async function getPasswordProtectedShare(
 	id: string,
-	body: GetPasswordProtectedShareRequest
+	body: GetPasswordProtectedShareRequest,
+	viewer?: Viewer
 ): Promise<Share> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/share/${id}/protected`, {
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/share/${id}/protected`, {
 			body: JSON.stringify(body),
 			headers: {
-				'Content-Type': 'application/json'
+				'Content-Type': 'application/json',
+				...viewerHeaders(viewer)
 			},
 			method: 'POST'
 		});
@@ -144,9 +184,9 @@ export async function getPasswordProtectedShare(
 			throw new WrongPasswordError();
 		}
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error getting share ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error getting share ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		return await response.json();
@@ -163,15 +203,15 @@ export async function getPasswordProtectedShare(
 
 export async function getSharesForUser(sessionId: string): Promise<Share[]> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/shares`, {
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/shares`, {
 			headers: {
 				Authorization: `Bearer ${sessionId}`
 			}
 		});
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error getting shares ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error getting shares ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		return await response.json();
@@ -185,16 +225,16 @@ export async function getSharesForUser(sessionId: string): Promise<Share[]> {
 
 export async function deleteShare(shareId: string, sessionId: string): Promise<void> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/share/${shareId}`, {
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/share/${shareId}`, {
 			headers: {
 				Authorization: `Bearer ${sessionId}`
 			},
 			method: 'DELETE'
 		});
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error deleting share ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error deleting share ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 	} catch (err) {
@@ -211,7 +251,7 @@ export async function editShare(
 	shareId: string
 ): Promise<void> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/share/${shareId}/edit`, {
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/share/${shareId}/edit`, {
 			body: JSON.stringify(request),
 			headers: {
 				'Content-Type': 'application/json',
@@ -220,9 +260,9 @@ export async function editShare(
 			method: 'PATCH'
 		});
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error editing share ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error editing share ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 	} catch (err) {
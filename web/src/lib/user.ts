@@ -10,7 +10,8 @@ export interface UserCredentials {
 export interface User {
 	id: number;
 	name: string;
-	role: number;
+	role: string;
+	permissions: string[];
 }
 
 export class UserAlreadyExistsError extends Error {
@@ -37,11 +38,11 @@ export class UserUsingOauthError extends Error {
 
 export async function checkIfGithubUserExists(userId: number): Promise<boolean> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/oauth/github/${userId}`);
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/oauth/github/${userId}`);
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error getting share ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error getting share ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		const { exists }: { exists: boolean } = await response.json();
@@ -56,7 +57,7 @@ export async function checkIfGithubUserExists(userId: number): Promise<boolean>
 
 export async function createNewUser(user: UserCredentials) {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/user`, {
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/user`, {
 			body: JSON.stringify(user),
 			headers: {
 				'Content-Type': 'application/json'
@@ -67,9 +68,9 @@ export async function createNewUser(user: UserCredentials) {
 			throw new UserAlreadyExistsError();
 		}
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error creating user ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error creating user ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 	} catch (err) {
@@ -85,7 +86,7 @@ export async function createNewUser(user: UserCredentials) {
 
 export async function tryCreateSessionForUser(user: UserCredentials): Promise<string> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/user/session`, {
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/user/session`, {
 			body: JSON.stringify(user),
 			headers: {
 				'Content-Type': 'application/json'
@@ -99,9 +100,9 @@ export async function tryCreateSessionForUser(user: UserCredentials): Promise<st
 			throw new UserUsingOauthError();
 		}
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error creating session ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error creating session ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		const parsedResponse: { sessionId: string } = await response.json();
@@ -119,11 +120,11 @@ export async function tryCreateSessionForUser(user: UserCredentials): Promise<st
 
 export async function tryGetSessionForUser(sessionId: string): Promise<User> {
 	try {
-		const response = await fetch(`${PUBLIC_API_ADDRESS}/user/session/${sessionId}`);
+		const response = await fetch(`${PUBLIC_API_ADDRESS}/v1/user/session/${sessionId}`);
 		if (!response.ok) {
-			const errorBody = await response.json().catch(() => ({ message: response.statusText }));
+			const errorBody = await response.json().catch(() => ({ detail: response.statusText }));
 			throw new Error(
-				`Error getting session for user ${response.status} - ${errorBody.message || 'Unknown error'}`
+				`Error getting session for user ${response.status} - ${errorBody.detail || 'Unknown error'}`
 			);
 		}
 		return await response.json();
@@ -10,15 +10,14 @@ export const load: PageServerLoad = ({ locals }) => {
 };
 
 export const actions = {
-	createShare: async ({ request }) => {
+	createShare: async ({ request, locals }) => {
 		const data = await request.formData();
-		const title = data.get('title') ? (data.get('title') as string) : '';
+		const title = data.get('title') ? (data.get('title') as string) : 'Untitled';
 		const content = data.get('content') ? (data.get('content') as string) : '';
 		const setPassword = data.get('setPassword') !== null;
 		const password = data.get('password') ? (data.get('password') as string) : '';
 		const expireIn = data.get('expireIn') as string;
 		const hideAuthor = data.get('hideAuthor') !== null;
-		const authorId = parseInt((data.get('userId') as string) ?? '-1');
 
 		if (setPassword && password == '') {
 			return fail(400, {
@@ -32,12 +31,11 @@ export const actions = {
 			setPassword,
 			password,
 			expireIn,
-			hideAuthor,
-			authorId
+			hideAuthor
 		};
 		let newShareId = '';
 		try {
-			newShareId = await createShare(params);
+			newShareId = await createShare(params, locals.sessionId);
 		} catch (err) {
 			return fail(500, {
 				message: err instanceof Error ? err.message : 'Unknown error'
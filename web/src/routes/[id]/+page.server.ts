@@ -5,6 +5,7 @@ import {
 	FetchShareStatus,
 	type Share,
 	type GetPasswordProtectedShareRequest,
+	type Viewer,
 	getPasswordProtectedShare,
 	WrongPasswordError,
 	deleteShare
@@ -12,18 +13,26 @@ import {
 import { fail } from '@sveltejs/kit';
 import { GOOGLE_API_KEY } from '$env/static/private';
 
-export const load: PageServerLoad = async ({ params, locals }) => {
-	// Check the role of viewing user
-	const role = locals.user?.role;
-	let isAdmin = false;
-	if (role && role === 1) {
-		isAdmin = true;
+export const load: PageServerLoad = async ({ params, locals, url, request, getClientAddress }) => {
+	// Check the permissions of viewing user
+	const permissions = locals.user?.permissions ?? [];
+	const isAdmin = permissions.includes('share.view.any');
+
+	// Signed links grant access without the password
+	let signedLink: URLSearchParams | undefined;
+	if (url.searchParams.has('sig')) {
+		signedLink = new URLSearchParams();
+		for (const key of ['exp', 'kid', 'sig']) {
+			signedLink.set(key, url.searchParams.get(key) ?? '');
+		}
 	}
 
 	// Load "Enter password" view if share is password protected (except if user is admin)
-	let hasPassword: boolean;
+	let hasPassword = false;
 	try {
-		hasPassword = await isSharePasswordProtected(params.id);
+		if (!signedLink) {
+			hasPassword = await isSharePasswordProtected(params.id, locals.sessionId);
+		}
 	} catch {
 		return {
 			status: FetchShareStatus.NotFound
@@ -38,7 +47,13 @@ export const load: PageServerLoad = async ({ params, locals }) => {
 	// GET share if it's not password protected
 	let share: Share;
 	try {
-		share = await getShare(params.id);
+		const viewer: Viewer = {
+			source: url.searchParams.get('src') ?? undefined,
+			userAgent: request.headers.get('user-agent') ?? undefined,
+			referrer: request.headers.get('referer') ?? undefined,
+			address: getClientAddress()
+		};
+		share = await getShare(params.id, locals.sessionId, signedLink, viewer);
 	} catch {
 		return {
 			status: FetchShareStatus.NotFound
@@ -52,7 +67,7 @@ export const load: PageServerLoad = async ({ params, locals }) => {
 };
 
 export const actions = {
-	getPasswordProtectedShare: async ({ request }) => {
+	getPasswordProtectedShare: async ({ request, getClientAddress }) => {
 		const data = await request.formData();
 		let id = data.get('id') as string;
 		id = id.substring(1);
@@ -61,7 +76,10 @@ export const actions = {
 		};
 		let share: Share;
 		try {
-			share = await getPasswordProtectedShare(id, params);
+			share = await getPasswordProtectedShare(id, params, {
+				userAgent: request.headers.get('user-agent') ?? undefined,
+				address: getClientAddress()
+			});
 		} catch (err) {
 			if (err instanceof Error) {
 				return fail(err instanceof WrongPasswordError ? 400 : 500, { message: err.message });
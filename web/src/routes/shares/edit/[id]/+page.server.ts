@@ -18,7 +18,7 @@ export const actions: Actions = {
 		const sessionId = locals.sessionId ?? '';
 
 		const shareBody: ShareRequest = {
-			title: data.get('title') as string,
+			title: (data.get('title') as string) || 'Untitled',
 			content: data.get('content') as string,
 			setPassword: data.get('setPassword') !== null,
 			password: data.get('password') as string,